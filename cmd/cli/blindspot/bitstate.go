@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+)
+
+// runBitstate ビット状態空間探索で、列挙しきれない大きさのモデルのデッドロックと条件の違反を近似的に探すサブコマンド
+func runBitstate(args []string) {
+	fs := flag.NewFlagSet("bitstate", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	memory := fs.Uint64("memory", 64, "訪問済みの状態を記録するビット配列の大きさ（MiB）")
+	hashes := fs.Int("hashes", 3, "1つの状態に使うハッシュ関数の数")
+	maxDeadlocks := fs.Int("max-deadlocks", core.DefaultMaxDeadlockTraces, "トレースを表示するデッドロックの数の上限（それ以降は数のみ表示）")
+	por := fs.Bool("por", false, "半順序削減で、独立なルールの実行順の入れ替えを省く（デッドロックと-invariantの違反は保存される）")
+	var invariantSources []string
+	fs.Func("invariant", "すべての状態で成り立つべきexpr-lang式（複数指定可）", func(source string) error {
+		invariantSources = append(invariantSources, source)
+		return nil
+	})
+
+	if len(args) < 1 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	fs.Parse(args[1:])
+
+	setupLogger(*common.logSeverity)
+
+	var matchErr error
+	invariants := make([]core.Invariant, 0, len(invariantSources))
+	for _, source := range invariantSources {
+		q, err := query.Compile(source)
+		if err != nil {
+			slog.Error("条件の式が不正です", "invariant", source, "error", err)
+			os.Exit(1)
+		}
+		invariants = append(invariants, core.Invariant{
+			Name: source,
+			Holds: func(node *core.Node) bool {
+				holds, err := q.Match(node)
+				if err != nil && matchErr == nil {
+					matchErr = err
+				}
+				return holds
+			},
+		})
+	}
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	limit := common.limit()
+	if !common.confirm(inputFile) {
+		os.Exit(0)
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if *por {
+		options, err := reductionOptions(invariantSources...)
+		if err != nil {
+			slog.Error("-por を使用できません", "error", err)
+			os.Exit(1)
+		}
+		generator.SetPartialOrderReduction(options)
+	}
+	result, err := generator.GenerateBitstate(core.BitstateOptions{
+		Bits:              *memory * 8 << 20,
+		Hashes:            *hashes,
+		Invariants:        invariants,
+		MaxDeadlockTraces: *maxDeadlocks,
+	})
+	if err != nil {
+		slog.Error("ビット状態空間探索に失敗", "error", err)
+		os.Exit(1)
+	}
+	if matchErr != nil {
+		slog.Warn("条件の式の評価に失敗した状態があります。それらは違反として扱います", "error", matchErr)
+	}
+
+	fmt.Print(formatBitstate(result))
+	if result.DeadlockCount > 0 || len(result.Violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+// formatBitstate ビット状態空間探索の結果と見落としの見積もりを表示用に整形
+func formatBitstate(result *core.BitstateResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "状態数: %d, 遷移数: %d, 最大の深さ: %d\n", result.States, result.Transitions, result.MaxDepth)
+	if result.Truncated {
+		fmt.Fprintln(&b, "  反復回数の上限により探索を打ち切りました")
+	}
+	fmt.Fprintf(&b, "ビット配列: %d ビット (使用率 %.4f%%), ハッシュ関数: %d, hash factor: %.1f\n",
+		result.Bits, float64(result.BitsSet)/float64(result.Bits)*100, result.Hashes, result.HashFactor())
+	fmt.Fprintf(&b, "終了時点の誤判定の確率: %.3g\n", result.FalsePositiveRate())
+	fmt.Fprintf(&b, "推定カバー率: %.6f%% (見落とした状態の期待値: %.3g 以上)\n", result.Coverage()*100, result.ExpectedOmitted)
+
+	if omitted := result.DeadlockCount - int64(len(result.Deadlocks)); omitted > 0 {
+		fmt.Fprintf(&b, "デッドロック: %d (最初の %d 件のトレースを表示、残り %d 件は省略)\n", result.DeadlockCount, len(result.Deadlocks), omitted)
+	} else {
+		fmt.Fprintf(&b, "デッドロック: %d\n", result.DeadlockCount)
+	}
+	for _, trace := range result.Deadlocks {
+		writeTrace(&b, trace.Node, trace.Path)
+	}
+	fmt.Fprintf(&b, "条件の違反: %d\n", len(result.Violations))
+	for _, violation := range result.Violations {
+		fmt.Fprintf(&b, "  %s\n", violation.Invariant)
+		writeTrace(&b, violation.Node, violation.Path)
+	}
+	return b.String()
+}
+
+// writeTrace 開始ノードからのルールの列と、到達した状態のリソースを書き込む
+func writeTrace(b *strings.Builder, node *core.Node, path core.TransitionPath) {
+	labels := make([]string, len(path))
+	for i, edge := range path {
+		labels[i] = edge.GetLabel()
+	}
+	if len(labels) == 0 {
+		fmt.Fprintln(b, "    (開始状態)")
+	} else {
+		fmt.Fprintf(b, "    %s\n", strings.Join(labels, " -> "))
+	}
+	fmt.Fprintf(b, "      %s\n", strings.Join((*node).GetResourcesString(), ", "))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// loadEvaluationCache ルールの評価キャッシュを読み込む
+// ファイルがない場合や読み込めない場合は、空のキャッシュから始める（キャッシュがなくても結果は変わらない）
+func loadEvaluationCache(cacheFile string) *core.EvaluationCache {
+	file, err := os.Open(cacheFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("キャッシュを読み込めないため、すべてのルールを評価します", "cache", cacheFile, "error", err)
+		}
+		return &core.EvaluationCache{}
+	}
+	defer file.Close()
+
+	cache, err := core.ReadEvaluationCache(file)
+	if err != nil {
+		slog.Warn("キャッシュを読み込めないため、すべてのルールを評価します", "cache", cacheFile, "error", err)
+		return &core.EvaluationCache{}
+	}
+	return cache
+}
+
+// saveEvaluationCache 今回の生成での評価結果を、次回の再生成のためのキャッシュとして保存する
+func saveEvaluationCache(generator *core.Generator, cacheFile string) error {
+	if err := writeFileAtomically(cacheFile, generator.EvaluationCache().Write); err != nil {
+		return fmt.Errorf("キャッシュの書き込みに失敗: %w", err)
+	}
+	return nil
+}
+
+// logEvaluationCacheStats キャッシュを使った再生成の効果をログに出力する
+func logEvaluationCacheStats(generator *core.Generator) {
+	stats := generator.GetEvaluationCacheStats()
+	slog.Info("ルールの評価キャッシュ", "reusedNodes", stats.ReusedNodes, "evaluatedNodes", stats.EvaluatedNodes, "changedRules", stats.ChangedRules)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+func TestEvaluationCacheFile(t *testing.T) {
+	dir := t.TempDir()
+	ruleFile := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(ruleFile, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(dir, "rules.cache")
+	generate := func() *core.Generator {
+		t.Helper()
+		firstResources, newNode, edgeRules, err := loadRules(ruleFile, "cud")
+		if err != nil {
+			t.Fatalf("failed to load rules: %v", err)
+		}
+		generator := core.NewGenerator(newNode, firstResources, edgeRules, nil)
+		generator.SetEvaluationCache(loadEvaluationCache(cacheFile))
+		if err := generator.Generate(); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		if err := saveEvaluationCache(generator, cacheFile); err != nil {
+			t.Fatalf("failed to save cache: %v", err)
+		}
+		return generator
+	}
+
+	// キャッシュファイルがない初回はすべて評価し、2回目は展開したノードの評価をすべて再利用する
+	if stats := generate().GetEvaluationCacheStats(); stats.ReusedNodes != 0 {
+		t.Errorf("expected nothing to be reused without a cache file, got %+v", stats)
+	}
+	if stats := generate().GetEvaluationCacheStats(); stats.ReusedNodes != 2 || len(stats.ChangedRules) != 0 {
+		t.Errorf("expected both expanded nodes to be reused, got %+v", stats)
+	}
+
+	// 壊れたキャッシュは無視して空のキャッシュから始める
+	if err := os.WriteFile(cacheFile, []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cache := loadEvaluationCache(cacheFile); len(cache.Nodes) != 0 {
+		t.Errorf("expected an empty cache for a broken file, got %+v", cache)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// generationContext Ctrl-C（と-timeoutの指定があればその時間の経過）で探索を中断するコンテキストを作成
+// 探索が終わったら返されたstopを呼び、Ctrl-Cの扱いを元に戻す
+func generationContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stopSignal := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stopSignal
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stopSignal()
+	}
+}
+
+// ruleSourceHash ルールファイルとincludeで取り込んだファイルの内容のハッシュ（チェックポイントと同じルールかの確認に使用）
+// 取り込んだファイルがない場合は入力ファイルの内容だけのハッシュになる。標準入力から読み込んだ場合は空文字を返す
+func ruleSourceHash(inputFile string, includes []string) (string, error) {
+	if inputFile == stdinInputFile {
+		return "", nil
+	}
+	content, err := os.ReadFile(inputFile)
+	if err != nil {
+		return "", fmt.Errorf("入力ファイルの読み込みに失敗: %w", err)
+	}
+	hash := sha256.New()
+	hash.Write(content)
+	for _, include := range includes {
+		content, err := os.ReadFile(include)
+		if err != nil {
+			return "", fmt.Errorf("取り込んだファイルの読み込みに失敗: %w", err)
+		}
+		// ファイルの境界とパスもハッシュに含め、内容の移動を変更として扱う
+		fmt.Fprintf(hash, "\x00%s\x00%d\x00", include, len(content))
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// resumeGenerator チェックポイントを読み込み、ジェネレーターの状態を復元する
+// ルールファイルの内容がチェックポイントを保存した時と異なる場合はエラーを返す
+func resumeGenerator(generator *core.Generator, checkpointFile string, source string) error {
+	file, err := os.Open(checkpointFile)
+	if err != nil {
+		return fmt.Errorf("チェックポイントの読み込みに失敗: %w", err)
+	}
+	defer file.Close()
+
+	checkpoint, err := core.ReadCheckpoint(file)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Source != source {
+		return fmt.Errorf("チェックポイントを保存した時からルールファイルが変更されています")
+	}
+	return generator.Restore(checkpoint)
+}
+
+// saveCheckpoint ジェネレーターの状態をチェックポイントとして保存する
+func saveCheckpoint(generator *core.Generator, checkpointFile string, source string) error {
+	checkpoint := generator.Checkpoint()
+	checkpoint.Source = source
+	if err := writeFileAtomically(checkpointFile, checkpoint.Write); err != nil {
+		return fmt.Errorf("チェックポイントの書き込みに失敗: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomically ファイルを書き込む
+// 書き込みの途中で中断されても以前の内容が壊れないよう、一時ファイルに書いてから置き換える
+func writeFileAtomically(path string, write func(io.Writer) error) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if err := temp.Chmod(0o644); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := write(temp); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// runCompose 複数のルールファイルをコンポーネントとして並行合成するサブコマンド
+func runCompose(args []string) {
+	fs := flag.NewFlagSet("compose", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	outputFormat := fs.String("output", "mermaid", "出力形式 (mermaid, visjs, dot, json)")
+	outFile := fs.String("out", "", "出力先ファイル（省略時は標準出力）")
+	groupBy := fs.String("group-by", "", "ノードを入れ子にまとめるexpr-lang式（カンマ区切りで外側から順に）")
+	syncMode := fs.String("sync", string(core.SyncRuleNames), "コンポーネントの同期方法 (rules, resources)")
+
+	// フラグが現れるまでの引数をコンポーネントの入力ファイルとして取得
+	var inputs []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		inputs = append(inputs, args[0])
+		args = args[1:]
+	}
+	if len(inputs) < 2 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	setupLogger(*common.logSeverity)
+
+	components := make([]core.Component, 0, len(inputs))
+	for _, input := range inputs {
+		name, inputFile := componentName(input)
+		firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+		if err != nil {
+			slog.Error("ルールの読み込みに失敗", "file", inputFile, "error", err)
+			os.Exit(1)
+		}
+		components = append(components, core.Component{
+			Name:    name,
+			Start:   firstResources,
+			NewNode: newNode,
+			Rules:   edgeRules,
+		})
+	}
+
+	firstResources, newNode, edgeRules, err := core.ComposeParallel(components, core.SyncMode(*syncMode))
+	if err != nil {
+		slog.Error("コンポーネントの合成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	limit := common.limit()
+	if !common.confirm(inputs[0]) {
+		os.Exit(0)
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if *groupBy != "" {
+		grouper, err := newNodeGrouper(*groupBy)
+		if err != nil {
+			slog.Error("-group-by の式が不正です", "error", err)
+			os.Exit(1)
+		}
+		generator.SetNodeGrouper(grouper)
+	}
+	if err := generator.Generate(); err != nil {
+		slog.Error("ステートマシンの生成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	formatter, err := getFormatter(*outputFormat)
+	if err != nil {
+		slog.Error("未対応の出力形式", "format", *outputFormat)
+		os.Exit(1)
+	}
+	result, err := formatter.Format(generator)
+	if err != nil {
+		slog.Error("出力の生成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	if *outFile != "" {
+		if err := os.WriteFile(*outFile, []byte(result+"\n"), 0o644); err != nil {
+			slog.Error("出力ファイルの書き込みに失敗", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Println(result)
+}
+
+// componentName 「名前=ファイル」形式の引数を分解する
+// 名前を省略した場合は拡張子を除いたファイル名をコンポーネント名とする
+func componentName(input string) (name string, inputFile string) {
+	if name, inputFile, found := strings.Cut(input, "="); found {
+		return name, inputFile
+	}
+	base := filepath.Base(input)
+	return strings.TrimSuffix(base, filepath.Ext(base)), input
+}
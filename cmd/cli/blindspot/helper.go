@@ -1,54 +1,482 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
-	"github.com/yuukiiwai/blindspot/pkg/std-impl/cud"
-	"github.com/yuukiiwai/blindspot/pkg/std-impl/stringlist"
+	_ "github.com/yuukiiwai/blindspot/pkg/std-impl/cud"
+	_ "github.com/yuukiiwai/blindspot/pkg/std-impl/output"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/plugin"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+	_ "github.com/yuukiiwai/blindspot/pkg/std-impl/stringlist"
 )
 
-var parserMap = map[string](func() (core.Parser, error)){
-	"stringlist": stringlist.NewRuledJsonParser,
-	"cud":        cud.NewCudYamlParser,
-}
-
 func getSupportedFormats() []string {
-	formats := make([]string, 0, len(parserMap))
-	for format := range parserMap {
-		formats = append(formats, format)
+	registrations := core.RegisteredParsers()
+	formats := make([]string, 0, len(registrations))
+	for _, registration := range registrations {
+		formats = append(formats, registration.Name)
 	}
 	return formats
 }
 
-func getParser(inputFormat string) (core.Parser, error) {
-	parser, ok := parserMap[inputFormat]
+// getRegisteredParser 登録済みのパーサーのみから取得（プラグインの変換先の解決に使用）
+func getRegisteredParser(inputFormat string) (core.Parser, error) {
+	registration, ok := core.LookupParser(inputFormat)
 	if !ok {
 		return nil, fmt.Errorf("unsupported input format: %s\nWe can only parse %v formats", inputFormat, strings.Join(getSupportedFormats(), ", "))
 	}
-	return parser()
+	return registration.New()
+}
+
+// getParser 登録済みのパーサー、またはPATH上のパーサープラグインを取得
+func getParser(inputFormat string) (core.Parser, error) {
+	if _, ok := core.LookupParser(inputFormat); ok {
+		return getRegisteredParser(inputFormat)
+	}
+	if command, err := plugin.FindParserCommand(inputFormat); err == nil {
+		return plugin.NewParser(getRegisteredParser, command), nil
+	}
+	return getRegisteredParser(inputFormat)
+}
+
+func getSupportedOutputFormats() []string {
+	registrations := core.RegisteredFormatters()
+	formats := make([]string, 0, len(registrations))
+	for _, registration := range registrations {
+		formats = append(formats, registration.Name)
+	}
+	return formats
+}
+
+// getFormatter 登録済みのフォーマッター、またはPATH上のフォーマッタープラグインを取得
+func getFormatter(outputFormat string) (core.Formatter, error) {
+	if registration, ok := core.LookupFormatter(outputFormat); ok {
+		return registration.New()
+	}
+	if command, err := plugin.FindFormatterCommand(outputFormat); err == nil {
+		return plugin.NewFormatter(command), nil
+	}
+	return nil, fmt.Errorf("unsupported output format: %s\nWe can only output %v formats", outputFormat, strings.Join(getSupportedOutputFormats(), ", "))
+}
+
+// getFormatsDefinition 登録されている入力形式・出力形式の説明を作成
+func getFormatsDefinition() string {
+	var definition strings.Builder
+	definition.WriteString("\tInput Formats:\n")
+	for _, registration := range core.RegisteredParsers() {
+		definition.WriteString(fmt.Sprintf("\t\t%s %v: %s\n", registration.Name, registration.Extensions, registration.Description))
+	}
+	definition.WriteString("\n\tOutput Formats:\n")
+	for _, registration := range core.RegisteredFormatters() {
+		definition.WriteString(fmt.Sprintf("\t\t%s: %s\n", registration.Name, registration.Description))
+	}
+	definition.WriteString("\n\t\tPATH上の blindspot-parser-<名前> / blindspot-formatter-<名前> もプラグインとして指定できます\n")
+	return definition.String()
+}
+
+// commonFlags サブコマンド間で共通のフラグ
+type commonFlags struct {
+	inputFormat *string
+	logSeverity *string
+	limitFlag   *int64
+	yes         *bool
+}
+
+// addCommonFlags 共通のフラグをFlagSetに登録
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	return &commonFlags{
+		inputFormat: fs.String("input", "", "入力形式（省略時は拡張子と内容から自動判定）"),
+		logSeverity: fs.String("log-severity", "warn", "ログの重大度 (debug, info, warn, error)"),
+		limitFlag:   fs.Int64("limit", -1, "反復回数の上限"),
+		yes:         fs.Bool("yes", false, "反復回数の上限の確認を省略する"),
+	}
+}
+
+// limit limitが指定されていない場合はnilポインタを返す
+func (c *commonFlags) limit() *int64 {
+	if *c.limitFlag == -1 {
+		return nil
+	}
+	return c.limitFlag
+}
+
+// confirm 反復回数の上限をユーザーに確認し、続行する場合はtrueを返す（-yes指定時は確認しない）
+// 標準入力からルールを読み込んだ場合は確認に応答できないため、-yesがなければ終了する
+func (c *commonFlags) confirm(inputFile string) bool {
+	if *c.yes {
+		return true
+	}
+	if inputFile == stdinInputFile {
+		slog.Error("標準入力からルールを読み込む場合は確認に応答できないため、-yes を指定してください")
+		os.Exit(1)
+	}
+	return confirmLimit(c.limit())
+}
+
+// searchFlags 探索の順序と深さの上限、半順序削減のフラグ
+type searchFlags struct {
+	strategyName *string
+	maxDepthFlag *int
+	por          *bool
+}
+
+// addSearchFlags 探索のフラグをFlagSetに登録
+func addSearchFlags(fs *flag.FlagSet) *searchFlags {
+	return &searchFlags{
+		strategyName: fs.String("strategy", "bfs", "探索の順序 (bfs, dfs, iddfs)"),
+		maxDepthFlag: fs.Int("max-depth", -1, "展開する深さの上限（開始ノードからの遷移数）"),
+		por:          fs.Bool("por", false, "半順序削減で、独立なルールの実行順の入れ替えを省く（デッドロックは保存される）"),
+	}
+}
+
+// maxDepth max-depthが指定されていない場合はnilポインタを返す
+func (s *searchFlags) maxDepth() *int {
+	if *s.maxDepthFlag < 0 {
+		return nil
+	}
+	return s.maxDepthFlag
+}
+
+// configure ジェネレーターに探索の順序と深さの上限、半順序削減を設定
+func (s *searchFlags) configure(generator *core.Generator) error {
+	strategy, err := core.ParseSearchStrategy(*s.strategyName)
+	if err != nil {
+		return err
+	}
+	generator.SetStrategy(strategy)
+	generator.SetMaxDepth(s.maxDepth())
+	if *s.por {
+		generator.SetPartialOrderReduction(&core.ReductionOptions{})
+	}
+	return nil
+}
+
+// reductionOptions 保存したい条件の式が参照するキーから、半順序削減の設定を作成
+// 式が$envやresources（stringlistなど）でリソース全体を参照する場合は、参照するキーを特定できないため、
+// 何かを変更するルールをすべて削減の対象から外す
+func reductionOptions(sources ...string) (*core.ReductionOptions, error) {
+	options := &core.ReductionOptions{}
+	for _, source := range sources {
+		if source == "" {
+			continue
+		}
+		names, err := query.Identifiers(source)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, "$env") || slices.Contains(names, "resources") {
+			options.VisibleAll = true
+		}
+		options.Visible = append(options.Visible, names...)
+	}
+	return options, nil
+}
+
+// formatCoverage ルールごとの適用状況を人が読める形式で表現
+func formatCoverage(generator *core.Generator) string {
+	var report strings.Builder
+	report.WriteString("ルールの適用状況:\n")
+	for _, coverage := range generator.GetRuleCoverage() {
+		status := ""
+		if coverage.Fired == 0 {
+			status = " (一度も遷移していません)"
+		}
+		report.WriteString(fmt.Sprintf("  %s [priority %d]: 遷移 %d, 抑制 %d%s\n",
+			coverage.Rule.GetName(), coverage.Rule.GetPriority(), coverage.Fired, coverage.Suppressed, status))
+	}
+	if suppressed := generator.GetSuppressedRules(); len(suppressed) > 0 {
+		report.WriteString("優先度による抑制:\n")
+		for _, s := range suppressed {
+			report.WriteString(fmt.Sprintf("  %s: %s (priority %d < %d)\n",
+				strings.Join((*s.Node).GetResourcesString(), ", "), s.Rule.GetName(), s.Rule.GetPriority(), s.ByPriority))
+		}
+	}
+	return report.String()
+}
+
+// setupLogger ログの重大度を設定してデフォルトロガーを差し替える
+func setupLogger(logSeverity string) {
+	var level slog.Level
+	switch logSeverity {
+	case "debug":
+		level = slog.LevelDebug
+	case "info":
+		level = slog.LevelInfo
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: true,
+	}
+	handler := slog.NewTextHandler(os.Stdout, opts)
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+}
+
+// stdinInputFile 標準入力からルールを読み込むことを表す入力ファイル名
+const stdinInputFile = "-"
+
+// loadRules 入力ファイル（"-"の場合は標準入力）を読み込み、指定された形式でパースする
+func loadRules(inputFile string, inputFormat string) (
+	firstResources core.Node,
+	newNode func(any) core.Node,
+	edgeRules []*core.EdgeRule,
+	err error,
+) {
+	firstResources, newNode, edgeRules, _, err = loadRuleSources(inputFile, inputFormat)
+	return firstResources, newNode, edgeRules, err
+}
+
+// loadRuleSources loadRulesと同様にルールを読み込み、includeなどで取り込んだファイルのパスも返す
+func loadRuleSources(inputFile string, inputFormat string) (
+	firstResources core.Node,
+	newNode func(any) core.Node,
+	edgeRules []*core.EdgeRule,
+	includes []string,
+	err error,
+) {
+	var ruleFile []byte
+	sourcePath := inputFile
+	if inputFile == stdinInputFile {
+		ruleFile, err = io.ReadAll(os.Stdin)
+		sourcePath = ""
+	} else {
+		ruleFile, err = os.ReadFile(inputFile)
+	}
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("入力ファイルの読み込みに失敗: %w", err)
+	}
+	if inputFormat == "" {
+		inputFormat, err = detectInputFormat(sourcePath, string(ruleFile))
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	return parseRules(string(ruleFile), inputFormat, sourcePath)
+}
+
+// detectInputFormat ファイルの拡張子から入力形式を判定し、判定できない場合は内容から判定する
+// 拡張子が一致する形式が1つならそれを使用し、複数または0の場合は各形式のDetectで内容を調べる
+func detectInputFormat(inputFile string, content string) (string, error) {
+	extension := strings.ToLower(filepath.Ext(inputFile))
+	var candidates []core.ParserRegistration
+	if extension != "" {
+		for _, registration := range core.RegisteredParsers() {
+			if slices.Contains(registration.Extensions, extension) {
+				candidates = append(candidates, registration)
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		slog.Info("拡張子から入力形式を判定", "format", candidates[0].Name)
+		return candidates[0].Name, nil
+	}
+	if len(candidates) == 0 {
+		candidates = core.RegisteredParsers()
+	}
+
+	var detected []string
+	for _, registration := range candidates {
+		if registration.Detect != nil && registration.Detect(content) {
+			detected = append(detected, registration.Name)
+		}
+	}
+	switch len(detected) {
+	case 1:
+		slog.Info("内容から入力形式を判定", "format", detected[0])
+		return detected[0], nil
+	case 0:
+		return "", fmt.Errorf("入力形式を判定できません。-input で指定してください (%s)", strings.Join(getSupportedFormats(), ", "))
+	default:
+		return "", fmt.Errorf("入力形式を1つに判定できません (%s)。-input で指定してください", strings.Join(detected, ", "))
+	}
+}
+
+// parseRules ルール定義の文字列を指定された形式でパースする
+// sourcePathは入力ファイルのパスで、includeなどの相対パスの解決に使用する（不明な場合は空文字）
+// includesはパーサーが報告した、入力ファイルのほかに読み込んだファイルのパス
+func parseRules(content string, inputFormat string, sourcePath string) (
+	firstResources core.Node,
+	newNode func(any) core.Node,
+	edgeRules []*core.EdgeRule,
+	includes []string,
+	err error,
+) {
+	parser, err := getParser(inputFormat)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("パーサーの作成に失敗: %w", err)
+	}
+	if setter, ok := parser.(core.SourcePathSetter); ok {
+		setter.SetSourcePath(sourcePath)
+	}
+
+	firstResources, newNode, edgeRules, err = parser.Parse(content)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("ルールのパースに失敗: %w", err)
+	}
+	if reporter, ok := parser.(core.SourceFilesReporter); ok {
+		includes = reporter.SourceFiles()
+	}
+	return firstResources, newNode, edgeRules, includes, nil
+}
+
+// newNodeGrouper カンマ区切りのexpr-lang式から、ノードを入れ子のグループに分ける関数を作成
+// 各式の評価結果を外側から順に「式: 値」のグループ名とし、nilまたは評価に失敗した階層で打ち切る
+func newNodeGrouper(spec string) (func(*core.Node) []string, error) {
+	var queries []*query.Query
+	for _, source := range strings.Split(spec, ",") {
+		q, err := query.Compile(strings.TrimSpace(source))
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return func(node *core.Node) []string {
+		var group []string
+		for _, q := range queries {
+			value, err := q.Run(node)
+			if err != nil || value == nil {
+				break
+			}
+			group = append(group, fmt.Sprintf("%s: %v", q, value))
+		}
+		return group
+	}, nil
+}
+
+// confirmLimit 反復回数の上限についてユーザーに確認し、続行する場合はtrueを返す
+func confirmLimit(limit *int64) bool {
+	if limit != nil {
+		fmt.Printf("反復回数の上限は%dです.よろしいですか？(y/n)", *limit)
+	} else {
+		fmt.Println("反復回数の上限は設定されていません.論理的に終了条件が存在しない場合,コンピューターに不具合が発生する可能性があります.また,生成後のステートマシンの出力中に停止する可能性があります.よろしいですか？(y/n)")
+	}
+	var input string
+	fmt.Scanln(&input)
+	return input == "y"
 }
 
 func getCommandDefinition() string {
+	return strings.TrimRight(getUsageDefinition(), "\t") + "\n" + getFormatsDefinition()
+}
+
+func getUsageDefinition() string {
 	return `
 	Usage:
 		blindspot <input_file> [OPTIONS]
+		blindspot - [OPTIONS] (標準入力からルールを読み込む。-yesが必要)
+		blindspot testgen <input_file> [OPTIONS]
+		blindspot replay <input_file> <trace_file> [OPTIONS]
+		blindspot sim <input_file> [OPTIONS]
+		blindspot serve [OPTIONS]
+		blindspot markov <input_file> [OPTIONS]
+		blindspot compose [<name>=]<input_file> [<name>=]<input_file>... [OPTIONS]
+		blindspot bitstate <input_file> [OPTIONS]
+		blindspot reach <input_file> -target <expr> [OPTIONS]
+		blindspot walk <input_file> [OPTIONS]
 		blindspot -help
 
 	Required:
 		<input_file> string (入力ファイルのパス)
 
 	Options:
-		-input string (stringlist, cud) default: stringlist
-		-output string (mermaid, visjs, dot) default: mermaid
+		-input string (Input Formatsを参照) default: 拡張子と内容から自動判定
+		-output string (Output Formatsを参照) default: mermaid
 		-log-severity string (debug, info, warn, error) default: warn
 		--limit int64 (反復回数の上限、無限ループ防止) default: 0 (無制限)
+		-yes (反復回数の上限の確認を省略する)
+		-out string (出力先ファイル) default: 標準出力
+		--watch (入力ファイルとincludeで取り込んだファイルの変更を監視し、変更のたびに再生成して-outを書き換える。-outが必須)
+		-watch-interval duration (--watch時の確認間隔) default: 1s
+		-coverage (ルールごとの遷移回数と、優先度による抑制を標準エラー出力に表示)
+		-group-by string (ノードを入れ子にまとめるexpr-lang式。カンマ区切りで外側から順に指定。cudのgroup_byより優先) default: なし
+		-timeout duration (探索を中断するまでの時間。Ctrl-Cでも中断でき、それまでの結果を出力する) default: 0 (無制限)
+		-checkpoint string (探索を打ち切った場合に、再開用の状態を保存するファイル) default: なし
+		-resume string (チェックポイントファイルから探索を再開する。ルールファイルが変更されている場合はエラー。--limitは再開前を含めた反復回数の累計に適用する) default: なし
+		-strategy string (探索の順序。bfs: 幅優先, dfs: 深さ優先, iddfs: 反復深化) default: bfs
+		--max-depth int (展開する深さの上限。開始ノードからN回の遷移で到達できる状態までを出力し、深さNの状態は展開しない。指定時は反復回数の上限の確認を省略する) default: -1 (無制限)
+		-por (半順序削減で、独立なルールの実行順の入れ替えを省く。デッドロックは保存されるが、グラフは元のグラフの一部になる)
+		-progress (発見した状態の数、キューの長さ、1秒あたりの状態数を標準エラー出力に表示。--limit指定時は上限に対する割合をバーで表示)
+		-cache string (ルールの条件の評価結果を保存するキャッシュファイル。次回は定義が変わっていないルールの条件の評価を省く（状態の探索はすべてやり直す）。--watchでは常に前回の結果を引き継ぐ) default: なし
+
+	testgen Options (全遷移を網羅するテストケースを、総ステップ数が最小になるように生成):
+		-format string (go, json) default: go
+		-package string (Goテストのパッケージ名) default: main_test
+
+	replay (実行ログをモデルと照合し、最初の食い違いを報告):
+		<trace_file> string (JSON Lines形式の実行ログ。1行に {"rule": "...", "resources": ...} を記載し、resourcesは省略可)
+
+	sim (開始状態からルールを1つずつ適用する対話型シミュレーター。起動後にhelpでコマンド一覧を表示):
+		-input, -log-severity のみ使用
+
+	serve Options (JSON APIとブラウザ用ビューアーを提供するHTTPサーバー):
+		-addr string (待ち受けるアドレス) default: localhost:8080
+		--limit int64 (反復回数の上限。limitパラメーターの省略時の値と、指定できる最大値) default: 10000
+		アップロードできるルールは組み込みの入力形式で10MiBまで。cudのincludeは使えない
+
+	markov Options (ルールの重みを遷移確率とみなし、マルコフ連鎖として解析):
+		-target string (到達確率を求める目標状態のexpr-lang式) default: なし
+		-top int (再帰類の定常分布で表示する状態の数) default: 10
+
+	compose Options (複数のルールファイルをコンポーネントとして並行合成。ルール名はコンポーネント名で修飾):
+		-sync string (rules: 同名のルールを同時に実行, resources: リソースを共有してインターリーブ) default: rules
+		-output, -out, -group-by はメインコマンドと同じ
+
+	bitstate Options (状態をビット配列に記録する近似探索。エッジを保持せず、デッドロックと条件の違反をトレース付きで報告):
+		-memory uint (ビット配列の大きさ、MiB) default: 64
+		-hashes int (1つの状態に使うハッシュ関数の数) default: 3
+		-invariant string (すべての状態で成り立つべきexpr-lang式。複数指定可) default: なし
+		-max-deadlocks int (トレースを表示するデッドロックの数の上限。それ以降は数のみ表示) default: 10
+		-por (半順序削減。デッドロックと-invariantの違反は保存される)
+
+	reach Options (目標の状態へのパスを、目標に近い状態から優先して探す。グラフ全体を生成しない):
+		-target string (目標の状態を表すexpr-lang式。必須)
+		-heuristic string (目標までの遷移数を見積もるexpr-lang式) default: -targetの&&で区切った条件のうち成り立っていないものの数を、1つのルールが変えうる条件の最大数で割ったもの（最短のパスを保証する）
+		-greedy (見積もりだけで順位を付ける最良優先探索。省略時はA*で、見積もりが過大でなければ最短のパスを返す)
+		-por (半順序削減。到達できるかどうかは保存されるが、パスは最短とは限らない)
+
+	walk Options (グラフを保持せずにランダムウォークを繰り返し、訪問した状態の数、ルールの実行回数、デッドロック、目標に到達したウォークを集計):
+		--runs int (ウォークの回数) default: 1000
+		--steps int (1回のウォークの最大の遷移数) default: 100
+		--seed uint (乱数の種。同じ種からは同じ結果になる) default: 1
+		-target string (到達したウォークを数える状態のexpr-lang式) default: なし
+		-top int (表示するデッドロックの数) default: 10
 
 	Examples:
+		blindspot rules.yaml
 		blindspot rules.json -input stringlist -output mermaid
 		blindspot rules.json -input cud -output visjs
 		blindspot rules.json -input stringlist -output dot -log-severity debug
 		blindspot rules.json -input stringlist -output mermaid --limit 1000
+		blindspot rules.yaml -input cud -output mermaid -out graph.mmd --watch --limit 1000
+		blindspot rules.yaml -input cud -output plantuml -group-by phase --limit 1000
+		blindspot rules.yaml -output json -out graph.json -timeout 2h -checkpoint rules.ckpt -yes
+		blindspot rules.yaml -output json -out graph.json -resume rules.ckpt -checkpoint rules.ckpt -yes
+		cat rules.yaml | blindspot - -input cud --limit 1000 -yes
+		blindspot testgen rules.yaml -input cud -format go -package rules_test > rules_test.go
+		blindspot replay rules.yaml trace.jsonl -input cud --limit 1000
+		blindspot sim rules.yaml -input cud
+		blindspot serve -addr localhost:8080
+		blindspot compose order.yaml payment.yaml -input cud -sync rules --limit 10000
+		blindspot bitstate huge.yaml -memory 512 -invariant 'stock >= 0' -yes
+		blindspot pipeline.yaml -output json -out graph.json -por -yes
+		blindspot order.yaml -output json -out graph.json -cache order.cache -yes
+		blindspot huge.yaml -output json -out graph.json -progress --limit 1000000 -yes
+		blindspot walk rules.yaml --runs 10000 --steps 200 --seed 42 -target 'status == "failed"'
+		blindspot reach huge.yaml -target 'status == "shipped" && paid == true' --limit 1000000 -yes
+		blindspot markov job.yaml -input cud -target 'status == "failed"' --limit 10000
 	`
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRules CLIのテストで使う、ルールを1つだけ持つcud形式のルールファイル
+const testRules = `
+start_resources:
+  status: "todo"
+edge_rules:
+  - name: finish
+    fire_condition: status == "todo"
+    effect:
+      - action: update
+        resource: {key: status, value: "done"}
+`
+
+func TestLoadRulesFromStdin(t *testing.T) {
+	stdinFile := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(stdinFile, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	original := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = original }()
+
+	// 拡張子がないため、入力形式は内容から判定する
+	firstResources, _, edgeRules, err := loadRules(stdinInputFile, "")
+	if err != nil {
+		t.Fatalf("failed to load rules from stdin: %v", err)
+	}
+	resources, ok := firstResources.GetResources().(map[string]any)
+	if !ok || resources["status"] != "todo" || len(edgeRules) != 1 {
+		t.Errorf("unexpected rules from stdin: %v, %d rules", firstResources.GetResources(), len(edgeRules))
+	}
+}
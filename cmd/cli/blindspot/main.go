@@ -5,21 +5,12 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
-	"github.com/yuukiiwai/blindspot/pkg/std-impl/output"
 )
 
 func main() {
-	// FlagSetを使用して混合引数を処理
-	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	var help bool
-	fs.BoolVar(&help, "help", false, "ヘルプを表示")
-	inputFormat := fs.String("input", "stringlist", "入力形式 (stringlist, cud)")
-	outputFormat := fs.String("output", "mermaid", "出力形式 (mermaid, visjs, dot)")
-	logSeverity := fs.String("log-severity", "warn", "ログの重大度 (debug, info, warn, error)")
-	limitFlag := fs.Int64("limit", -1, "反復回数の上限")
-
 	// 引数が足りない場合はヘルプを表示
 	if len(os.Args) < 2 {
 		fmt.Println(getCommandDefinition())
@@ -32,6 +23,55 @@ func main() {
 		os.Exit(0)
 	}
 
+	// サブコマンドの場合
+	switch os.Args[1] {
+	case "testgen":
+		runTestgen(os.Args[2:])
+		return
+	case "replay":
+		runReplay(os.Args[2:])
+		return
+	case "sim":
+		runSim(os.Args[2:])
+		return
+	case "serve":
+		runServe(os.Args[2:])
+		return
+	case "markov":
+		runMarkov(os.Args[2:])
+		return
+	case "compose":
+		runCompose(os.Args[2:])
+		return
+	case "bitstate":
+		runBitstate(os.Args[2:])
+		return
+	case "reach":
+		runReach(os.Args[2:])
+		return
+	case "walk":
+		runWalk(os.Args[2:])
+		return
+	}
+
+	// FlagSetを使用して混合引数を処理
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	var help bool
+	fs.BoolVar(&help, "help", false, "ヘルプを表示")
+	common := addCommonFlags(fs)
+	search := addSearchFlags(fs)
+	outputFormat := fs.String("output", "mermaid", "出力形式 (mermaid, visjs, dot, json)")
+	outFile := fs.String("out", "", "出力先ファイル（省略時は標準出力）")
+	watch := fs.Bool("watch", false, "入力ファイルの変更を監視して再生成する")
+	watchInterval := fs.Duration("watch-interval", time.Second, "--watch時の入力ファイルの確認間隔")
+	groupBy := fs.String("group-by", "", "ノードを入れ子にまとめるexpr-lang式（カンマ区切りで外側から順に）")
+	coverage := fs.Bool("coverage", false, "ルールごとの適用回数と優先度による抑制回数を標準エラー出力に表示する")
+	checkpointFile := fs.String("checkpoint", "", "探索を打ち切った場合（上限、Ctrl-C、-timeout）に状態を保存するチェックポイントファイル")
+	resumeFile := fs.String("resume", "", "チェックポイントファイルから探索を再開する")
+	timeout := fs.Duration("timeout", 0, "探索を中断するまでの時間（0は無制限）")
+	progress := fs.Bool("progress", false, "探索の進み具合（状態数、キューの長さ、1秒あたりの状態数）を標準エラー出力に表示する")
+	cacheFile := fs.String("cache", "", "ルールの条件の評価結果を保存するキャッシュファイル（変更されていないルールの条件の評価を省く）")
+
 	// 最初の引数を入力ファイルとして取得
 	inputFile := os.Args[1]
 
@@ -39,10 +79,7 @@ func main() {
 	fs.Parse(os.Args[2:])
 
 	// limitが指定されていない場合はnilポインタを使用
-	var limit *int64
-	if *limitFlag != -1 {
-		limit = limitFlag
-	}
+	limit := common.limit()
 
 	if help {
 		fmt.Println(getCommandDefinition())
@@ -50,83 +87,151 @@ func main() {
 	}
 
 	// ログの重大度の設定
-	var level slog.Level
-	switch *logSeverity {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelWarn
-	}
-	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: true,
-	}
-	handler := slog.NewTextHandler(os.Stdout, opts)
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
-
-	// 入力ファイルの読み込み
-	ruleFile, err := os.ReadFile(inputFile)
-	if err != nil {
-		slog.Error("入力ファイルの読み込みに失敗", "error", err)
+	setupLogger(*common.logSeverity)
+
+	if _, err := core.ParseSearchStrategy(*search.strategyName); err != nil {
+		slog.Error("-strategy の指定が不正です", "error", err)
 		os.Exit(1)
 	}
+	// 深さの上限がある場合は探索が必ず終わるため、反復回数の上限を確認しない
+	confirm := func() bool {
+		return search.maxDepth() != nil || common.confirm(inputFile)
+	}
 
-	// パーサーの作成
-	parser, err := getParser(*inputFormat)
+	var grouper func(*core.Node) []string
+	if *groupBy != "" {
+		var err error
+		grouper, err = newNodeGrouper(*groupBy)
+		if err != nil {
+			slog.Error("-group-by の式が不正です", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// 監視モードの場合
+	if *watch {
+		if *outFile == "" {
+			slog.Error("--watch には -out の指定が必要です")
+			os.Exit(1)
+		}
+		if inputFile == stdinInputFile {
+			slog.Error("--watch では標準入力を監視できません")
+			os.Exit(1)
+		}
+		if *checkpointFile != "" || *resumeFile != "" {
+			slog.Error("--watch では --checkpoint と --resume を使用できません")
+			os.Exit(1)
+		}
+		if !confirm() {
+			os.Exit(0)
+		}
+		runWatch(watchConfig{
+			inputFile:    inputFile,
+			inputFormat:  *common.inputFormat,
+			outputFormat: *outputFormat,
+			outFile:      *outFile,
+			limit:        limit,
+			interval:     *watchInterval,
+			grouper:      grouper,
+			search:       search,
+			cacheFile:    *cacheFile,
+		})
+		return
+	}
+
+	// 入力ファイルの読み込みとルールのパース
+	firstResources, newNode, edgeRules, includes, err := loadRuleSources(inputFile, *common.inputFormat)
 	if err != nil {
-		slog.Error("パーサーの作成に失敗", "error", err)
+		slog.Error("ルールの読み込みに失敗", "error", err)
 		os.Exit(1)
 	}
 
-	// ルールのパース
-	firstResources, newNode, edgeRules, err := parser.Parse(string(ruleFile))
-	if err != nil {
-		slog.Error("ルールのパースに失敗", "error", err)
+	if !confirm() {
+		os.Exit(0)
+	}
+
+	// ジェネレーターの作成
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if grouper != nil {
+		generator.SetNodeGrouper(grouper)
+	}
+	if err := search.configure(generator); err != nil {
+		slog.Error("-strategy の指定が不正です", "error", err)
 		os.Exit(1)
 	}
+	if *cacheFile != "" {
+		generator.SetEvaluationCache(loadEvaluationCache(*cacheFile))
+	}
 
-	if limit != nil {
-		fmt.Printf("反復回数の上限は%dです.よろしいですか？(y/n)", *limit)
-		var input string
-		fmt.Scanln(&input)
-		if input != "y" {
-			os.Exit(0)
+	// チェックポイントからの再開
+	var source string
+	if *checkpointFile != "" || *resumeFile != "" {
+		source, err = ruleSourceHash(inputFile, includes)
+		if err != nil {
+			slog.Error("ルールファイルのハッシュの計算に失敗", "error", err)
+			os.Exit(1)
 		}
-	} else {
-		fmt.Println("反復回数の上限は設定されていません.論理的に終了条件が存在しない場合,コンピューターに不具合が発生する可能性があります.また,生成後のステートマシンの出力中に停止する可能性があります.よろしいですか？(y/n)")
-		var input string
-		fmt.Scanln(&input)
-		if input != "y" {
-			os.Exit(0)
+	}
+	if *resumeFile != "" {
+		if err := resumeGenerator(generator, *resumeFile, source); err != nil {
+			slog.Error("チェックポイントから再開できません", "error", err)
+			os.Exit(1)
 		}
 	}
 
-	// ジェネレーターの作成
-	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	var progressBar *progressObserver
+	if *progress {
+		progressBar = newProgressObserver(os.Stderr, limit)
+		progressBar.resumed = generator.GetIterations()
+		generator.AddObserver(progressBar)
+		slog.SetDefault(slog.New(progressLogHandler{Handler: slog.Default().Handler(), progress: progressBar}))
+	}
 
-	// ステートマシンの生成
-	if err := generator.Generate(); err != nil {
+	// ステートマシンの生成（Ctrl-Cや-timeoutで中断した場合は、それまでの結果を出力する）
+	ctx, stop := generationContext(*timeout)
+	err = generator.GenerateContext(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+	if progressBar != nil {
+		progressBar.finish()
+	}
+	if err != nil && !interrupted {
 		slog.Error("ステートマシンの生成に失敗", "error", err)
 		os.Exit(1)
 	}
 
+	if generator.IsPartial() {
+		if *checkpointFile != "" {
+			if err := saveCheckpoint(generator, *checkpointFile, source); err != nil {
+				slog.Error("チェックポイントの保存に失敗", "error", err)
+				os.Exit(1)
+			}
+			slog.Warn("探索を打ち切ったため、チェックポイントを保存しました。--resume で再開できます", "checkpoint", *checkpointFile, "frontier", len(generator.GetFrontierNodes()))
+		} else if interrupted {
+			slog.Warn("探索を中断しました。--checkpoint を指定すると、中断した状態を保存して後で再開できます")
+		}
+	}
+
+	// 探索を打ち切った場合も、展開したノードの評価結果は次回に再利用できる
+	if *cacheFile != "" {
+		logEvaluationCacheStats(generator)
+		if err := saveEvaluationCache(generator, *cacheFile); err != nil {
+			slog.Warn("キャッシュを保存できません", "error", err)
+		}
+	}
+
+	if *search.por {
+		stats := generator.GetReductionStats()
+		slog.Info("半順序削減", "reducedStates", stats.ReducedStates, "prunedRules", stats.PrunedRules)
+	}
+
+	if *coverage {
+		fmt.Fprint(os.Stderr, formatCoverage(generator))
+	}
+
 	// フォーマッターの選択
-	var formatter core.Formatter
-	switch *outputFormat {
-	case "mermaid":
-		formatter = output.NewMermaidFormatter()
-	case "visjs":
-		formatter = output.NewVisjsFormatter()
-	case "dot":
-		formatter = output.NewDotFormatter()
-	default:
+	formatter, err := getFormatter(*outputFormat)
+	if err != nil {
 		slog.Error("未対応の出力形式", "format", *outputFormat)
 		os.Exit(1)
 	}
@@ -139,5 +244,12 @@ func main() {
 	}
 
 	// 結果の出力
+	if *outFile != "" {
+		if err := os.WriteFile(*outFile, []byte(result+"\n"), 0o644); err != nil {
+			slog.Error("出力ファイルの書き込みに失敗", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 	fmt.Println(result)
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+)
+
+// runMarkov ルールの重みを遷移確率とみなしてマルコフ連鎖として解析するサブコマンド
+func runMarkov(args []string) {
+	fs := flag.NewFlagSet("markov", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	target := fs.String("target", "", "到達確率を求める目標状態のexpr-lang式")
+	top := fs.Int("top", 10, "定常分布で表示する状態の数")
+
+	if len(args) < 1 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	fs.Parse(args[1:])
+
+	setupLogger(*common.logSeverity)
+
+	var targetQuery *query.Query
+	if *target != "" {
+		q, err := query.Compile(*target)
+		if err != nil {
+			slog.Error("目標状態の式が不正です", "error", err)
+			os.Exit(1)
+		}
+		targetQuery = q
+	}
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	limit := common.limit()
+	if !common.confirm(inputFile) {
+		os.Exit(0)
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if err := generator.Generate(); err != nil {
+		slog.Error("ステートマシンの生成に失敗", "error", err)
+		os.Exit(1)
+	}
+	if generator.IsPartial() {
+		// 展開されなかった状態は出力エッジを持たないため、吸収状態として扱われる（結果では「未展開」と表示する）
+		slog.Warn("反復回数の上限などにより展開されなかった状態があります。それらは吸収状態として計算されます", "frontier", len(generator.GetFrontierNodes()))
+	}
+
+	chain, err := core.NewMarkovChain(generator)
+	if err != nil {
+		slog.Error("マルコフ連鎖の作成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	var targetMatch func(*core.Node) bool
+	var matchErr error
+	if targetQuery != nil {
+		targetMatch = func(node *core.Node) bool {
+			matched, err := targetQuery.Match(node)
+			if err != nil && matchErr == nil {
+				matchErr = err
+			}
+			return matched
+		}
+	}
+
+	result := formatMarkov(chain, *target, targetMatch, *top)
+	if matchErr != nil {
+		slog.Warn("目標状態の式の評価に失敗した状態があります", "error", matchErr)
+	}
+	fmt.Print(result)
+}
+
+// formatMarkov 開始状態からの到達確率、期待ステップ数、定常分布を表示用に整形
+func formatMarkov(chain *core.MarkovChain, targetSource string, target func(*core.Node) bool, top int) string {
+	var b strings.Builder
+	states := chain.GetStates()
+	start := chain.GetStartIndex()
+	if start < 0 {
+		return "開始状態がありません\n"
+	}
+
+	var absorbing []int
+	frontier := 0
+	for i := range states {
+		if chain.IsAbsorbing(i) {
+			absorbing = append(absorbing, i)
+			if chain.IsFrontier(i) {
+				frontier++
+			}
+		}
+	}
+	if frontier > 0 {
+		fmt.Fprintf(&b, "状態数: %d (吸収状態: %d、うち探索を打ち切った未展開の状態: %d)\n", len(states), len(absorbing), frontier)
+		fmt.Fprintln(&b, "注意: グラフが不完全なため、未展開の状態を吸収状態とみなした値です")
+	} else {
+		fmt.Fprintf(&b, "状態数: %d (吸収状態: %d)\n", len(states), len(absorbing))
+	}
+
+	// 反復計算が収束しなかった値には印を付け、最後に注意を表示する
+	diverged := false
+	mark := func(converged bool) string {
+		if converged {
+			return ""
+		}
+		diverged = true
+		return " (未収束)"
+	}
+
+	fmt.Fprintln(&b, "開始状態からの解析:")
+	if target != nil {
+		probabilities, converged := chain.ReachProbability(target)
+		fmt.Fprintf(&b, "  目標状態 (%s) にいずれ到達する確率: %.6f%s\n", targetSource, probabilities[start], mark(converged))
+	}
+	absorption, converged := chain.AbsorptionProbability()
+	fmt.Fprintf(&b, "  吸収状態にいずれ到達する確率: %.6f%s\n", absorption[start], mark(converged))
+	steps, converged := chain.ExpectedSteps()
+	fmt.Fprintf(&b, "  吸収までの期待ステップ数: %.6f%s\n", steps[start], mark(converged))
+
+	if len(absorbing) > 0 {
+		fmt.Fprintln(&b, "吸収状態ごとの到達確率:")
+		for _, i := range absorbing {
+			state := states[i]
+			probabilities, converged := chain.ReachProbability(func(node *core.Node) bool {
+				return (*node).GetID() == (*state).GetID()
+			})
+			marker := ""
+			if chain.IsFrontier(i) {
+				marker = "[未展開] "
+			}
+			fmt.Fprintf(&b, "  %.6f%s  %s%s\n", probabilities[start], mark(converged), marker, strings.Join((*state).GetResourcesString(), ", "))
+		}
+	}
+
+	for n, class := range chain.RecurrentClasses() {
+		fmt.Fprintf(&b, "再帰類 %d (状態数: %d) の定常分布%s:\n", n+1, len(class.States), mark(class.Converged))
+		order := make([]int, len(class.States))
+		for k := range order {
+			order[k] = k
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return class.Stationary[order[a]] > class.Stationary[order[b]]
+		})
+		for rank, k := range order {
+			if rank >= top {
+				fmt.Fprintf(&b, "  ... 他 %d 状態\n", len(order)-top)
+				break
+			}
+			fmt.Fprintf(&b, "  %.6f  %s\n", class.Stationary[k], strings.Join((*class.States[k]).GetResourcesString(), ", "))
+		}
+	}
+	if diverged {
+		fmt.Fprintln(&b, "注意: (未収束) の値は反復計算が上限回数までに収束しなかった近似値です")
+	}
+	return b.String()
+}
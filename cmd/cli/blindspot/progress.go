@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+const (
+	// progressInterval 進み具合の表示を更新する間隔
+	progressInterval = 200 * time.Millisecond
+	// progressLogInterval 出力先が端末でない場合に、進み具合を1行ずつ書き出す間隔
+	progressLogInterval = 5 * time.Second
+)
+
+// progressObserver 探索の進み具合を1行で表示し続けるオブザーバー（-progress）
+// 反復回数の上限がある場合は、上限に対する展開済みの状態の割合をバーで表示する。
+// 出力先が端末でない場合（リダイレクトやCIのログ）は、制御文字を使わずに一定間隔で1行ずつ書き出す。
+type progressObserver struct {
+	core.NopObserver
+	out          io.Writer
+	terminal     bool // 出力先が端末で、行を書き換えられる
+	drawn        bool // 端末に改行していない進み具合の行が残っている
+	limit        *int64
+	resumed      int64 // チェックポイントから再開する前の反復回数（上限に対する割合に含める）
+	start        time.Time
+	last         time.Time
+	discovered   int64
+	expanded     int64
+	edges        int64
+	queued       int
+	limitReached bool
+}
+
+// newProgressObserver 進み具合をoutに表示するオブザーバーを作成
+func newProgressObserver(out io.Writer, limit *int64) *progressObserver {
+	now := time.Now()
+	return &progressObserver{out: out, terminal: isTerminal(out), limit: limit, start: now, last: now}
+}
+
+// isTerminal 出力先が端末（キャラクターデバイス）かどうか
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// OnNodeDiscovered core.GeneratorObserverの実装
+func (p *progressObserver) OnNodeDiscovered(*core.Node) {
+	p.discovered++
+}
+
+// OnEdgeAdded core.GeneratorObserverの実装
+func (p *progressObserver) OnEdgeAdded(*core.Edge) {
+	p.edges++
+}
+
+// OnNodeExpanded core.GeneratorObserverの実装（前回の表示から一定時間が経っていれば表示を更新する）
+func (p *progressObserver) OnNodeExpanded(_ *core.Node, _ []*core.Edge, queued int) {
+	p.expanded++
+	p.queued = queued
+	interval := progressInterval
+	if !p.terminal {
+		interval = progressLogInterval
+	}
+	if now := time.Now(); now.Sub(p.last) >= interval {
+		p.last = now
+		p.render(now)
+	}
+}
+
+// OnLimitReached core.GeneratorObserverの実装
+func (p *progressObserver) OnLimitReached(int64) {
+	p.limitReached = true
+}
+
+// finish 最後の進み具合を表示して改行する
+func (p *progressObserver) finish() {
+	p.render(time.Now())
+	if p.drawn {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// clear 端末に表示している進み具合の行を消す（次の更新で表示し直す）
+func (p *progressObserver) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// render 現在の進み具合で表示を書き換える
+func (p *progressObserver) render(now time.Time) {
+	elapsed := now.Sub(p.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.expanded) / elapsed.Seconds()
+	}
+	var line strings.Builder
+	if p.limit != nil && *p.limit > 0 {
+		// 反復回数には展開済みのノードの取り出しも含まれるため、上限に達した時点で100%とする
+		ratio := min(float64(p.resumed+p.expanded)/float64(*p.limit), 1)
+		if p.limitReached {
+			ratio = 1
+		}
+		filled := int(ratio * 20)
+		fmt.Fprintf(&line, "[%s%s] %3.0f%% ", strings.Repeat("#", filled), strings.Repeat("-", 20-filled), ratio*100)
+	}
+	fmt.Fprintf(&line, "状態 %d (展開 %d), 遷移 %d, キュー %d, %.0f 状態/秒, %s",
+		p.discovered, p.expanded, p.edges, p.queued, rate, elapsed.Round(100*time.Millisecond))
+	if p.limitReached {
+		line.WriteString(" (反復回数の上限に達しました)")
+	}
+	if !p.terminal {
+		fmt.Fprintln(p.out, line.String())
+		return
+	}
+	// 行頭に戻り、前回の表示を消してから書き込む
+	fmt.Fprintf(p.out, "\r\033[K%s", line.String())
+	p.drawn = true
+}
+
+// progressLogHandler ログを書き込む前に進み具合の行を消すslog.Handler
+// ログと進み具合が同じ端末の同じ行に混ざらないようにする
+type progressLogHandler struct {
+	slog.Handler
+	progress *progressObserver
+}
+
+// Handle slog.Handlerの実装
+func (h progressLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.progress.clear()
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs slog.Handlerの実装
+func (h progressLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return progressLogHandler{Handler: h.Handler.WithAttrs(attrs), progress: h.progress}
+}
+
+// WithGroup slog.Handlerの実装
+func (h progressLogHandler) WithGroup(name string) slog.Handler {
+	return progressLogHandler{Handler: h.Handler.WithGroup(name), progress: h.progress}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressWithoutTerminal(t *testing.T) {
+	var out bytes.Buffer
+	progress := newProgressObserver(&out, nil)
+	if progress.terminal {
+		t.Fatal("expected a buffer not to be treated as a terminal")
+	}
+	progress.render(time.Now())
+	progress.finish()
+	if strings.Contains(out.String(), "\r") || strings.Contains(out.String(), "\033") {
+		t.Errorf("expected no control characters outside a terminal, got %q", out.String())
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 2 {
+		t.Errorf("expected one line per update, got %d lines: %q", lines, out.String())
+	}
+}
+
+func TestProgressClearsBeforeLogs(t *testing.T) {
+	var out bytes.Buffer
+	limit := int64(10)
+	progress := newProgressObserver(&out, &limit)
+	progress.terminal = true
+	logger := slog.New(progressLogHandler{Handler: slog.NewTextHandler(&out, nil), progress: progress})
+
+	progress.render(time.Now())
+	logger.Error("limit reached")
+	// 進み具合の行を消してからログを書き、2回目のログの前には消すものがない
+	logger.Warn("interrupted")
+	got := out.String()
+	logAt := strings.Index(got, "time=")
+	if logAt < 0 || !strings.HasSuffix(got[:logAt], "\r\033[K") {
+		t.Errorf("expected the progress line to be cleared before the log, got %q", got)
+	}
+	if strings.Count(got, "\r\033[K") != 2 {
+		t.Errorf("expected one clear for the render and one before the first log, got %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+)
+
+// runReach 目標の状態へのパスを、見積もりの小さい状態から優先して探すサブコマンド
+func runReach(args []string) {
+	fs := flag.NewFlagSet("reach", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	targetSource := fs.String("target", "", "目標の状態を表すexpr-lang式（必須）")
+	heuristicSource := fs.String("heuristic", "", "目標までの遷移数を見積もるexpr-lang式（省略時は-targetの&&で区切った条件のうち成り立っていないものの数を、1つのルールが変えうる条件の最大数で割ったもの）")
+	greedy := fs.Bool("greedy", false, "見積もりだけで順位を付ける最良優先探索を行う（省略時はA*で、最短のパスを探す）")
+	por := fs.Bool("por", false, "半順序削減で、独立なルールの実行順の入れ替えを省く（到達できるかどうかは保存されるが、パスが最短とは限らない）")
+
+	if len(args) < 1 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	fs.Parse(args[1:])
+
+	setupLogger(*common.logSeverity)
+
+	if *targetSource == "" {
+		slog.Error("-target を指定してください")
+		os.Exit(1)
+	}
+	target, err := query.Compile(*targetSource)
+	if err != nil {
+		slog.Error("目標の式が不正です", "error", err)
+		os.Exit(1)
+	}
+	var evalErr error
+	options := core.ReachOptions{
+		Greedy: *greedy,
+		Target: func(node *core.Node) bool {
+			matched, err := target.Match(node)
+			if err != nil && evalErr == nil {
+				evalErr = err
+			}
+			return matched
+		},
+	}
+	if *heuristicSource != "" {
+		heuristic, err := query.Compile(*heuristicSource)
+		if err != nil {
+			slog.Error("見積もりの式が不正です", "error", err)
+			os.Exit(1)
+		}
+		options.Heuristic = func(node *core.Node) float64 {
+			distance, err := heuristic.Distance(node)
+			if err != nil && evalErr == nil {
+				evalErr = err
+			}
+			return distance
+		}
+	}
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+	if options.Heuristic == nil {
+		options.Heuristic, err = query.UnmetDistance(target, edgeRules)
+		if err != nil {
+			slog.Error("目標の式が不正です", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	limit := common.limit()
+	if !common.confirm(inputFile) {
+		os.Exit(0)
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if *por {
+		reduction, err := reductionOptions(*targetSource)
+		if err != nil {
+			slog.Error("-por を使用できません", "error", err)
+			os.Exit(1)
+		}
+		generator.SetPartialOrderReduction(reduction)
+	}
+	result, err := generator.SearchReach(options)
+	if err != nil {
+		slog.Error("目標の状態の探索に失敗", "error", err)
+		os.Exit(1)
+	}
+	if evalErr != nil {
+		slog.Warn("式の評価に失敗した状態があります。目標ではない（見積もりは0）として扱います", "error", evalErr)
+	}
+
+	fmt.Print(formatReach(result))
+	if !result.Found {
+		os.Exit(1)
+	}
+}
+
+// formatReach 目標の状態へのパスと探索の統計を表示用に整形
+func formatReach(result *core.ReachResult) string {
+	var b strings.Builder
+	switch {
+	case result.Found:
+		fmt.Fprintf(&b, "目標の状態に到達しました (%d 遷移)\n", len(result.Path))
+		writeTrace(&b, result.Node, result.Path)
+	case result.Truncated:
+		fmt.Fprintln(&b, "反復回数の上限により探索を打ち切りました。目標の状態は見つかっていません")
+	default:
+		fmt.Fprintln(&b, "目標の状態には到達できません（到達可能なすべての状態を調べました）")
+	}
+	fmt.Fprintf(&b, "展開した状態: %d, 発見した状態: %d\n", result.Expanded, result.Discovered)
+	return b.String()
+}
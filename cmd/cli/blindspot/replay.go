@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// traceLine 実行ログ（JSON Lines）の1行
+type traceLine struct {
+	Rule      string          `json:"rule"`
+	Resources json.RawMessage `json:"resources,omitempty"`
+}
+
+// runReplay 実行ログをモデルに照らして検査するサブコマンド
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	common := addCommonFlags(fs)
+
+	if len(args) < 2 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	traceFile := args[1]
+	fs.Parse(args[2:])
+
+	setupLogger(*common.logSeverity)
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	events, err := readTrace(traceFile, firstResources, newNode)
+	if err != nil {
+		slog.Error("実行ログの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	limit := common.limit()
+	if !common.confirm(inputFile) {
+		os.Exit(0)
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if err := generator.Generate(); err != nil {
+		slog.Error("ステートマシンの生成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	mismatch := generator.Replay(events)
+	if mismatch == nil {
+		fmt.Printf("全%d件のイベントがモデルと一致しました\n", len(events))
+		return
+	}
+
+	fmt.Print(formatMismatch(mismatch))
+	os.Exit(1)
+}
+
+// readTrace JSON Lines形式の実行ログを読み込む
+func readTrace(traceFile string, firstResources core.Node, newNode func(any) core.Node) ([]core.TraceEvent, error) {
+	file, err := os.Open(traceFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []core.TraceEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var line traceLine
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if line.Rule == "" {
+			return nil, fmt.Errorf("line %d: rule is required", lineNumber)
+		}
+
+		event := core.TraceEvent{Rule: line.Rule}
+		if len(line.Resources) > 0 && string(line.Resources) != "null" {
+			resources, err := core.DecodeResources(line.Resources, firstResources.GetResources())
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			event.Resources = newNode(resources)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// formatMismatch 食い違いを人が読める形式で表現
+func formatMismatch(mismatch *core.ReplayMismatch) string {
+	var report strings.Builder
+	switch mismatch.Kind {
+	case core.MismatchForbiddenTransition:
+		report.WriteString(fmt.Sprintf("イベント%d: ルール %q はモデル上で許可されていない遷移です\n", mismatch.Step, mismatch.Event.Rule))
+	case core.MismatchUnexpectedResources:
+		report.WriteString(fmt.Sprintf("イベント%d: ルール %q の実行後のリソースをモデルが予測していません\n", mismatch.Step, mismatch.Event.Rule))
+		report.WriteString(fmt.Sprintf("  観測: %s\n", strings.Join(mismatch.Event.Resources.GetResourcesString(), ", ")))
+		for _, node := range mismatch.Expected {
+			report.WriteString(fmt.Sprintf("  予測: %s\n", strings.Join((*node).GetResourcesString(), ", ")))
+		}
+	case core.MismatchUnexplored:
+		report.WriteString(fmt.Sprintf("イベント%d: ルール %q の手前で探索を打ち切ったため、モデルと一致するか判断できません（-limitを増やしてください）\n", mismatch.Step, mismatch.Event.Rule))
+	}
+	for _, node := range mismatch.Current {
+		report.WriteString(fmt.Sprintf("  直前の状態: %s\n", strings.Join((*node).GetResourcesString(), ", ")))
+	}
+	if len(mismatch.Allowed) == 0 {
+		report.WriteString("  実行可能なルール: なし\n")
+	} else {
+		report.WriteString(fmt.Sprintf("  実行可能なルール: %s\n", strings.Join(mismatch.Allowed, ", ")))
+	}
+	return report.String()
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+)
+
+//go:embed static/index.html
+var staticFiles embed.FS
+
+// maxRuleFileSize アップロードできるルールファイルの最大サイズ
+const maxRuleFileSize = 10 << 20
+
+// server ルールと生成結果を保持するHTTPサーバー
+type server struct {
+	mu           sync.Mutex
+	defaultLimit int64
+
+	inputFormat    string
+	firstResources core.Node
+	newNode        func(any) core.Node
+	edgeRules      []*core.EdgeRule
+	generator      *core.Generator
+}
+
+// pathStep パスの1ステップのJSON表現
+type pathStep struct {
+	Rule      string   `json:"rule"`
+	Resources []string `json:"resources"`
+}
+
+// runServe JSON APIとビューアーを提供するHTTPサーバーのサブコマンド
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "待ち受けるアドレス")
+	logSeverity := fs.String("log-severity", "warn", "ログの重大度 (debug, info, warn, error)")
+	defaultLimit := fs.Int64("limit", 10000, "反復回数の上限（limitパラメーターの省略時の値と、指定できる最大値）")
+	fs.Parse(args)
+
+	setupLogger(*logSeverity)
+
+	s := &server{defaultLimit: *defaultLimit}
+	fmt.Printf("http://%s で待ち受けています\n", *addr)
+	if err := http.ListenAndServe(*addr, s.routes()); err != nil {
+		slog.Error("サーバーの起動に失敗", "error", err)
+		os.Exit(1)
+	}
+}
+
+// routes エンドポイントを登録したハンドラーを作成
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /api/formats", s.handleFormats)
+	mux.HandleFunc("POST /api/rules", s.handleRules)
+	mux.HandleFunc("POST /api/generate", s.handleGenerate)
+	mux.HandleFunc("GET /api/graph", s.handleGraph)
+	mux.HandleFunc("GET /api/path", s.handlePath)
+	mux.HandleFunc("GET /api/invariant", s.handleInvariant)
+	return mux
+}
+
+// handleIndex 同梱のビューアーを返す
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	page, err := staticFiles.ReadFile("static/index.html")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// handleFormats 対応している入力形式と出力形式を返す
+func (s *server) handleFormats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"inputs":  getSupportedFormats(),
+		"outputs": getSupportedOutputFormats(),
+	})
+}
+
+// handleRules リクエストボディのルール定義をパースして保持する
+// ?input= で入力形式を指定する（省略時は ?filename= の拡張子と内容から自動判定）
+// サーバーのファイルやコマンドを使わせないよう、組み込みの入力形式のみを受け付け、cudのincludeは使えない
+func (s *server) handleRules(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRuleFileSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("rule file must be at most %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	inputFormat := r.URL.Query().Get("input")
+	if inputFormat == "" {
+		inputFormat, err = detectInputFormat(r.URL.Query().Get("filename"), string(body))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var firstResources core.Node
+	var newNode func(any) core.Node
+	var edgeRules []*core.EdgeRule
+	err = recoverPanic(func() error {
+		var err error
+		firstResources, newNode, edgeRules, err = parseUploadedRules(string(body), inputFormat)
+		return err
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputFormat = inputFormat
+	s.firstResources = firstResources
+	s.newNode = newNode
+	s.edgeRules = edgeRules
+	s.generator = nil
+
+	names := make([]string, 0, len(edgeRules))
+	for _, rule := range edgeRules {
+		names = append(names, rule.GetName())
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"input": inputFormat,
+		"rules": names,
+	})
+}
+
+// parseUploadedRules アップロードされたルールを組み込みのパーサーでパースする
+// PATH上のパーサープラグインは使わず、includeなど他のファイルを読む指定はエラーにする
+func parseUploadedRules(content string, inputFormat string) (
+	firstResources core.Node,
+	newNode func(any) core.Node,
+	edgeRules []*core.EdgeRule,
+	err error,
+) {
+	if _, ok := core.LookupParser(inputFormat); !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported input format: %s", inputFormat)
+	}
+	parser, err := getRegisteredParser(inputFormat)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if disabler, ok := parser.(core.IncludeDisabler); ok {
+		disabler.DisableInclude()
+	}
+	return parser.Parse(content)
+}
+
+// handleGenerate 保持しているルールからステートマシンを生成する
+// ?limit= で反復回数の上限を指定する（省略時、またはサーバー起動時の-limitより大きい場合は-limit）
+func (s *server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	limit := s.defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer: %s", value))
+			return
+		}
+		limit = min(parsed, s.defaultLimit)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.edgeRules == nil {
+		writeError(w, http.StatusConflict, errors.New("rules are not uploaded"))
+		return
+	}
+
+	generator := core.NewGenerator(s.newNode, s.firstResources, s.edgeRules, &limit)
+	if err := recoverPanic(generator.Generate); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	s.generator = generator
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"nodes":     len(generator.GetNodes()),
+		"edges":     len(generator.GetEdges()),
+		"deadlocks": len(generator.GetDeadlockNodes()),
+		"frontier":  len(generator.GetFrontierNodes()),
+	})
+}
+
+// handleGraph 生成結果を指定された形式で返す
+// ?format= で出力形式を指定する（省略時はjson）。PATH上のフォーマッタープラグインは使わない
+func (s *server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	outputFormat := r.URL.Query().Get("format")
+	if outputFormat == "" {
+		outputFormat = "json"
+	}
+	registration, ok := core.LookupFormatter(outputFormat)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported output format: %s", outputFormat))
+		return
+	}
+	formatter, err := registration.New()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generator == nil {
+		writeError(w, http.StatusConflict, errors.New("graph is not generated"))
+		return
+	}
+	result, err := formatter.Format(s.generator)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if outputFormat == "json" {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	io.WriteString(w, result)
+}
+
+// handlePath 開始ノードから ?target= のexpr-lang式を満たすノードまでの最短パスを返す
+func (s *server) handlePath(w http.ResponseWriter, r *http.Request) {
+	q, err := query.Compile(r.URL.Query().Get("target"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generator == nil {
+		writeError(w, http.StatusConflict, errors.New("graph is not generated"))
+		return
+	}
+
+	var queryErr error
+	path, found := s.generator.FindPath(func(node *core.Node) bool {
+		matched, err := q.Match(node)
+		if err != nil && queryErr == nil {
+			queryErr = err
+		}
+		return matched
+	})
+	if queryErr != nil {
+		writeError(w, http.StatusBadRequest, queryErr)
+		return
+	}
+
+	response := map[string]any{"found": found}
+	if found {
+		response["steps"] = pathSteps(path)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleInvariant ?expr= のexpr-lang式をすべてのノードで満たすか検査し、違反するノードと最短パスを返す
+func (s *server) handleInvariant(w http.ResponseWriter, r *http.Request) {
+	q, err := query.Compile(r.URL.Query().Get("expr"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generator == nil {
+		writeError(w, http.StatusConflict, errors.New("graph is not generated"))
+		return
+	}
+
+	var queryErr error
+	paths := s.generator.FindAllPaths(func(node *core.Node) bool {
+		holds, err := q.Match(node)
+		if err != nil && queryErr == nil {
+			queryErr = err
+		}
+		return !holds
+	})
+	if queryErr != nil {
+		writeError(w, http.StatusBadRequest, queryErr)
+		return
+	}
+
+	violations := make([]map[string]any, 0, len(paths))
+	for _, path := range paths {
+		resources := (*s.generator.GetStartNode()).GetResourcesString()
+		if len(path) > 0 {
+			resources = (*path[len(path)-1].GetTo()).GetResourcesString()
+		}
+		violations = append(violations, map[string]any{
+			"resources": resources,
+			"steps":     pathSteps(path),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"holds":      len(violations) == 0,
+		"violations": violations,
+	})
+}
+
+// pathSteps パスをJSON表現に変換
+func pathSteps(path core.TransitionPath) []pathStep {
+	steps := make([]pathStep, 0, len(path))
+	for _, edge := range path {
+		steps = append(steps, pathStep{
+			Rule:      edge.GetLabel(),
+			Resources: (*edge.GetTo()).GetResourcesString(),
+		})
+	}
+	return steps
+}
+
+// recoverPanic パースや生成中のpanic（式の不正など）をエラーとして返す
+func recoverPanic(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return f()
+}
+
+// writeJSON JSONレスポンスを書き込む
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Error("レスポンスの書き込みに失敗", "error", err)
+	}
+}
+
+// writeError エラーレスポンスを書き込む
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
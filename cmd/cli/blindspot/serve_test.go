@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeRules(t *testing.T) {
+	s := &server{defaultLimit: 100}
+	handler := s.routes()
+	post := func(target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	if response := post("/api/rules?input=cud", testRules); response.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", response.Code, response.Body)
+	}
+
+	// 上限を超えるボディは切り詰めてパースせず、413を返す
+	tooLarge := testRules + "# " + strings.Repeat("x", maxRuleFileSize) + "\n"
+	if response := post("/api/rules?input=cud", tooLarge); response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a body over the limit, got %d", response.Code)
+	}
+
+	// includeでサーバーのファイルを読ませない
+	secret := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(secret, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	response := post("/api/rules?input=cud", "include:\n  - "+secret+"\n")
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "include is not allowed") {
+		t.Errorf("expected include to be rejected, got %d: %s", response.Code, response.Body)
+	}
+
+	// PATH上のパーサープラグインは使わない
+	if response := post("/api/rules?input=evil", testRules); response.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown input format to be rejected, got %d", response.Code)
+	}
+}
+
+func TestServeGenerateLimit(t *testing.T) {
+	s := &server{defaultLimit: 1}
+	handler := s.routes()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/rules?input=cud", strings.NewReader(testRules)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	// -limitより大きいlimitは-limitに抑える
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/generate?limit=1000000", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"frontier":1`) {
+		t.Errorf("expected generation to stop at the server limit, got %d: %s", recorder.Code, recorder.Body)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+)
+
+// simulator ルールを1ステップずつ適用する対話型シミュレーター
+type simulator struct {
+	rules   []*core.EdgeRule
+	start   *core.Node
+	current *core.Node
+	history []simulatorStep
+	saved   map[string]simulatorState
+	out     io.Writer
+}
+
+// simulatorStep undoのために記録する1ステップ分の履歴
+type simulatorStep struct {
+	rule string
+	node *core.Node
+}
+
+// simulatorState saveで保存した状態
+type simulatorState struct {
+	current *core.Node
+	history []simulatorStep
+}
+
+// runSim 対話型シミュレーターのサブコマンド
+func runSim(args []string) {
+	fs := flag.NewFlagSet("sim", flag.ExitOnError)
+	common := addCommonFlags(fs)
+
+	if len(args) < 1 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	fs.Parse(args[1:])
+
+	setupLogger(*common.logSeverity)
+
+	if inputFile == stdinInputFile {
+		slog.Error("sim は標準入力でコマンドを受け付けるため、標準入力からルールを読み込めません")
+		os.Exit(1)
+	}
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	startNode := newNode(firstResources.GetResources())
+	sim := &simulator{
+		rules:   edgeRules,
+		start:   &startNode,
+		current: &startNode,
+		saved:   make(map[string]simulatorState),
+		out:     os.Stdout,
+	}
+	sim.run(os.Stdin)
+}
+
+// simulatorCommands シミュレーターのコマンド名（同じ名前のルールはfire <ルール名>で選ぶ）
+var simulatorCommands = []string{"quit", "exit", "help", "show", "undo", "reset", "save", "load", "states", "trail", "eval", "fire"}
+
+// run 入力を1行ずつ読み込んでコマンドを実行
+func (s *simulator) run(in io.Reader) {
+	fmt.Fprintln(s.out, "helpでコマンド一覧を表示します")
+	s.warnCommandNames()
+	evaluations := s.show()
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(s.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		command, argument, _ := strings.Cut(line, " ")
+		argument = strings.TrimSpace(argument)
+		switch command {
+		case "quit", "exit":
+			return
+		case "help":
+			s.help()
+			continue
+		case "show":
+		case "undo":
+			if len(s.history) == 0 {
+				fmt.Fprintln(s.out, "これ以上戻せません")
+				continue
+			}
+			s.current = s.history[len(s.history)-1].node
+			s.history = s.history[:len(s.history)-1]
+		case "reset":
+			s.current = s.start
+			s.history = nil
+		case "save":
+			if argument == "" {
+				fmt.Fprintln(s.out, "使い方: save <名前>")
+				continue
+			}
+			s.saved[argument] = simulatorState{current: s.current, history: append([]simulatorStep(nil), s.history...)}
+			fmt.Fprintf(s.out, "現在の状態を %q として保存しました\n", argument)
+			continue
+		case "load":
+			state, ok := s.saved[argument]
+			if !ok {
+				fmt.Fprintf(s.out, "保存された状態 %q はありません\n", argument)
+				continue
+			}
+			s.current = state.current
+			s.history = append([]simulatorStep(nil), state.history...)
+		case "states":
+			s.listSaved()
+			continue
+		case "trail":
+			s.showTrail()
+			continue
+		case "eval":
+			s.eval(argument)
+			continue
+		case "fire":
+			if argument == "" {
+				fmt.Fprintln(s.out, "使い方: fire <番号 | ルール名>")
+				continue
+			}
+			if !s.fireRule(argument, evaluations) {
+				continue
+			}
+		default:
+			if !s.fireRule(line, evaluations) {
+				continue
+			}
+		}
+		evaluations = s.show()
+	}
+}
+
+// warnCommandNames コマンドと同じ名前（または先頭の単語がコマンド名）のルールがあれば、fireで選ぶよう案内する
+func (s *simulator) warnCommandNames() {
+	for _, rule := range s.rules {
+		command, _, _ := strings.Cut(rule.GetName(), " ")
+		if slices.Contains(simulatorCommands, command) {
+			fmt.Fprintf(s.out, "注意: ルール %q はコマンドと名前が重なるため、番号か fire %s で適用してください\n", rule.GetName(), rule.GetName())
+		}
+	}
+}
+
+// fireRule 番号またはラベルで指定したルールを適用する（適用できた場合はtrue）
+func (s *simulator) fireRule(selector string, evaluations []core.RuleEvaluation) bool {
+	choice, ok := s.findRule(selector, evaluations)
+	if !ok {
+		fmt.Fprintf(s.out, "実行可能なルール %q はありません\n", selector)
+		return false
+	}
+	if err := s.fire(choice); err != nil {
+		fmt.Fprintf(s.out, "ルールの適用に失敗: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// show 現在のリソースとルールの評価結果を表示し、評価結果を返す
+// 評価に失敗した場合はエラーを表示してnilを返す（undoやresetで別の状態へ移れる）
+func (s *simulator) show() []core.RuleEvaluation {
+	fmt.Fprintf(s.out, "\n[ステップ %d] 現在のリソース:\n", len(s.history))
+	for _, resource := range (*s.current).GetResourcesString() {
+		fmt.Fprintf(s.out, "  %s\n", resource)
+	}
+
+	evaluations, err := s.evaluate()
+	if err != nil {
+		fmt.Fprintf(s.out, "ルールの評価に失敗: %v\n", err)
+		return nil
+	}
+	selected, suppressed := core.SelectByPriority(evaluations)
+	fmt.Fprintln(s.out, "実行可能なルール:")
+	for i, choice := range simulatorChoices(selected) {
+		fmt.Fprintf(s.out, "  %d) %s\n", i+1, choice.label())
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(s.out, "  なし（デッドロック）")
+	}
+	if len(suppressed) > 0 {
+		fmt.Fprintln(s.out, "優先度により抑制されたルール:")
+		for _, evaluation := range suppressed {
+			fmt.Fprintf(s.out, "  - %s (priority: %d)\n", evaluation.Rule.GetName(), evaluation.Rule.GetPriority())
+		}
+	}
+
+	var blocked []core.RuleEvaluation
+	var notFired []string
+	for _, evaluation := range evaluations {
+		switch {
+		case evaluation.Enabled():
+		case evaluation.Block:
+			blocked = append(blocked, evaluation)
+		default:
+			notFired = append(notFired, evaluation.Rule.GetName())
+		}
+	}
+	if len(blocked) > 0 {
+		fmt.Fprintln(s.out, "ブロックされたルール:")
+		for _, evaluation := range blocked {
+			fmt.Fprintf(s.out, "  - %s (block: %s)\n", evaluation.Rule.GetName(), evaluation.Rule.BlockConditionText)
+		}
+	}
+	if len(notFired) > 0 {
+		fmt.Fprintf(s.out, "発火条件を満たさないルール: %s\n", strings.Join(notFired, ", "))
+	}
+	return evaluations
+}
+
+// simulatorChoice 適用できるルールとその結果の組
+type simulatorChoice struct {
+	rule    *core.EdgeRule
+	outcome *core.Outcome
+}
+
+// label 表示と選択に使う名前（複数の結果を持つルールは「ルール名/結果名」）
+func (c simulatorChoice) label() string {
+	if c.outcome.Name == "" {
+		return c.rule.GetName()
+	}
+	return c.rule.GetName() + "/" + c.outcome.Name
+}
+
+// simulatorChoices 実行可能なルールを結果ごとの選択肢に展開
+func simulatorChoices(selected []core.RuleEvaluation) []simulatorChoice {
+	var choices []simulatorChoice
+	for _, evaluation := range selected {
+		for _, outcome := range evaluation.Rule.GetOutcomes() {
+			choices = append(choices, simulatorChoice{rule: evaluation.Rule, outcome: outcome})
+		}
+	}
+	return choices
+}
+
+// findRule 番号またはラベルから実行可能なルールとその結果を探す
+func (s *simulator) findRule(selector string, evaluations []core.RuleEvaluation) (simulatorChoice, bool) {
+	selected, _ := core.SelectByPriority(evaluations)
+	choices := simulatorChoices(selected)
+	if index, err := strconv.Atoi(selector); err == nil {
+		if index < 1 || index > len(choices) {
+			return simulatorChoice{}, false
+		}
+		return choices[index-1], true
+	}
+	for _, choice := range choices {
+		if choice.label() == selector {
+			return choice, true
+		}
+	}
+	return simulatorChoice{}, false
+}
+
+// evaluate 現在の状態でルールの条件を評価する
+func (s *simulator) evaluate() (evaluations []core.RuleEvaluation, err error) {
+	// 条件の式は型の不一致などでpanicするため、シミュレーターを終了させずにエラーとして扱う
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return core.EvaluateRules(s.current, s.rules), nil
+}
+
+// fire ルールの結果を適用して現在の状態を進める
+func (s *simulator) fire(choice simulatorChoice) (err error) {
+	// Effectは型の不一致などでpanicするため、シミュレーターを終了させずにエラーとして扱う
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	next := choice.outcome.Effect(s.current)
+	s.history = append(s.history, simulatorStep{rule: choice.label(), node: s.current})
+	s.current = next
+	return nil
+}
+
+// eval 現在のリソースに対してexpr-lang式を評価して表示
+func (s *simulator) eval(source string) {
+	if source == "" {
+		fmt.Fprintln(s.out, "使い方: eval <式>")
+		return
+	}
+	q, err := query.Compile(source)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	result, err := q.Run(s.current)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	fmt.Fprintf(s.out, "%v\n", result)
+}
+
+// listSaved 保存された状態の一覧を表示
+func (s *simulator) listSaved() {
+	if len(s.saved) == 0 {
+		fmt.Fprintln(s.out, "保存された状態はありません")
+		return
+	}
+	names := make([]string, 0, len(s.saved))
+	for name := range s.saved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "  %s: %s\n", name, strings.Join((*s.saved[name].current).GetResourcesString(), ", "))
+	}
+}
+
+// showTrail 開始状態から現在までに適用したルールを表示
+func (s *simulator) showTrail() {
+	if len(s.history) == 0 {
+		fmt.Fprintln(s.out, "まだルールを適用していません")
+		return
+	}
+	rules := make([]string, 0, len(s.history))
+	for _, step := range s.history {
+		rules = append(rules, step.rule)
+	}
+	fmt.Fprintln(s.out, strings.Join(rules, " -> "))
+}
+
+// help コマンド一覧を表示
+func (s *simulator) help() {
+	fmt.Fprintln(s.out, `コマンド:
+  <番号> | <ルール名>  実行可能なルールを適用（複数の結果を持つルールは <ルール名>/<結果名>）
+  fire <ルール名>     コマンドと同じ名前のルールを適用
+  undo                直前の適用を取り消す
+  reset               開始状態に戻る
+  save <名前>         現在の状態を保存
+  load <名前>         保存した状態へ移動
+  states              保存した状態の一覧
+  trail               適用したルールの履歴
+  eval <式>           現在のリソースに対してexpr-lang式を評価
+  show                現在の状態を再表示
+  quit | exit         終了`)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// runSimulator ルールを読み込んだシミュレーターに入力を与え、表示された内容を返す
+func runSimulator(t *testing.T, rules string, input string) string {
+	t.Helper()
+	firstResources, newNode, edgeRules, _, err := parseRules(rules, "cud", "")
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	startNode := newNode(firstResources.GetResources())
+	var out strings.Builder
+	sim := &simulator{
+		rules:   edgeRules,
+		start:   &startNode,
+		current: &startNode,
+		saved:   make(map[string]simulatorState),
+		out:     &out,
+	}
+	sim.run(strings.NewReader(input))
+	return out.String()
+}
+
+func TestSimulatorCommandNames(t *testing.T) {
+	rules := `
+start_resources:
+  status: "draft"
+edge_rules:
+  - name: undo
+    fire_condition: status == "draft"
+    effect:
+      - action: update
+        resource: {key: status, value: "reverted"}
+`
+	out := runSimulator(t, rules, "undo\nfire undo\ntrail\n")
+	if !strings.Contains(out, `ルール "undo" はコマンドと名前が重なる`) {
+		t.Errorf("expected a warning for a rule named like a command, got:\n%s", out)
+	}
+	// undoはコマンドとして扱われ、fire undoでルールが適用される
+	if !strings.Contains(out, "これ以上戻せません") || !strings.Contains(out, `status:"reverted"`) {
+		t.Errorf("expected undo to be a command and fire undo to apply the rule, got:\n%s", out)
+	}
+}
+
+func TestSimulatorEvaluationPanic(t *testing.T) {
+	// 文字列に数を足す発火条件は評価時にpanicする
+	rules := `
+start_resources:
+  status: "draft"
+edge_rules:
+  - name: broken
+    fire_condition: status + 1 > 0
+    effect:
+      - action: delete
+        resource: {key: status}
+`
+	out := runSimulator(t, rules, "show\n")
+	if strings.Count(out, "ルールの評価に失敗") != 2 {
+		t.Errorf("expected the evaluation error to be reported on start and on show, got:\n%s", out)
+	}
+}
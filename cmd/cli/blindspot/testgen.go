@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/output"
+)
+
+// runTestgen 全遷移を網羅するテストケースを生成するサブコマンド
+func runTestgen(args []string) {
+	fs := flag.NewFlagSet("testgen", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	testFormat := fs.String("format", "go", "テストケースの出力形式 (go, json)")
+	packageName := fs.String("package", "main_test", "Goテストのパッケージ名")
+
+	if len(args) < 1 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	fs.Parse(args[1:])
+
+	setupLogger(*common.logSeverity)
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	var formatter core.Formatter
+	switch *testFormat {
+	case "go":
+		formatter = output.NewGoTestFormatter(*packageName)
+	case "json":
+		formatter = output.NewTestCaseJsonFormatter()
+	default:
+		slog.Error("未対応のテストケース形式", "format", *testFormat)
+		os.Exit(1)
+	}
+
+	limit := common.limit()
+	if !common.confirm(inputFile) {
+		os.Exit(0)
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, limit)
+	if err := generator.Generate(); err != nil {
+		slog.Error("ステートマシンの生成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	result, err := formatter.Format(generator)
+	if err != nil {
+		slog.Error("テストケースの生成に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(result)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
+)
+
+// runWalk グラフを保持せずにランダムウォークを繰り返し、統計を表示するサブコマンド
+func runWalk(args []string) {
+	fs := flag.NewFlagSet("walk", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	runs := fs.Int("runs", 1000, "ウォークの回数")
+	steps := fs.Int("steps", 100, "1回のウォークの最大の遷移数")
+	seed := fs.Uint64("seed", 1, "乱数の種")
+	target := fs.String("target", "", "到達したウォークを数える状態のexpr-lang式")
+	top := fs.Int("top", 10, "表示するデッドロックの数")
+
+	if len(args) < 1 {
+		fmt.Println(getCommandDefinition())
+		os.Exit(1)
+	}
+	inputFile := args[0]
+	fs.Parse(args[1:])
+
+	setupLogger(*common.logSeverity)
+
+	options := core.WalkOptions{Runs: *runs, Steps: *steps, Seed: *seed}
+	var matchErr error
+	if *target != "" {
+		targetQuery, err := query.Compile(*target)
+		if err != nil {
+			slog.Error("目標状態の式が不正です", "error", err)
+			os.Exit(1)
+		}
+		options.Target = func(node *core.Node) bool {
+			matched, err := targetQuery.Match(node)
+			if err != nil && matchErr == nil {
+				matchErr = err
+			}
+			return matched
+		}
+	}
+
+	firstResources, newNode, edgeRules, err := loadRules(inputFile, *common.inputFormat)
+	if err != nil {
+		slog.Error("ルールの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	// ウォークの遷移数は --runs と --steps で決まるため、反復回数の上限は確認しない
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, nil)
+	result, err := generator.Walk(options)
+	if err != nil {
+		slog.Error("ランダムウォークに失敗", "error", err)
+		os.Exit(1)
+	}
+	if matchErr != nil {
+		slog.Warn("目標状態の式の評価に失敗した状態があります。それらは目標ではないものとして扱います", "error", matchErr)
+	}
+
+	fmt.Print(formatWalk(result, *target, *top))
+}
+
+// formatWalk ランダムウォークの統計を表示用に整形
+func formatWalk(result *core.WalkResult, target string, top int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ランダムウォーク: %d 回, 最大 %d 遷移, seed %d\n", result.Runs, result.Steps, result.Seed)
+	fmt.Fprintf(&b, "遷移数: 合計 %d (平均 %.1f), 訪問した状態: %d\n",
+		result.TotalSteps, float64(result.TotalSteps)/float64(result.Runs), result.DistinctStates)
+
+	fmt.Fprintln(&b, "ルールの実行回数:")
+	nameWidth := 0
+	for _, firing := range result.RuleFirings {
+		nameWidth = max(nameWidth, len(firing.Rule.GetName()))
+	}
+	for _, firing := range result.RuleFirings {
+		ratio := 0.0
+		if result.TotalSteps > 0 {
+			ratio = float64(firing.Count) / float64(result.TotalSteps) * 100
+		}
+		note := ""
+		if firing.Count == 0 {
+			note = "  (一度も実行されていません)"
+		}
+		fmt.Fprintf(&b, "  %-*s %8d %6.2f%%%s\n", nameWidth, firing.Rule.GetName(), firing.Count, ratio, note)
+	}
+
+	deadlockRuns := 0
+	for _, deadlock := range result.Deadlocks {
+		deadlockRuns += deadlock.Hits
+	}
+	fmt.Fprintf(&b, "デッドロック: %d 状態 (%d 回のウォークが到達, %.2f%%)\n",
+		len(result.Deadlocks), deadlockRuns, float64(deadlockRuns)/float64(result.Runs)*100)
+	deadlocks := append([]*core.WalkDeadlock(nil), result.Deadlocks...)
+	sort.SliceStable(deadlocks, func(i, j int) bool { return deadlocks[i].Hits > deadlocks[j].Hits })
+	for i, deadlock := range deadlocks {
+		if i >= top {
+			fmt.Fprintf(&b, "  ... 他 %d 状態\n", len(deadlocks)-top)
+			break
+		}
+		fmt.Fprintf(&b, "  %d 回\n", deadlock.Hits)
+		writeTrace(&b, deadlock.Node, deadlock.Path)
+	}
+
+	if target != "" {
+		fmt.Fprintf(&b, "%s に到達したウォーク: %d 回 (%.2f%%)\n", target, len(result.Reached), float64(len(result.Reached))/float64(result.Runs)*100)
+		if len(result.Reached) > 0 {
+			total := 0
+			for _, reach := range result.Reached {
+				total += reach.Steps
+			}
+			fmt.Fprintf(&b, "  到達までの遷移数: 最短 %d, 平均 %.1f\n", result.Shortest.Steps, float64(total)/float64(len(result.Reached)))
+			fmt.Fprintf(&b, "  最短のウォーク (#%d):\n", result.Shortest.Run)
+			writeTrace(&b, result.Shortest.Node, result.Shortest.Path)
+		}
+	}
+	return b.String()
+}
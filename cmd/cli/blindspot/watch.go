@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// watchConfig 監視モードの設定
+type watchConfig struct {
+	inputFile    string
+	inputFormat  string
+	outputFormat string
+	outFile      string
+	limit        *int64
+	interval     time.Duration
+	grouper      func(*core.Node) []string // -group-byで指定したグループ分け（nilの場合は入力形式の指定に従う）
+	search       *searchFlags              // 探索の順序と深さの上限
+	cacheFile    string                    // 評価結果を保存するキャッシュファイル（空の場合はメモリ上でのみ引き継ぐ）
+}
+
+// watchSummary 前回の生成結果との差分を求めるための集計
+type watchSummary struct {
+	nodes     int
+	edges     int
+	deadlocks map[string][]string // ノードID -> リソースの文字列表現
+	cache     core.EvaluationCacheStats
+	includes  []string // includeで取り込んだファイル（次回から監視する）
+}
+
+// runWatch 入力ファイルとincludeで取り込んだファイルの変更を監視し、変更のたびに再生成して出力ファイルを書き換える
+// 取り込むファイルは再生成のたびに解決し直す（再生成に失敗した場合は前回のものを監視し続ける）
+func runWatch(config watchConfig) {
+	fmt.Printf("%s を監視しています（Ctrl-Cで終了）\n", config.inputFile)
+
+	var lastState string
+	var includes []string
+	var previous *watchSummary
+	// 前回の評価結果を引き継ぎ、変更されたルールだけを評価し直す
+	cache := &core.EvaluationCache{}
+	if config.cacheFile != "" {
+		cache = loadEvaluationCache(config.cacheFile)
+	}
+	for {
+		if _, err := os.Stat(config.inputFile); err != nil {
+			slog.Warn("入力ファイルの確認に失敗", "error", err)
+		} else if state := watchedFilesState(append([]string{config.inputFile}, includes...)); state != lastState {
+			lastState = state
+
+			summary, next, err := regenerate(config, cache)
+			if err != nil {
+				fmt.Printf("[%s] 再生成に失敗: %v\n", time.Now().Format("15:04:05"), err)
+			} else {
+				fmt.Printf("[%s] %s", time.Now().Format("15:04:05"), summary.diff(previous))
+				previous = summary
+				cache = next
+				if !slices.Equal(summary.includes, includes) {
+					includes = summary.includes
+					// 監視するファイルが増減した場合は、その時点の状態を基準にする
+					lastState = watchedFilesState(append([]string{config.inputFile}, includes...))
+				}
+			}
+		}
+		time.Sleep(config.interval)
+	}
+}
+
+// watchedFilesState 監視するファイルの更新日時とサイズをつなげた文字列（いずれかが変わると異なる値になる）
+// 確認できないファイル（削除されたincludeなど）も状態に含め、再生成してエラーを報告できるようにする
+func watchedFilesState(files []string) string {
+	var state strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			fmt.Fprintf(&state, "%s:missing\n", file)
+			continue
+		}
+		fmt.Fprintf(&state, "%s:%d:%d\n", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return state.String()
+}
+
+// regenerate ルールを読み込み直して生成・出力し、集計と次回のためのキャッシュを返す
+// ルールの編集途中では式のコンパイルや評価でpanicすることがあるため、エラーとして扱って監視を続ける
+func regenerate(config watchConfig, cache *core.EvaluationCache) (summary *watchSummary, next *core.EvaluationCache, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	firstResources, newNode, edgeRules, includes, err := loadRuleSources(config.inputFile, config.inputFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generator := core.NewGenerator(newNode, firstResources, edgeRules, config.limit)
+	if config.grouper != nil {
+		generator.SetNodeGrouper(config.grouper)
+	}
+	if err := config.search.configure(generator); err != nil {
+		return nil, nil, err
+	}
+	generator.SetEvaluationCache(cache)
+	if err := generator.Generate(); err != nil {
+		return nil, nil, fmt.Errorf("ステートマシンの生成に失敗: %w", err)
+	}
+
+	formatter, err := getFormatter(config.outputFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := formatter.Format(generator)
+	if err != nil {
+		return nil, nil, fmt.Errorf("出力の生成に失敗: %w", err)
+	}
+	if err := os.WriteFile(config.outFile, []byte(result+"\n"), 0o644); err != nil {
+		return nil, nil, fmt.Errorf("出力ファイルの書き込みに失敗: %w", err)
+	}
+
+	next = generator.EvaluationCache()
+	if config.cacheFile != "" {
+		if err := saveEvaluationCache(generator, config.cacheFile); err != nil {
+			slog.Warn("キャッシュを保存できません", "error", err)
+		}
+	}
+
+	summary = &watchSummary{
+		nodes:     len(generator.GetNodes()),
+		edges:     len(generator.GetEdges()),
+		deadlocks: make(map[string][]string),
+		cache:     generator.GetEvaluationCacheStats(),
+		includes:  includes,
+	}
+	for _, node := range generator.GetDeadlockNodes() {
+		summary.deadlocks[(*node).GetID()] = (*node).GetResourcesString()
+	}
+	return summary, next, nil
+}
+
+// diff 前回の集計との差分を表現（初回はpreviousにnilを渡す）
+func (s *watchSummary) diff(previous *watchSummary) string {
+	var report strings.Builder
+	if previous == nil {
+		report.WriteString(fmt.Sprintf("生成しました: ノード %d, エッジ %d, デッドロック %d\n", s.nodes, s.edges, len(s.deadlocks)))
+		return report.String()
+	}
+
+	report.WriteString(fmt.Sprintf(
+		"再生成しました: ノード %d (%+d), エッジ %d (%+d), デッドロック %d (%+d)\n",
+		s.nodes, s.nodes-previous.nodes,
+		s.edges, s.edges-previous.edges,
+		len(s.deadlocks), len(s.deadlocks)-len(previous.deadlocks),
+	))
+	changed := strings.Join(s.cache.ChangedRules, ", ")
+	if changed == "" {
+		changed = "なし"
+	}
+	report.WriteString(fmt.Sprintf("  条件の評価を再利用したノード: %d, 条件を評価し直したルール: %s\n", s.cache.ReusedNodes, changed))
+	ids := make([]string, 0, len(s.deadlocks))
+	for id := range s.deadlocks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, exists := previous.deadlocks[id]; !exists {
+			report.WriteString(fmt.Sprintf("  新しいデッドロック: %s\n", strings.Join(s.deadlocks[id], ", ")))
+		}
+	}
+	return report.String()
+}
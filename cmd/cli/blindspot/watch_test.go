@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuleSourcesIncludes(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.yaml")
+	includePath := filepath.Join(dir, "payment.yaml")
+	if err := os.WriteFile(mainPath, []byte("include:\n  - payment.yaml\n"+testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(includePath, []byte("start_resources:\n  payment: \"none\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, includes, err := loadRuleSources(mainPath, "cud")
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	if len(includes) != 1 || filepath.Base(includes[0]) != "payment.yaml" {
+		t.Fatalf("expected payment.yaml to be reported, got %v", includes)
+	}
+
+	// 取り込んだファイルの変更は、監視する状態とチェックポイントのハッシュの両方に現れる
+	files := append([]string{mainPath}, includes...)
+	state := watchedFilesState(files)
+	hash, err := ruleSourceHash(mainPath, includes)
+	if err != nil {
+		t.Fatalf("failed to hash rules: %v", err)
+	}
+	if err := os.WriteFile(includePath, []byte("start_resources:\n  payment: \"paid\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(includePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if watchedFilesState(files) == state {
+		t.Error("expected a change in an included file to be detected")
+	}
+	if changed, _ := ruleSourceHash(mainPath, includes); changed == hash {
+		t.Error("expected the source hash to cover included files")
+	}
+
+	// 取り込むファイルがない場合は、以前と同じく入力ファイルの内容のハッシュ
+	content, _ := os.ReadFile(mainPath)
+	sum := sha256.Sum256(content)
+	if plain, _ := ruleSourceHash(mainPath, nil); plain != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected the hash of the input file alone, got %s", plain)
+	}
+}
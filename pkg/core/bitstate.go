@@ -0,0 +1,320 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+)
+
+// DefaultMaxDeadlockTraces BitstateOptions.MaxDeadlockTracesを指定しない場合に、トレースを保存するデッドロックの数
+const DefaultMaxDeadlockTraces = 10
+
+// BitstateOptions ビット状態空間探索（bitstate hashing）の設定
+type BitstateOptions struct {
+	Bits       uint64      // 訪問済みの状態を記録するビット配列の大きさ（ビット数）
+	Hashes     int         // 1つの状態に使うハッシュ関数の数（ブルームフィルターのk）
+	Invariants []Invariant // すべての状態で成り立つべき条件
+	// MaxDeadlockTraces トレースを保存するデッドロックの数の上限（0以下の場合はDefaultMaxDeadlockTraces）
+	// それ以降に見つけたデッドロックは数だけを数え、メモリの使用量がデッドロックの数に比例して増えないようにする。
+	MaxDeadlockTraces int
+}
+
+// Invariant すべての状態で成り立つべき条件
+type Invariant struct {
+	Name  string
+	Holds func(node *Node) bool
+}
+
+// BitstateTrace 開始ノードから、問題のある状態までの遷移
+type BitstateTrace struct {
+	Node *Node          // 問題のある状態
+	Path TransitionPath // 開始ノードからNodeまでのエッジ（開始ノード自体の場合は空）
+}
+
+// InvariantViolation 条件が成り立たない状態と、そこまでの遷移
+type InvariantViolation struct {
+	Invariant string
+	BitstateTrace
+}
+
+// BitstateResult ビット状態空間探索の結果
+// 状態とエッジは保持せず、解析結果と、近似による見落としの見積もりのみを返す
+type BitstateResult struct {
+	States      int64 // 訪問した状態の数
+	Transitions int64 // 調べた遷移の数
+	MaxDepth    int   // 開始ノードからの最大の深さ
+	Truncated   bool  // 反復回数の上限により探索を打ち切ったかどうか
+
+	Bits    uint64 // ビット配列の大きさ
+	BitsSet uint64 // 立っているビットの数
+	Hashes  int    // 1つの状態に使ったハッシュ関数の数
+
+	// ExpectedOmitted 新しい状態を訪問済みと誤判定して見落とした状態の数の期待値
+	// 見落とした状態から先の状態は数えていないため、下限の目安となる
+	ExpectedOmitted float64
+
+	Deadlocks     []*BitstateTrace      // どのルールも実行できない状態（見つけた順に、MaxDeadlockTraces件まで）
+	DeadlockCount int64                 // 見つけたデッドロックの総数（トレースを保存しなかったものを含む）
+	Violations    []*InvariantViolation // 条件が成り立たない状態（条件ごとに最初に見つけた状態のみ）
+}
+
+// FalsePositiveRate 探索の終了時点で、新しい状態を訪問済みと誤判定する確率
+func (r *BitstateResult) FalsePositiveRate() float64 {
+	if r.Bits == 0 {
+		return 0
+	}
+	return math.Pow(float64(r.BitsSet)/float64(r.Bits), float64(r.Hashes))
+}
+
+// Coverage 到達可能な状態のうち、訪問できた割合の推定値
+func (r *BitstateResult) Coverage() float64 {
+	if r.States == 0 {
+		return 1
+	}
+	return float64(r.States) / (float64(r.States) + r.ExpectedOmitted)
+}
+
+// HashFactor 状態1つあたりのビット数（SPINのhash factor。大きいほど見落としが少ない）
+func (r *BitstateResult) HashFactor() float64 {
+	if r.States == 0 {
+		return math.Inf(1)
+	}
+	return float64(r.Bits) / float64(r.States)
+}
+
+// bitstateStep 遷移をルールと結果の番号で表したもの（トレースの再現に使用）
+type bitstateStep struct {
+	rule    int
+	outcome int
+}
+
+// bitstateFrame 深さ優先探索のスタックの1段
+type bitstateFrame struct {
+	edges []*Edge
+	steps []bitstateStep // edgesに対応するルールと結果の番号
+	next  int            // 次に調べるedgesの位置
+}
+
+// GenerateBitstate ビット状態空間探索でステートマシンを近似的に探索する
+// SPINのbitstate hashingと同様に、訪問済みの状態をノードIDのハッシュによるビット配列（ブルームフィルター）で記録し、
+// ノードとエッジを保持しない。そのため列挙しきれない大きさのモデルでもデッドロックと条件の違反を探せるが、
+// ハッシュの衝突により一部の状態を見落とす可能性がある。
+// 深さ優先探索のスタックを現在のパスとして使い、問題のある状態へのトレースはルールの番号の列として記録して、
+// 報告時に開始ノードから再適用して復元する。
+// ジェネレーターの生成結果（GetNodes, GetEdges）には影響しない。
+func (g *Generator) GenerateBitstate(options BitstateOptions) (*BitstateResult, error) {
+	if options.Bits == 0 {
+		return nil, fmt.Errorf("ビット配列の大きさを指定してください")
+	}
+	if options.Hashes <= 0 {
+		return nil, fmt.Errorf("ハッシュ関数の数は1以上を指定してください: %d", options.Hashes)
+	}
+
+	maxDeadlockTraces := options.MaxDeadlockTraces
+	if maxDeadlockTraces <= 0 {
+		maxDeadlockTraces = DefaultMaxDeadlockTraces
+	}
+
+	filter := newBitstateFilter(options.Bits, options.Hashes)
+	result := &BitstateResult{Bits: options.Bits, Hashes: options.Hashes}
+	violated := make(map[string]bool)
+	var deadlockTrails, violationTrails [][]bitstateStep
+	var violationNames []string
+
+	// visit 新しい状態を訪問済みとして記録し、デッドロック以外の検査を行う（訪問済みの場合はfalse）
+	visit := func(node *Node, trail func() []bitstateStep) bool {
+		falsePositive := filter.falsePositiveRate()
+		if !filter.add((*node).GetID()) {
+			return false
+		}
+		result.ExpectedOmitted += falsePositive
+		result.States++
+		for _, invariant := range options.Invariants {
+			if violated[invariant.Name] || invariant.Holds(node) {
+				continue
+			}
+			violated[invariant.Name] = true
+			violationNames = append(violationNames, invariant.Name)
+			violationTrails = append(violationTrails, trail())
+			slog.Debug("[BITSTATE] 条件の違反", "invariant", invariant.Name, "id", (*node).GetID())
+		}
+		return true
+	}
+
+	ruleIndex := make(map[*EdgeRule]int, len(g.edgeRules))
+	for i, rule := range g.edgeRules {
+		ruleIndex[rule] = i
+	}
+	startNode := g.newNode(g.startResources.GetResources())
+	var stack []*bitstateFrame
+	push := func(node *Node) {
+		edges, _ := g.expand(node, filter.contains)
+		frame := &bitstateFrame{edges: edges, steps: bitstateSteps(ruleIndex, edges)}
+		result.Transitions += int64(len(edges))
+		if len(edges) == 0 {
+			result.DeadlockCount++
+			if len(deadlockTrails) < maxDeadlockTraces {
+				deadlockTrails = append(deadlockTrails, currentTrail(stack))
+			}
+			slog.Debug("[BITSTATE] デッドロック", "id", (*node).GetID())
+		}
+		stack = append(stack, frame)
+		if len(stack)-1 > result.MaxDepth {
+			result.MaxDepth = len(stack) - 1
+		}
+	}
+
+	visit(&startNode, func() []bitstateStep { return nil })
+	push(&startNode)
+	var expanded int64 = 1
+	for len(stack) > 0 {
+		frame := stack[len(stack)-1]
+		if frame.next >= len(frame.edges) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		edge := frame.edges[frame.next]
+		frame.next++
+
+		if !visit(edge.GetTo(), func() []bitstateStep { return currentTrail(stack) }) {
+			continue
+		}
+		expanded++
+		if g.limit != nil && expanded > *g.limit {
+			slog.Error("[ERROR] 反復回数が上限を超えました。強制終了します。")
+			result.Truncated = true
+			break
+		}
+		push(edge.GetTo())
+	}
+	result.BitsSet = filter.set
+
+	// 記録したトレースを開始ノードから再適用して復元する
+	for _, trail := range deadlockTrails {
+		trace, err := g.replayBitstateTrail(&startNode, trail)
+		if err != nil {
+			return nil, err
+		}
+		result.Deadlocks = append(result.Deadlocks, trace)
+	}
+	for i, trail := range violationTrails {
+		trace, err := g.replayBitstateTrail(&startNode, trail)
+		if err != nil {
+			return nil, err
+		}
+		result.Violations = append(result.Violations, &InvariantViolation{Invariant: violationNames[i], BitstateTrace: *trace})
+	}
+
+	slog.Debug("[BITSTATE] 完了", "states", result.States, "transitions", result.Transitions, "bitsSet", result.BitsSet)
+	return result, nil
+}
+
+// currentTrail スタックの各段で直前に選んだ遷移を、開始ノードからの遷移の列として取得
+// スタックの先頭の段の直前の遷移が、最後に訪問した状態への遷移になる
+func currentTrail(stack []*bitstateFrame) []bitstateStep {
+	trail := make([]bitstateStep, 0, len(stack))
+	for _, frame := range stack {
+		if frame.next > 0 {
+			trail = append(trail, frame.steps[frame.next-1])
+		}
+	}
+	return trail
+}
+
+// bitstateSteps エッジに対応するルールと結果の番号を求める
+func bitstateSteps(ruleIndex map[*EdgeRule]int, edges []*Edge) []bitstateStep {
+	steps := make([]bitstateStep, len(edges))
+	for i, edge := range edges {
+		steps[i].rule = ruleIndex[edge.GetRule()]
+		if outcome := edge.GetOutcome(); outcome != nil {
+			for o, candidate := range edge.GetRule().GetOutcomes() {
+				if candidate.Name == outcome.Name {
+					steps[i].outcome = o
+				}
+			}
+		}
+	}
+	return steps
+}
+
+// replayBitstateTrail 開始ノードからルールと結果を順に再適用し、トレースを復元する
+func (g *Generator) replayBitstateTrail(startNode *Node, trail []bitstateStep) (*BitstateTrace, error) {
+	trace := &BitstateTrace{Node: startNode}
+	for _, step := range trail {
+		if step.rule >= len(g.edgeRules) {
+			return nil, fmt.Errorf("トレースのルールの番号が不正です: %d", step.rule)
+		}
+		rule := g.edgeRules[step.rule]
+		outcomes := rule.GetOutcomes()
+		if step.outcome >= len(outcomes) {
+			return nil, fmt.Errorf("トレースのルール %s の結果の番号が不正です: %d", rule.GetName(), step.outcome)
+		}
+		outcome := outcomes[step.outcome]
+		to := outcome.Effect(trace.Node)
+		trace.Path = append(trace.Path, NewOutcomeEdge(trace.Node, to, rule, outcome))
+		trace.Node = to
+	}
+	return trace, nil
+}
+
+// bitstateFilter 訪問済みの状態を記録するブルームフィルター
+type bitstateFilter struct {
+	words  []uint64
+	size   uint64
+	hashes int
+	set    uint64 // 立っているビットの数
+}
+
+// newBitstateFilter sizeビットのブルームフィルターを作成
+func newBitstateFilter(size uint64, hashes int) *bitstateFilter {
+	return &bitstateFilter{
+		words:  make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// add IDを記録する。すでに記録済み（と判定された）場合はfalseを返す
+// 2つのハッシュ値の線形結合でk個の位置を求める（Kirsch-Mitzenmacherの方法）
+func (f *bitstateFilter) add(id string) bool {
+	a, b := bitstateHashes(id)
+
+	added := false
+	for i := 0; i < f.hashes; i++ {
+		position := (a + uint64(i)*b) % f.size
+		word, mask := position/64, uint64(1)<<(position%64)
+		if f.words[word]&mask == 0 {
+			f.words[word] |= mask
+			f.set++
+			added = true
+		}
+	}
+	return added
+}
+
+// contains IDが記録済み（と判定される）かどうか
+func (f *bitstateFilter) contains(id string) bool {
+	a, b := bitstateHashes(id)
+	for i := 0; i < f.hashes; i++ {
+		position := (a + uint64(i)*b) % f.size
+		if f.words[position/64]&(uint64(1)<<(position%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bitstateHashes IDから、位置を求めるための2つのハッシュ値を求める
+func bitstateHashes(id string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(id))
+	h2 := fnv.New64()
+	h2.Write([]byte(id))
+	return h1.Sum64(), h2.Sum64() | 1
+}
+
+// falsePositiveRate 現在の状態で、新しいIDを記録済みと誤判定する確率
+func (f *bitstateFilter) falsePositiveRate() float64 {
+	return math.Pow(float64(f.set)/float64(f.size), float64(f.hashes))
+}
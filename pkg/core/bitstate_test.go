@@ -0,0 +1,67 @@
+package core
+
+import "testing"
+
+func TestGenerateBitstate(t *testing.T) {
+	// 0 -> 1 -> 2(終端), 0 -> 3 -> 4 -> 0 のループ
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 2),
+		newTestRule(t, "c", 0, 3),
+		newTestRule(t, "d", 3, 4),
+		newTestRule(t, "e", 4, 0),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	result, err := generator.GenerateBitstate(BitstateOptions{
+		Bits:   1 << 16,
+		Hashes: 3,
+		Invariants: []Invariant{
+			{Name: "not 4", Holds: func(n *Node) bool { return (*n).GetResources().(int) != 4 }},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to explore: %v", err)
+	}
+	if len(generator.GetNodes()) != 0 {
+		t.Error("bitstate exploration must not store nodes in the generator")
+	}
+	if result.States != 5 || result.Transitions != 5 {
+		t.Errorf("expected 5 states and 5 transitions, got %d and %d", result.States, result.Transitions)
+	}
+
+	if result.DeadlockCount != 1 || len(result.Deadlocks) != 1 || (*result.Deadlocks[0].Node).GetID() != "n2" {
+		t.Fatalf("expected a deadlock at n2, got %+v", result.Deadlocks)
+	}
+	if path := result.Deadlocks[0].Path; len(path) != 2 || path[0].GetRule().GetName() != "a" || path[1].GetRule().GetName() != "b" {
+		t.Errorf("expected the deadlock trace a, b, got %v", path)
+	}
+	if len(result.Violations) != 1 || (*result.Violations[0].Node).GetID() != "n4" || len(result.Violations[0].Path) != 2 {
+		t.Fatalf("expected a violation at n4 via c, d, got %+v", result.Violations)
+	}
+	if result.Coverage() < 0.999999 {
+		t.Errorf("expected nearly full coverage with a large bit array, got %f", result.Coverage())
+	}
+
+	// トレースを保存するデッドロックの数を超えた分は、数だけを数える
+	fanout := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 0, 2),
+		newTestRule(t, "c", 0, 3),
+	}
+	limited, err := NewGenerator(newTestNode, newTestNode(0), fanout, nil).GenerateBitstate(BitstateOptions{Bits: 1 << 16, Hashes: 3, MaxDeadlockTraces: 2})
+	if err != nil {
+		t.Fatalf("failed to explore: %v", err)
+	}
+	if limited.DeadlockCount != 3 || len(limited.Deadlocks) != 2 {
+		t.Errorf("expected 3 deadlocks with 2 traces, got %d with %d traces", limited.DeadlockCount, len(limited.Deadlocks))
+	}
+
+	// ビット配列が小さすぎる場合は状態を見落とし、カバー率の推定値が下がる
+	tiny, err := generator.GenerateBitstate(BitstateOptions{Bits: 4, Hashes: 1})
+	if err != nil {
+		t.Fatalf("failed to explore: %v", err)
+	}
+	if tiny.States > 4 || tiny.Coverage() >= 1 {
+		t.Errorf("expected omitted states with 4 bits, got %d states and coverage %f", tiny.States, tiny.Coverage())
+	}
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// checkpointVersion チェックポイントの形式のバージョン
+const checkpointVersion = 1
+
+// Checkpoint 探索を中断した時点のジェネレーターの状態
+// ノードのリソースは直列化せず、復元時に開始ノードからエッジのルール（と結果）を順に再適用して作り直す。
+// そのためNodeの実装によらず保存でき、ルールの効果が変わっていた場合はノードIDの不一致として検出できる。
+type Checkpoint struct {
+	Version    int                    `json:"version"`
+	Source     string                 `json:"source,omitempty"` // 呼び出し側が設定するルールの出所の識別子（ルールファイルのハッシュなど）
+	Rules      string                 `json:"rules"`            // ルールの定義（名前、条件式、優先度、重み、結果）のハッシュ
+	Start      string                 `json:"start"`            // 開始ノードのID
+	Nodes      []string               `json:"nodes"`            // 発見済みのノードのID
+	Processed  []string               `json:"processed"`        // 展開済みのノードのID
+	Edges      []CheckpointEdge       `json:"edges"`            // 生成した順のエッジ
+	Queue      []string               `json:"queue"`            // 展開を待っているノードのID（探索戦略に従って取り出す）
+	Suppressed []CheckpointSuppressed `json:"suppressed,omitempty"`
+	Strategy   string                 `json:"strategy,omitempty"` // 探索戦略の名前（省略時はbfs）
+	Depths     map[string]int         `json:"depths,omitempty"`   // ノードIDごとの開始ノードからの深さ
+	Bound      int                    `json:"bound,omitempty"`    // 反復深化の現在の深さの上限
+	Iterations int64                  `json:"iterations"`         // 保存時までの反復回数の累計（再開後も反復回数の上限はこの回数から数える）
+}
+
+// CheckpointEdge チェックポイントに保存するエッジ
+type CheckpointEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Rule    string `json:"rule"`
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// CheckpointSuppressed チェックポイントに保存する、優先度によって抑制されたルール
+type CheckpointSuppressed struct {
+	Node       string `json:"node"`
+	Rule       string `json:"rule"`
+	ByPriority int    `json:"by_priority"`
+}
+
+// ReadCheckpoint JSON形式のチェックポイントを読み込む
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	if err := json.NewDecoder(r).Decode(&checkpoint); err != nil {
+		return nil, fmt.Errorf("チェックポイントの読み込みに失敗: %w", err)
+	}
+	if checkpoint.Version != checkpointVersion {
+		return nil, fmt.Errorf("未対応のチェックポイントのバージョンです: %d", checkpoint.Version)
+	}
+	return &checkpoint, nil
+}
+
+// Write チェックポイントをJSON形式で書き込む
+func (c *Checkpoint) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// Checkpoint 現在の探索の状態をチェックポイントとして取得
+func (g *Generator) Checkpoint() *Checkpoint {
+	checkpoint := &Checkpoint{
+		Version:    checkpointVersion,
+		Rules:      g.rulesFingerprint(),
+		Start:      g.newNode(g.startResources.GetResources()).GetID(),
+		Nodes:      make([]string, 0, len(g.nodes)),
+		Processed:  make([]string, 0, len(g.processedNodes)),
+		Edges:      make([]CheckpointEdge, 0, len(g.edges)),
+		Queue:      make([]string, 0, len(g.queue)),
+		Strategy:   g.strategy.String(),
+		Depths:     make(map[string]int, len(g.depths)),
+		Bound:      g.bound,
+		Iterations: g.iterations,
+	}
+	for id, depth := range g.depths {
+		checkpoint.Depths[id] = depth
+	}
+	for id := range g.nodes {
+		checkpoint.Nodes = append(checkpoint.Nodes, id)
+	}
+	sort.Strings(checkpoint.Nodes)
+	for id := range g.processedNodes {
+		checkpoint.Processed = append(checkpoint.Processed, id)
+	}
+	sort.Strings(checkpoint.Processed)
+	for _, edge := range g.edges {
+		saved := CheckpointEdge{
+			From: (*edge.GetFrom()).GetID(),
+			To:   (*edge.GetTo()).GetID(),
+			Rule: edge.GetRule().GetName(),
+		}
+		if outcome := edge.GetOutcome(); outcome != nil {
+			saved.Outcome = outcome.Name
+		}
+		checkpoint.Edges = append(checkpoint.Edges, saved)
+	}
+	for _, node := range g.queue {
+		checkpoint.Queue = append(checkpoint.Queue, (*node).GetID())
+	}
+	for _, s := range g.suppressed {
+		checkpoint.Suppressed = append(checkpoint.Suppressed, CheckpointSuppressed{
+			Node:       (*s.Node).GetID(),
+			Rule:       s.Rule.GetName(),
+			ByPriority: s.ByPriority,
+		})
+	}
+	return checkpoint
+}
+
+// Restore チェックポイントからジェネレーターの状態を復元する
+// 復元後にGenerateを呼ぶと、中断したところから探索を続ける。
+// 反復回数の累計も復元するため、反復回数の上限は中断をまたいだ探索全体に対して適用される。
+// 探索戦略はSetStrategyで保存時と同じものを設定しておく。深さの上限（SetMaxDepth）は変えてもよく、
+// 大きくした場合は上限により展開しなかったノードから探索を続ける。
+// ルールの定義が保存時と異なる場合や、ルールを再適用した結果が保存したノードと一致しない場合はエラーを返す。
+func (g *Generator) Restore(checkpoint *Checkpoint) error {
+	if checkpoint.Rules != g.rulesFingerprint() {
+		return fmt.Errorf("チェックポイントを保存した時からルールが変更されています")
+	}
+	strategy := StrategyBFS
+	if checkpoint.Strategy != "" {
+		var err error
+		if strategy, err = ParseSearchStrategy(checkpoint.Strategy); err != nil {
+			return err
+		}
+	}
+	if strategy != g.strategy {
+		return fmt.Errorf("チェックポイントの探索戦略 %s が、指定された探索戦略 %s と異なります", strategy, g.strategy)
+	}
+	rules := make(map[string]*EdgeRule, len(g.edgeRules))
+	for _, rule := range g.edgeRules {
+		if _, exists := rules[rule.GetName()]; exists {
+			return fmt.Errorf("ルール名 %q が重複しているため、チェックポイントから復元できません", rule.GetName())
+		}
+		rules[rule.GetName()] = rule
+	}
+
+	startNode := g.newNode(g.startResources.GetResources())
+	if startNode.GetID() != checkpoint.Start {
+		return fmt.Errorf("開始ノードがチェックポイントと一致しません: %s != %s", startNode.GetID(), checkpoint.Start)
+	}
+	g.nodes = map[string]Node{startNode.GetID(): startNode}
+	g.depths = map[string]int{startNode.GetID(): 0}
+	g.bound = checkpoint.Bound
+	g.iterations = checkpoint.Iterations
+	g.edges = make([]*Edge, 0, len(checkpoint.Edges))
+	g.processedNodes = make(map[string]bool, len(checkpoint.Processed))
+	g.suppressed = nil
+	g.queue = nil
+
+	// 開始ノードから、保存した順にエッジのルールを再適用してノードを作り直す
+	for i, saved := range checkpoint.Edges {
+		from, exists := g.nodes[saved.From]
+		if !exists {
+			return fmt.Errorf("エッジ %d の遷移元 %s が見つかりません", i, saved.From)
+		}
+		rule, exists := rules[saved.Rule]
+		if !exists {
+			return fmt.Errorf("エッジ %d のルール %q が見つかりません", i, saved.Rule)
+		}
+		outcome, exists := rule.GetOutcome(saved.Outcome)
+		if !exists {
+			return fmt.Errorf("エッジ %d のルール %q に結果 %q が見つかりません", i, saved.Rule, saved.Outcome)
+		}
+		to := outcome.Effect(&from)
+		if (*to).GetID() != saved.To {
+			return fmt.Errorf("エッジ %d (%s) の遷移先がチェックポイントと一致しません。ルールの効果が変更されています: %s != %s", i, saved.Rule, (*to).GetID(), saved.To)
+		}
+		edge := NewOutcomeEdge(&from, to, rule, outcome)
+		edge.to = g.addOrGetNode(to)
+		g.edges = append(g.edges, edge)
+		// 深さを保存していない場合（古いチェックポイント）は、生成した順にエッジを辿って求める
+		g.recordDepth(saved.To, g.depths[saved.From]+1)
+	}
+	for id, depth := range checkpoint.Depths {
+		if _, exists := g.nodes[id]; !exists {
+			return fmt.Errorf("深さを保存したノード %s が見つかりません", id)
+		}
+		g.depths[id] = depth
+	}
+	if len(g.nodes) != len(checkpoint.Nodes) {
+		return fmt.Errorf("復元したノード数がチェックポイントと一致しません: %d != %d", len(g.nodes), len(checkpoint.Nodes))
+	}
+
+	for _, id := range checkpoint.Processed {
+		if _, exists := g.nodes[id]; !exists {
+			return fmt.Errorf("展開済みのノード %s が見つかりません", id)
+		}
+		g.processedNodes[id] = true
+	}
+	for _, id := range checkpoint.Queue {
+		node, exists := g.nodes[id]
+		if !exists {
+			return fmt.Errorf("キューのノード %s が見つかりません", id)
+		}
+		g.queue = append(g.queue, &node)
+	}
+	for _, saved := range checkpoint.Suppressed {
+		node, exists := g.nodes[saved.Node]
+		if !exists {
+			return fmt.Errorf("抑制が起きたノード %s が見つかりません", saved.Node)
+		}
+		rule, exists := rules[saved.Rule]
+		if !exists {
+			return fmt.Errorf("抑制されたルール %q が見つかりません", saved.Rule)
+		}
+		g.suppressed = append(g.suppressed, SuppressedRule{Node: &node, Rule: rule, ByPriority: saved.ByPriority})
+	}
+	return nil
+}
+
+// rulesFingerprint ルールの定義から、変更の検出に使うハッシュを求める
+// 効果は関数のため含められないが、変更された場合はRestoreでの再適用時に検出する
+func (g *Generator) rulesFingerprint() string {
+	hash := sha256.New()
+	for _, rule := range g.edgeRules {
+		fmt.Fprintf(hash, "rule %q priority=%d weight=%v fire=%q block=%q\n",
+			rule.GetName(), rule.GetPriority(), rule.GetWeight(), rule.FireConditionText, rule.BlockConditionText)
+		for _, outcome := range rule.Outcomes {
+			fmt.Fprintf(hash, "  outcome %q weight=%v\n", outcome.Name, outcome.Weight)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	// 0 -> 1 -> 2 -> 3, 0 -> 2, 3 -> 0 のループを途中で打ち切って保存し、再開する
+	newRules := func(last int) []*EdgeRule {
+		return []*EdgeRule{
+			newTestRule(t, "a", 0, 1),
+			newTestRule(t, "b", 1, 2),
+			newTestRule(t, "c", 0, 2),
+			newTestRule(t, "d", 2, 3),
+			newTestRule(t, "e", 3, last),
+		}
+	}
+	limit := int64(2)
+	interrupted := NewGenerator(newTestNode, newTestNode(0), newRules(0), &limit)
+	if err := interrupted.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	var saved bytes.Buffer
+	if err := interrupted.Checkpoint().Write(&saved); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+
+	checkpoint, err := ReadCheckpoint(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	resumed := NewGenerator(newTestNode, newTestNode(0), newRules(0), nil)
+	if err := resumed.Restore(checkpoint); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if !resumed.IsPartial() || len(resumed.GetEdges()) != len(interrupted.GetEdges()) {
+		t.Fatalf("expected the restored generator to match the interrupted one")
+	}
+	if resumed.GetIterations() != limit {
+		t.Fatalf("expected the iteration count %d to be restored, got %d", limit, resumed.GetIterations())
+	}
+
+	// 反復回数の上限は再開をまたいだ累計に適用されるため、同じ上限で再開しても探索は進まない
+	limited := NewGenerator(newTestNode, newTestNode(0), newRules(0), &limit)
+	if err := limited.Restore(checkpoint); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if err := limited.Generate(); err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	if limited.GetIterations() != limit || len(limited.GetEdges()) != len(interrupted.GetEdges()) {
+		t.Errorf("expected no further iterations under the same limit, got %d iterations", limited.GetIterations())
+	}
+	if err := resumed.Generate(); err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+
+	complete := NewGenerator(newTestNode, newTestNode(0), newRules(0), nil)
+	if err := complete.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if resumed.IsPartial() {
+		t.Error("expected the resumed graph to be complete")
+	}
+	if len(resumed.GetEdges()) != len(complete.GetEdges()) {
+		t.Fatalf("expected %d edges, got %d", len(complete.GetEdges()), len(resumed.GetEdges()))
+	}
+	for i, edge := range resumed.GetEdges() {
+		if edge.String() != complete.GetEdges()[i].String() {
+			t.Errorf("edge %d: expected %s, got %s", i, complete.GetEdges()[i], edge)
+		}
+	}
+
+	// 発火済みのルールの効果を変えた場合は、再適用の結果が一致しないため復元できない
+	// （まだ発火していないルールeの効果の変更は検出できないため、CLIではルールファイルのハッシュも比較する）
+	if err := NewGenerator(newTestNode, newTestNode(0), newRules(1), nil).Restore(checkpoint); err != nil {
+		t.Errorf("rule e has not fired yet, so the checkpoint should still be valid: %v", err)
+	}
+	changed := newRules(0)
+	changed[0] = newTestRule(t, "a", 0, 3)
+	if err := NewGenerator(newTestNode, newTestNode(0), changed, nil).Restore(checkpoint); err == nil {
+		t.Error("expected an error when the effect of a fired rule changed")
+	}
+	renamed := newRules(0)
+	renamed[0] = newTestRule(t, "a2", 0, 1)
+	if err := NewGenerator(newTestNode, newTestNode(0), renamed, nil).Restore(checkpoint); err == nil {
+		t.Error("expected an error when the rules changed")
+	}
+}
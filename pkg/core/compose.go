@@ -0,0 +1,430 @@
+package core
+
+import (
+	"crypto/md5"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// SyncMode 並行合成でコンポーネント同士を同期させる方法
+type SyncMode string
+
+const (
+	// SyncRuleNames 同名のルールを持つコンポーネントは、そのルールを同時に実行する（CSP方式）
+	// 各コンポーネントは独立した状態を持ち、全体の状態はそれらの組になる
+	SyncRuleNames SyncMode = "rules"
+	// SyncSharedResources すべてのコンポーネントが1つのリソースを共有し、同じキーを読み書きして連携する
+	// ルールはインターリーブで実行される（リソースがmap[string]anyのパーサーのみ対応）
+	SyncSharedResources SyncMode = "resources"
+)
+
+// Component 並行合成の構成要素となる1つのステートマシン
+type Component struct {
+	Name    string
+	Start   Node
+	NewNode func(resources any) Node
+	Rules   []*EdgeRule
+}
+
+// ComposeParallel 複数のコンポーネントを並行合成し、Generatorに渡せる開始状態・ノード生成関数・ルールを返す
+// 合成後のルール名はコンポーネント名で修飾される（例: order.pay）
+// ルールの優先度は、どちらの同期方法でもコンポーネントの中でだけ比較する（他のコンポーネントのルールを抑制しない）。
+// 合成後のルールのAccessは参加するルールのものを合わせたもので、SyncRuleNamesではキーを「コンポーネント名.キー」と修飾する。
+func ComposeParallel(components []Component, mode SyncMode) (start Node, newNode func(any) Node, rules []*EdgeRule, err error) {
+	if len(components) == 0 {
+		return nil, nil, nil, fmt.Errorf("no components to compose")
+	}
+	seen := make(map[string]bool)
+	for _, component := range components {
+		if component.Name == "" || strings.ContainsAny(component.Name, ".+") {
+			return nil, nil, nil, fmt.Errorf("invalid component name: %q", component.Name)
+		}
+		if seen[component.Name] {
+			return nil, nil, nil, fmt.Errorf("duplicate component name: %s", component.Name)
+		}
+		seen[component.Name] = true
+	}
+
+	switch mode {
+	case SyncRuleNames:
+		return composeByRuleNames(components)
+	case SyncSharedResources:
+		return composeBySharedResources(components)
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown sync mode: %s", mode)
+	}
+}
+
+// ProductNode 各コンポーネントの状態の組
+type ProductNode struct {
+	names []string
+	parts []Node
+	id    string // 作成時に求めた識別子
+}
+
+// newProductNode 各コンポーネントの状態の組を作成し、識別子を求めておく
+func newProductNode(names []string, parts []Node) ProductNode {
+	ids := make([]string, len(parts))
+	for i, part := range parts {
+		ids[i] = names[i] + "=" + part.GetID()
+	}
+	return ProductNode{names: names, parts: parts, id: fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(ids, "\n"))))}
+}
+
+// GetID 各コンポーネントのIDから一意な識別子を取得
+func (n ProductNode) GetID() string {
+	return n.id
+}
+
+// Equals ノードが同じかどうかを判定
+func (n ProductNode) Equals(other Node) bool {
+	return n.GetID() == other.GetID()
+}
+
+// GetResources コンポーネント名から各コンポーネントのリソースへのマップを取得
+func (n ProductNode) GetResources() any {
+	resources := make(map[string]any, len(n.parts))
+	for i, part := range n.parts {
+		resources[n.names[i]] = part.GetResources()
+	}
+	return resources
+}
+
+// GetResourcesString 各コンポーネントのリソースを、コンポーネント名で修飾して取得
+func (n ProductNode) GetResourcesString() []string {
+	var lines []string
+	for i, part := range n.parts {
+		for _, line := range part.GetResourcesString() {
+			lines = append(lines, n.names[i]+"."+line)
+		}
+	}
+	return lines
+}
+
+// GetPart コンポーネントの状態を取得
+func (n ProductNode) GetPart(name string) (Node, bool) {
+	for i, partName := range n.names {
+		if partName == name {
+			return n.parts[i], true
+		}
+	}
+	return nil, false
+}
+
+// with i番目のコンポーネントの状態を置き換えたノードを返す
+func (n ProductNode) with(i int, part Node) ProductNode {
+	parts := make([]Node, len(n.parts))
+	copy(parts, n.parts)
+	parts[i] = part
+	return newProductNode(n.names, parts)
+}
+
+// participant 合成後のルールに参加するコンポーネントとそのルール
+type participant struct {
+	index int
+	rule  *EdgeRule
+}
+
+// composeByRuleNames 同名のルールで同期する積を作る
+func composeByRuleNames(components []Component) (Node, func(any) Node, []*EdgeRule, error) {
+	names := make([]string, len(components))
+	for i, component := range components {
+		names[i] = component.Name
+	}
+
+	newNode := func(resources any) Node {
+		byName, ok := resources.(map[string]any)
+		if !ok {
+			panic(fmt.Sprintf("product resources is not map[string]any: %v", resources))
+		}
+		parts := make([]Node, len(components))
+		for i, component := range components {
+			parts[i] = component.NewNode(byName[component.Name])
+		}
+		return newProductNode(names, parts)
+	}
+
+	startParts := make([]Node, len(components))
+	for i, component := range components {
+		startParts[i] = component.Start
+	}
+	start := newProductNode(names, startParts)
+
+	// ルール名ごとに参加するコンポーネントを集める（最初に現れた順）
+	var order []string
+	participants := make(map[string][]participant)
+	for i, component := range components {
+		for _, rule := range component.Rules {
+			if _, exists := participants[rule.GetName()]; !exists {
+				order = append(order, rule.GetName())
+			}
+			participants[rule.GetName()] = append(participants[rule.GetName()], participant{index: i, rule: rule})
+		}
+	}
+
+	selections := make([]*componentSelection, len(components))
+	for i, component := range components {
+		selections[i] = newComponentSelection(component)
+	}
+	var rules []*EdgeRule
+	for _, name := range order {
+		rule, err := newProductRule(components, selections, participants[name])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return start, newNode, rules, nil
+}
+
+// newProductRule 参加するコンポーネントのルールを1つの合成ルールにまとめる
+// 参加するすべてのコンポーネントでルールが実行可能な場合にだけ発火し、各コンポーネントの状態を同時に進める
+// 優先度はコンポーネントの中でだけ比較するため、合成ルールの優先度は使用しない
+func newProductRule(components []Component, selections []*componentSelection, members []participant) (*EdgeRule, error) {
+	qualifiers := make([]string, len(members))
+	fireTexts := make([]string, len(members))
+	blockTexts := make([]string, 0, len(members))
+	weight := 1.0
+	var branching *participant
+	for k, member := range members {
+		componentName := components[member.index].Name
+		qualifiers[k] = componentName
+		fireTexts[k] = componentName + ": " + member.rule.FireConditionText
+		if member.rule.BlockConditionText != "" {
+			blockTexts = append(blockTexts, componentName+": "+member.rule.BlockConditionText)
+		}
+		weight *= member.rule.GetWeight()
+		if len(member.rule.Outcomes) > 0 {
+			if branching != nil {
+				return nil, fmt.Errorf("synchronised rule %s has outcomes in more than one component", member.rule.GetName())
+			}
+			branching = &members[k]
+		}
+	}
+	name := strings.Join(qualifiers, "+") + "." + members[0].rule.GetName()
+
+	fireCondition := func(n *Node) bool {
+		product := (*n).(ProductNode)
+		for _, member := range members {
+			local := product.parts[member.index]
+			if !member.rule.GetFireCondition()(&local) {
+				return false
+			}
+		}
+		return true
+	}
+	blockCondition := func(n *Node) bool {
+		product := (*n).(ProductNode)
+		for _, member := range members {
+			local := product.parts[member.index]
+			if member.rule.GetBlockCondition()(&local) || !selections[member.index].selects(member.rule, &local) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 各コンポーネントの状態を順に進める。結果を持つルールの場合は、その結果の遷移先で置き換える
+	step := func(n *Node, outcome *Outcome) *Node {
+		product := (*n).(ProductNode)
+		for _, member := range members {
+			local := product.parts[member.index]
+			effect := member.rule.GetEffect()
+			if outcome != nil && branching != nil && member.index == branching.index {
+				effect = outcome.Effect
+			}
+			product = product.with(member.index, *effect(&local))
+		}
+		var next Node = product
+		return &next
+	}
+
+	var rule *EdgeRule
+	var err error
+	if branching == nil {
+		rule, err = NewEdgeRule(name, func(n *Node) *Node { return step(n, nil) }, fireCondition, blockCondition)
+	} else {
+		var outcomes []*Outcome
+		for _, local := range branching.rule.Outcomes {
+			localOutcome := local
+			outcome, err := NewOutcome(localOutcome.Name, func(n *Node) *Node { return step(n, localOutcome) })
+			if err != nil {
+				return nil, err
+			}
+			outcome.Weight = localOutcome.Weight
+			outcomes = append(outcomes, outcome)
+		}
+		rule, err = NewEdgeRuleWithOutcomes(name, outcomes, fireCondition, blockCondition)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rule.Weight = weight
+	rule.FireConditionText = strings.Join(fireTexts, "; ")
+	rule.BlockConditionText = strings.Join(blockTexts, "; ")
+
+	// 参照・変更するキーと定義のハッシュは、参加するルール（と優先度の比較の相手）のものを合わせる
+	accesses := make([]*RuleAccess, len(members))
+	digests := make([]string, len(members))
+	for k, member := range members {
+		accesses[k] = qualifyAccess(selections[member.index].access(member.rule), components[member.index].Name)
+		digests[k] = selections[member.index].digest(member.rule)
+	}
+	rule.Access = mergeAccess(accesses...)
+	rule.Digest = combineDigests(qualifiers, digests)
+	return rule, nil
+}
+
+// componentSelection コンポーネントの中での、優先度によるルールの選択
+type componentSelection struct {
+	rules   []*EdgeRule
+	uniform bool // すべてのルールが同じ優先度（優先度による抑制が起きない）
+
+	mu       sync.Mutex
+	lastID   string             // selectedを求めた状態のID
+	selected map[*EdgeRule]bool // lastIDの状態で、優先度によって抑制されなかったルール
+}
+
+// newComponentSelection コンポーネントのルールの優先度による選択を作成
+func newComponentSelection(component Component) *componentSelection {
+	selection := &componentSelection{rules: component.Rules, uniform: true}
+	for _, rule := range component.Rules {
+		if rule.GetPriority() != component.Rules[0].GetPriority() {
+			selection.uniform = false
+			break
+		}
+	}
+	return selection
+}
+
+// selects コンポーネントの中で、ruleが優先度によって抑制されていないかどうか
+// 同じ状態に対するコンポーネントのルールの評価は一度だけ行い、続けて問い合わせる他のルールで使い回す
+func (s *componentSelection) selects(rule *EdgeRule, node *Node) bool {
+	if s.uniform {
+		return true
+	}
+	id := (*node).GetID()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.selected == nil || s.lastID != id {
+		selected, _ := SelectByPriority(EvaluateRules(node, s.rules))
+		s.selected = make(map[*EdgeRule]bool, len(selected))
+		for _, evaluation := range selected {
+			s.selected[evaluation.Rule] = true
+		}
+		s.lastID = id
+	}
+	return s.selected[rule]
+}
+
+// access ruleが参照・変更するキー
+// 優先度による抑制がある場合、ruleの実行可否はコンポーネントの他のルールの実行可否にも依存するため、それらが参照するキーも加える
+func (s *componentSelection) access(rule *EdgeRule) *RuleAccess {
+	if s.uniform {
+		return rule.Access
+	}
+	accesses := []*RuleAccess{rule.Access}
+	for _, other := range s.rules {
+		if other.Access == nil {
+			return nil
+		}
+		accesses = append(accesses, &RuleAccess{Reads: other.Access.Reads, ReadsAll: other.Access.ReadsAll})
+	}
+	return mergeAccess(accesses...)
+}
+
+// digest ruleの定義のハッシュ
+// 優先度による抑制がある場合は、コンポーネントの他のルールの定義が変わっても評価し直すよう、それらのハッシュも含める
+func (s *componentSelection) digest(rule *EdgeRule) string {
+	if s.uniform {
+		return rule.Digest
+	}
+	names := []string{rule.GetName()}
+	digests := []string{rule.Digest}
+	for _, other := range s.rules {
+		names = append(names, other.GetName())
+		digests = append(digests, other.Digest)
+	}
+	return combineDigests(names, digests)
+}
+
+// qualifyAccess キーをコンポーネント名で修飾する（例: order.status）
+func qualifyAccess(access *RuleAccess, componentName string) *RuleAccess {
+	if access == nil {
+		return nil
+	}
+	qualify := func(keys []string) []string {
+		qualified := make([]string, len(keys))
+		for i, key := range keys {
+			qualified[i] = componentName + "." + key
+		}
+		return qualified
+	}
+	return &RuleAccess{Reads: qualify(access.Reads), Writes: qualify(access.Writes), ReadsAll: access.ReadsAll}
+}
+
+// mergeAccess 参照・変更するキーの和を求める（いずれかが不明な場合はnil）
+func mergeAccess(accesses ...*RuleAccess) *RuleAccess {
+	merged := &RuleAccess{}
+	for _, access := range accesses {
+		if access == nil {
+			return nil
+		}
+		merged.Reads = append(merged.Reads, access.Reads...)
+		merged.Writes = append(merged.Writes, access.Writes...)
+		merged.ReadsAll = merged.ReadsAll || access.ReadsAll
+	}
+	return merged
+}
+
+// combineDigests 複数のルールの定義のハッシュを1つにまとめる（いずれかが空の場合は空）
+func combineDigests(names []string, digests []string) string {
+	for _, digest := range digests {
+		if digest == "" {
+			return ""
+		}
+	}
+	return RuleDigest(map[string]any{"names": names, "digests": digests})
+}
+
+// composeBySharedResources すべてのコンポーネントのリソースを1つに統合し、ルールをインターリーブで実行する
+// 同じキーの開始値がコンポーネント間で異なる場合はエラーを返す
+func composeBySharedResources(components []Component) (Node, func(any) Node, []*EdgeRule, error) {
+	merged := make(map[string]any)
+	owner := make(map[string]string)
+	for _, component := range components {
+		resources, ok := component.Start.GetResources().(map[string]any)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("component %s: shared resources require map resources, got %T", component.Name, component.Start.GetResources())
+		}
+		for key, value := range resources {
+			if existing, exists := merged[key]; exists && fmt.Sprint(existing) != fmt.Sprint(value) {
+				return nil, nil, nil, fmt.Errorf("shared key %s has different start values in %s and %s", key, owner[key], component.Name)
+			}
+			merged[key] = value
+			owner[key] = component.Name
+		}
+	}
+
+	// 優先度はコンポーネントの中で比較するため、ジェネレーターには優先度を渡さず、ブロック条件で抑制する
+	newNode := components[0].NewNode
+	var rules []*EdgeRule
+	for _, component := range components {
+		selection := newComponentSelection(component)
+		for _, rule := range component.Rules {
+			original := rule
+			qualified := *rule
+			qualified.Name = component.Name + "." + rule.GetName()
+			qualified.Priority = 0
+			qualified.BlockCondition = func(n *Node) bool {
+				return original.GetBlockCondition()(n) || !selection.selects(original, n)
+			}
+			qualified.Access = selection.access(original)
+			qualified.Digest = selection.digest(original)
+			rules = append(rules, &qualified)
+		}
+	}
+	return newNode(maps.Clone(merged)), newNode, rules, nil
+}
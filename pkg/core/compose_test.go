@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestComposeParallel(t *testing.T) {
+	// 各コンポーネントは 0 -> 1 の独自ルールを実行した後、syncで同時に 1 -> 2 へ進む
+	order := Component{
+		Name:    "order",
+		Start:   newTestNode(0),
+		NewNode: newTestNode,
+		Rules:   []*EdgeRule{newTestRule(t, "place", 0, 1), newTestRule(t, "sync", 1, 2)},
+	}
+	payment := Component{
+		Name:    "payment",
+		Start:   newTestNode(0),
+		NewNode: newTestNode,
+		Rules:   []*EdgeRule{newTestRule(t, "authorize", 0, 1), newTestRule(t, "sync", 1, 2)},
+	}
+	start, newNode, rules, err := ComposeParallel([]Component{order, payment}, SyncRuleNames)
+	if err != nil {
+		t.Fatalf("failed to compose: %v", err)
+	}
+	generator := NewGenerator(newNode, start, rules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	if len(generator.GetNodes()) != 5 {
+		t.Errorf("expected 5 product states, got %d", len(generator.GetNodes()))
+	}
+	labels := make(map[string]int)
+	for _, edge := range generator.GetEdges() {
+		labels[edge.GetLabel()]++
+	}
+	if labels["order.place"] != 2 || labels["payment.authorize"] != 2 || labels["order+payment.sync"] != 1 {
+		t.Errorf("unexpected edge labels: %v", labels)
+	}
+	deadlocks := generator.GetDeadlockNodes()
+	if len(deadlocks) != 1 || (*deadlocks[0]).GetResources().(map[string]any)["payment"] != 2 {
+		t.Errorf("expected both components to finish together, got %v", deadlocks)
+	}
+
+	if _, _, _, err := ComposeParallel([]Component{order, payment}, SyncSharedResources); err == nil {
+		t.Error("expected error for shared resources with non-map resources")
+	}
+}
+
+// mapTestNode リソースを共有する合成のテスト用のノード
+type mapTestNode map[string]any
+
+func (n mapTestNode) GetID() string                { return fmt.Sprint(map[string]any(n)) }
+func (n mapTestNode) Equals(other Node) bool       { return n.GetID() == other.GetID() }
+func (n mapTestNode) GetResources() any            { return map[string]any(n) }
+func (n mapTestNode) GetResourcesString() []string { return []string{n.GetID()} }
+
+func newMapTestNode(resources any) Node {
+	return mapTestNode(resources.(map[string]any))
+}
+
+// newMapTestRule keyの値がfromの場合に発火し、toに更新するルールを作成
+func newMapTestRule(t *testing.T, name string, key string, from, to int, priority int) *EdgeRule {
+	t.Helper()
+	rule, err := NewEdgeRule(
+		name,
+		func(n *Node) *Node {
+			resources := maps.Clone((*n).GetResources().(map[string]any))
+			resources[key] = to
+			next := newMapTestNode(resources)
+			return &next
+		},
+		func(n *Node) bool { return (*n).GetResources().(map[string]any)[key] == from },
+		func(n *Node) bool { return false },
+	)
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	rule.Priority = priority
+	rule.Access = &RuleAccess{Reads: []string{key}, Writes: []string{key}}
+	rule.Digest = name
+	return rule
+}
+
+func TestComposePriorityPerComponent(t *testing.T) {
+	// aの中ではhighがlowを抑制するが、bのotherはaの優先度の影響を受けない
+	startLabels := func(t *testing.T, generator *Generator) map[string]bool {
+		t.Helper()
+		if err := generator.Generate(); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		labels := make(map[string]bool)
+		start := (*generator.GetStartNode()).GetID()
+		for _, edge := range generator.GetEdges() {
+			if (*edge.GetFrom()).GetID() == start {
+				labels[edge.GetLabel()] = true
+			}
+		}
+		return labels
+	}
+	expected := map[string]bool{"a.high": true, "b.other": true}
+
+	high := newTestRule(t, "high", 0, 1)
+	high.Priority = 10
+	byRules := []Component{
+		{Name: "a", Start: newTestNode(0), NewNode: newTestNode, Rules: []*EdgeRule{high, newTestRule(t, "low", 0, 2)}},
+		{Name: "b", Start: newTestNode(0), NewNode: newTestNode, Rules: []*EdgeRule{newTestRule(t, "other", 0, 1)}},
+	}
+	start, newNode, rules, err := ComposeParallel(byRules, SyncRuleNames)
+	if err != nil {
+		t.Fatalf("failed to compose: %v", err)
+	}
+	if labels := startLabels(t, NewGenerator(newNode, start, rules, nil)); !maps.Equal(labels, expected) {
+		t.Errorf("rule sync: expected %v from the start, got %v", expected, labels)
+	}
+
+	shared := []Component{
+		{Name: "a", Start: newMapTestNode(map[string]any{"x": 0}), NewNode: newMapTestNode, Rules: []*EdgeRule{
+			newMapTestRule(t, "high", "x", 0, 1, 10),
+			newMapTestRule(t, "low", "x", 0, 2, 0),
+		}},
+		{Name: "b", Start: newMapTestNode(map[string]any{"y": 0}), NewNode: newMapTestNode, Rules: []*EdgeRule{
+			newMapTestRule(t, "other", "y", 0, 1, 0),
+		}},
+	}
+	start, newNode, rules, err = ComposeParallel(shared, SyncSharedResources)
+	if err != nil {
+		t.Fatalf("failed to compose: %v", err)
+	}
+	if labels := startLabels(t, NewGenerator(newNode, start, rules, nil)); !maps.Equal(labels, expected) {
+		t.Errorf("shared resources: expected %v from the start, got %v", expected, labels)
+	}
+	// lowの実行可否はhighが参照するキーにも依存し、highの定義が変われば評価し直す
+	for _, rule := range rules {
+		if rule.GetName() == "a.low" && (rule.Access == nil || rule.Digest == "" || rule.Digest == "low") {
+			t.Errorf("expected a.low to carry the access and digest of its component, got %+v, %q", rule.Access, rule.Digest)
+		}
+	}
+}
+
+func TestComposeAccessAndDigest(t *testing.T) {
+	newComponent := func(name string, digest string) Component {
+		sync := newTestRule(t, "sync", 0, 1)
+		sync.Access = &RuleAccess{Reads: []string{"k"}, Writes: []string{"k"}}
+		sync.Digest = digest
+		return Component{Name: name, Start: newTestNode(0), NewNode: newTestNode, Rules: []*EdgeRule{sync}}
+	}
+	_, _, rules, err := ComposeParallel([]Component{newComponent("order", "v1"), newComponent("payment", "v1")}, SyncRuleNames)
+	if err != nil {
+		t.Fatalf("failed to compose: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Access == nil || !slices.Equal(rules[0].Access.Writes, []string{"order.k", "payment.k"}) {
+		t.Fatalf("expected the synchronised rule to write order.k and payment.k, got %+v", rules[0].Access)
+	}
+	digest := rules[0].Digest
+	_, _, rules, _ = ComposeParallel([]Component{newComponent("order", "v1"), newComponent("payment", "v2")}, SyncRuleNames)
+	if digest == "" || rules[0].Digest == digest {
+		t.Errorf("expected the digest to follow the participants' digests, got %q and %q", digest, rules[0].Digest)
+	}
+	_, _, rules, _ = ComposeParallel([]Component{newComponent("order", "v1"), newComponent("payment", "")}, SyncRuleNames)
+	if rules[0].Digest != "" {
+		t.Errorf("expected an empty digest when a participant has none, got %q", rules[0].Digest)
+	}
+}
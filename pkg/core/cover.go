@@ -0,0 +1,232 @@
+package core
+
+import (
+	"container/heap"
+	"math"
+)
+
+// TransitionPath 開始ノードから連続して辿るエッジの列
+type TransitionPath []*Edge
+
+// CoverAllTransitions すべてのエッジを少なくとも1回通る、開始ノードからのパスの集合を求める
+//
+// 各パスは開始ノードから始まり、任意のノードで終わる。総ステップ数が最小となり、その中でパス数が最小となる組み合わせを返す
+// （開始ノードからやり直せる、有向グラフの中国人郵便配達問題）。
+// 各エッジを1回ずつ通った場合の入次数と出次数の差を、エッジの追加の通過と「パスを終えて開始ノードからやり直す」遷移で
+// 打ち消す最小費用流として解き、得られた多重グラフのオイラー閉路をやり直しの箇所で区切ってパスとする。
+func CoverAllTransitions(generator *Generator) []TransitionPath {
+	startNode := generator.GetStartNode()
+	edges := generator.GetEdges()
+	if startNode == nil || len(edges) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int)
+	nodeIndex := func(node *Node) int {
+		id := (*node).GetID()
+		i, exists := index[id]
+		if !exists {
+			i = len(index)
+			index[id] = i
+		}
+		return i
+	}
+	start := nodeIndex(startNode)
+	from := make([]int, len(edges))
+	to := make([]int, len(edges))
+	for i, edge := range edges {
+		from[i] = nodeIndex(edge.GetFrom())
+		to[i] = nodeIndex(edge.GetTo())
+	}
+
+	// ノード番号: 0..n-1 グラフのノード、n パスの終わり（やり直し）、n+1 供給元、n+2 需要先
+	n := len(index)
+	restart, source, sink := n, n+1, n+2
+	flow := newCoverFlow(n + 3)
+
+	// エッジの追加の通過1回の費用を、パス数の上限（エッジ数）より大きくし、総ステップ数を優先して最小化する
+	stepCost := int64(len(edges)) + 1
+	balance := make([]int64, n)
+	edgeArcs := make([]int, len(edges))
+	for i := range edges {
+		balance[to[i]]++
+		balance[from[i]]--
+		edgeArcs[i] = flow.addArc(from[i], to[i], coverUnbounded, stepCost)
+	}
+	endArcs := make([]int, n)
+	for v := 0; v < n; v++ {
+		endArcs[v] = flow.addArc(v, restart, coverUnbounded, 0)
+	}
+	restartArc := flow.addArc(restart, start, coverUnbounded, 1)
+	for v := 0; v < n; v++ {
+		if balance[v] > 0 {
+			flow.addArc(source, v, balance[v], 0)
+		} else if balance[v] < 0 {
+			flow.addArc(v, sink, -balance[v], 0)
+		}
+	}
+	flow.solve(source, sink)
+
+	// 多重グラフ上の移動（edgeが-1の場合はやり直しのための仮想的な移動）
+	type move struct {
+		to   int
+		edge int
+	}
+	moves := make([][]move, n+1)
+	for i := range edges {
+		for c := int64(0); c <= flow.flow(edgeArcs[i]); c++ {
+			moves[from[i]] = append(moves[from[i]], move{to: to[i], edge: i})
+		}
+	}
+	for v := 0; v < n; v++ {
+		for c := int64(0); c < flow.flow(endArcs[v]); c++ {
+			moves[v] = append(moves[v], move{to: restart, edge: -1})
+		}
+	}
+	restarts := flow.flow(restartArc)
+	for c := int64(0); c < restarts; c++ {
+		moves[restart] = append(moves[restart], move{to: start, edge: -1})
+	}
+
+	// オイラー閉路（Hierholzerのアルゴリズム）。やり直しがない場合は開始ノードに戻る閉路になる
+	begin := start
+	if restarts > 0 {
+		begin = restart
+	}
+	next := make([]int, n+1)
+	stack := []move{{to: begin, edge: -1}}
+	var circuit []int // 閉路上のエッジを逆順に並べたもの
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if next[top.to] < len(moves[top.to]) {
+			stack = append(stack, moves[top.to][next[top.to]])
+			next[top.to]++
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		if len(stack) > 0 {
+			circuit = append(circuit, top.edge)
+		}
+	}
+
+	var paths []TransitionPath
+	var path TransitionPath
+	for i := len(circuit) - 1; i >= 0; i-- {
+		if circuit[i] < 0 {
+			if len(path) > 0 {
+				paths = append(paths, path)
+				path = nil
+			}
+			continue
+		}
+		path = append(path, edges[circuit[i]])
+	}
+	if len(path) > 0 {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// coverUnbounded 容量の制限がないアークの容量
+const coverUnbounded = math.MaxInt64 / 4
+
+// coverArc 残余ネットワークのアーク（順方向と逆方向を隣り合う添字に置く）
+type coverArc struct {
+	to       int
+	capacity int64 // 残りの容量
+	cost     int64
+}
+
+// coverFlow CoverAllTransitionsの最小費用流のネットワーク
+type coverFlow struct {
+	arcs     []coverArc
+	outgoing [][]int // ノードごとの出力アーク（arcsの添字）
+}
+
+func newCoverFlow(size int) *coverFlow {
+	return &coverFlow{outgoing: make([][]int, size)}
+}
+
+// addArc アークを追加し、順方向のアークの添字を返す
+func (f *coverFlow) addArc(from, to int, capacity, cost int64) int {
+	i := len(f.arcs)
+	f.arcs = append(f.arcs, coverArc{to: to, capacity: capacity, cost: cost}, coverArc{to: from, capacity: 0, cost: -cost})
+	f.outgoing[from] = append(f.outgoing[from], i)
+	f.outgoing[to] = append(f.outgoing[to], i+1)
+	return i
+}
+
+// flow 順方向のアークに流れている量
+func (f *coverFlow) flow(arc int) int64 {
+	return f.arcs[arc^1].capacity
+}
+
+// solve 最短路を繰り返し流す（ポテンシャル付きDijkstra法）ことで、sourceからsinkへ流せるだけの量を最小費用で流す
+func (f *coverFlow) solve(source, sink int) {
+	size := len(f.outgoing)
+	potential := make([]int64, size)
+	for {
+		dist := make([]int64, size)
+		for i := range dist {
+			dist[i] = math.MaxInt64
+		}
+		prev := make([]int, size)
+		dist[source] = 0
+		queue := &coverQueue{{node: source}}
+		for queue.Len() > 0 {
+			current := heap.Pop(queue).(coverEntry)
+			if current.dist > dist[current.node] {
+				continue
+			}
+			for _, i := range f.outgoing[current.node] {
+				arc := f.arcs[i]
+				if arc.capacity == 0 {
+					continue
+				}
+				d := current.dist + arc.cost + potential[current.node] - potential[arc.to]
+				if d < dist[arc.to] {
+					dist[arc.to] = d
+					prev[arc.to] = i
+					heap.Push(queue, coverEntry{node: arc.to, dist: d})
+				}
+			}
+		}
+		if dist[sink] == math.MaxInt64 {
+			return
+		}
+		for v := range potential {
+			if dist[v] != math.MaxInt64 {
+				potential[v] += dist[v]
+			}
+		}
+
+		amount := int64(coverUnbounded)
+		for v := sink; v != source; v = f.arcs[prev[v]^1].to {
+			amount = min(amount, f.arcs[prev[v]].capacity)
+		}
+		for v := sink; v != source; v = f.arcs[prev[v]^1].to {
+			f.arcs[prev[v]].capacity -= amount
+			f.arcs[prev[v]^1].capacity += amount
+		}
+	}
+}
+
+// coverEntry Dijkstra法の優先度付きキューの要素
+type coverEntry struct {
+	node int
+	dist int64
+}
+
+// coverQueue coverEntryの優先度付きキュー（container/heapの実装）
+type coverQueue []coverEntry
+
+func (q coverQueue) Len() int           { return len(q) }
+func (q coverQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q coverQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *coverQueue) Push(x any)        { *q = append(*q, x.(coverEntry)) }
+func (q *coverQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,88 @@
+package core
+
+import "testing"
+
+// checkCover パスが開始ノードから連続し、すべてのエッジを通ることを確かめ、総ステップ数を返す
+func checkCover(t *testing.T, generator *Generator, paths []TransitionPath) int {
+	t.Helper()
+	covered := make(map[*Edge]bool)
+	steps := 0
+	for _, path := range paths {
+		if len(path) == 0 {
+			t.Fatal("path must not be empty")
+		}
+		if (*path[0].GetFrom()).GetID() != "n0" {
+			t.Errorf("path must start from the start node, got %s", (*path[0].GetFrom()).GetID())
+		}
+		for i, edge := range path {
+			if i > 0 && (*path[i-1].GetTo()).GetID() != (*edge.GetFrom()).GetID() {
+				t.Errorf("path is not continuous at step %d", i)
+			}
+			covered[edge] = true
+		}
+		steps += len(path)
+	}
+	if len(covered) != len(generator.GetEdges()) {
+		t.Errorf("expected all %d edges to be covered, got %d", len(generator.GetEdges()), len(covered))
+	}
+	return steps
+}
+
+func TestCoverAllTransitions(t *testing.T) {
+	// 0 -> 1 -> 2(終端), 0 -> 3 -> 0 のループ
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 2),
+		newTestRule(t, "c", 0, 3),
+		newTestRule(t, "d", 3, 0),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	paths := CoverAllTransitions(generator)
+	if steps := checkCover(t, generator, paths); steps != 4 {
+		t.Errorf("expected 4 steps, got %d", steps)
+	}
+	if len(paths) != 1 {
+		t.Errorf("expected 1 path (c, d, a, b), got %d", len(paths))
+	}
+}
+
+func TestCoverAllTransitionsMinimal(t *testing.T) {
+	// 0 <-> 1 <-> 2 は各エッジを1回ずつ通る1本のパスで網羅できる
+	// 最も近い未到達エッジへ進む方法では、1から0へ戻った後に1を経由し直すことがある
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 0),
+		newTestRule(t, "c", 1, 2),
+		newTestRule(t, "d", 2, 1),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	paths := CoverAllTransitions(generator)
+	if steps := checkCover(t, generator, paths); steps != 4 || len(paths) != 1 {
+		t.Errorf("expected 1 path of 4 steps, got %d paths of %d steps", len(paths), steps)
+	}
+
+	// 開始ノードから3方向に分かれて終端の4で合流するグラフは、開始ノードからやり直す3本のパスで網羅する
+	rules = []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 0, 2),
+		newTestRule(t, "c", 0, 3),
+		newTestRule(t, "d", 1, 4),
+		newTestRule(t, "e", 2, 4),
+		newTestRule(t, "f", 3, 4),
+	}
+	generator = NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	paths = CoverAllTransitions(generator)
+	if steps := checkCover(t, generator, paths); steps != 6 || len(paths) != 3 {
+		t.Errorf("expected 3 paths of 6 steps, got %d paths of %d steps", len(paths), steps)
+	}
+}
@@ -9,6 +9,8 @@ type Edge struct {
 	from *Node
 	to   *Node
 	rule *EdgeRule
+	// outcome 複数の結果を持つルールの場合、このエッジに対応する結果
+	outcome *Outcome
 }
 
 // NewEdge 新しいEdgeを作成
@@ -20,9 +22,19 @@ func NewEdge(from, to *Node, rule *EdgeRule) *Edge {
 	}
 }
 
+// NewOutcomeEdge ルールの結果の1つに対応する新しいEdgeを作成
+// 名前のない結果（結果を定義していないルール）の場合はNewEdgeと同じ
+func NewOutcomeEdge(from, to *Node, rule *EdgeRule, outcome *Outcome) *Edge {
+	edge := NewEdge(from, to, rule)
+	if outcome != nil && outcome.Name != "" {
+		edge.outcome = outcome
+	}
+	return edge
+}
+
 // String エッジの文字列表現
 func (e *Edge) String() string {
-	return fmt.Sprintf("%s --%s--> %s", (*e.from).GetID(), e.rule.Name, (*e.to).GetID())
+	return fmt.Sprintf("%s --%s--> %s", (*e.from).GetID(), e.GetLabel(), (*e.to).GetID())
 }
 
 // GetFrom 開始ノードを取得
@@ -39,3 +51,26 @@ func (e *Edge) GetTo() *Node {
 func (e *Edge) GetRule() *EdgeRule {
 	return e.rule
 }
+
+// GetOutcome エッジに対応するルールの結果を取得（結果を定義していないルールの場合はnil）
+func (e *Edge) GetOutcome() *Outcome {
+	return e.outcome
+}
+
+// GetLabel エッジのラベルを取得
+// 複数の結果を持つルールの場合は「ルール名/結果名」、それ以外はルール名
+func (e *Edge) GetLabel() string {
+	if e.outcome == nil {
+		return e.rule.GetName()
+	}
+	return e.rule.GetName() + "/" + e.outcome.Name
+}
+
+// GetWeight エッジの重みを取得
+// ルールの重みを、ルールの結果の重みの比で分配したもの
+func (e *Edge) GetWeight() float64 {
+	if e.outcome == nil {
+		return e.rule.GetWeight()
+	}
+	return e.rule.GetWeight() * e.rule.outcomeShare(e.outcome)
+}
@@ -6,9 +6,16 @@ import "fmt"
 EdgeRule エッジのルールを定義
 
 	Name: ルールの名前
-	Effect: エッジ発火後のNodeを返す関数
+	Effect: エッジ発火後のNodeを返す関数（Outcomesを持つ場合は最初の結果のエフェクト）
+	Outcomes: ルールの結果の一覧。複数の結果を持つルールは、結果ごとに「ルール名/結果名」のエッジを生成する（省略時はEffectのみ）
 	FireCondition: ルールが発火する条件に合致した場合にtrueを返す関数
 	BlockCondition: ルールがブロックされる条件に合致した場合にtrueを返す関数(前提として、FireConditionがtrueの場合に評価する)
+	Priority: ルールの優先度。ある状態で実行可能なルールのうち、最も優先度が高いものだけが遷移を生成する（既定値は0）
+	Weight: ルールの重み。ある状態で実行可能なルールの重みの比で遷移確率を定める（既定値は1、マルコフ連鎖の解析に使用）
+	FireConditionText: 発火条件の人が読める表現（任意、シミュレーターなどの表示に使用）
+	BlockConditionText: ブロック条件の人が読める表現（任意、シミュレーターなどの表示に使用）
+	Access: ルールが参照・変更するリソースのキー（任意、半順序削減に使用。nilの場合は不明として扱う）
+	Digest: ルールの定義の内容のハッシュ（任意、評価キャッシュに使用。空の場合は常に変更されたものとして扱う）
 
 EffectやFireCondition, BlockConditionは処理中に型が違う場合panicを起こしたほうが良い。
 
@@ -17,8 +24,26 @@ EffectやFireCondition, BlockConditionは処理中に型が違う場合panicを
 type EdgeRule struct {
 	Name           string
 	Effect         func(*Node) *Node
+	Outcomes       []*Outcome
 	FireCondition  func(*Node) bool
 	BlockCondition func(*Node) bool
+	Priority       int
+	Weight         float64
+
+	FireConditionText  string
+	BlockConditionText string
+
+	Access *RuleAccess
+	Digest string
+}
+
+// RuleAccess ルールが参照・変更するリソースのキー
+// 半順序削減で、ルールどうしが独立か（実行順を入れ替えても同じ状態になり、互いの実行可否を変えないか）を判定するために使う。
+// パーサーが条件式と効果から推定して設定する。過不足があると削減の結果が正しくなくなるため、推定できない場合は設定しないこと。
+type RuleAccess struct {
+	Reads    []string // 発火条件・ブロック条件・効果が値や有無を参照するキー
+	Writes   []string // 効果が作成・更新・削除する可能性のあるキー
+	ReadsAll bool     // すべてのキーを参照する（リソースが空かどうかを見る条件など）
 }
 
 // NewEdgeRule 新しいEdgeRuleを作成
@@ -42,19 +67,104 @@ func NewEdgeRule(
 		Effect:         effect,
 		FireCondition:  fireCondition,
 		BlockCondition: blockCondition,
+		Weight:         1,
 	}, nil
 }
 
+// Outcome ルールの結果の1つ（成功・タイムアウト・失敗など）
+type Outcome struct {
+	Name   string
+	Effect func(*Node) *Node
+	Weight float64 // 同じルールの結果の中での重み（既定値は1）
+}
+
+// NewOutcome 新しいOutcomeを作成
+func NewOutcome(name string, effect func(*Node) *Node) (*Outcome, error) {
+	if name == "" {
+		return nil, fmt.Errorf("outcome name cannot be empty")
+	}
+	if effect == nil {
+		return nil, fmt.Errorf("effect function cannot be nil for outcome: %s", name)
+	}
+	return &Outcome{Name: name, Effect: effect, Weight: 1}, nil
+}
+
+// NewEdgeRuleWithOutcomes 複数の結果を持つEdgeRuleを作成
+func NewEdgeRuleWithOutcomes(
+	name string,
+	outcomes []*Outcome,
+	fireCondition func(*Node) bool,
+	blockCondition func(*Node) bool,
+) (*EdgeRule, error) {
+	if len(outcomes) == 0 {
+		return nil, fmt.Errorf("outcomes cannot be empty for rule: %s", name)
+	}
+	seen := make(map[string]bool)
+	for _, outcome := range outcomes {
+		if seen[outcome.Name] {
+			return nil, fmt.Errorf("duplicate outcome %s in rule: %s", outcome.Name, name)
+		}
+		seen[outcome.Name] = true
+	}
+	rule, err := NewEdgeRule(name, outcomes[0].Effect, fireCondition, blockCondition)
+	if err != nil {
+		return nil, err
+	}
+	rule.Outcomes = outcomes
+	return rule, nil
+}
+
 // GetName ルール名を取得
 func (r *EdgeRule) GetName() string {
 	return r.Name
 }
 
+// GetPriority ルールの優先度を取得
+func (r *EdgeRule) GetPriority() int {
+	return r.Priority
+}
+
+// GetWeight ルールの重みを取得
+func (r *EdgeRule) GetWeight() float64 {
+	return r.Weight
+}
+
 // GetEffect エフェクト関数を取得
 func (r *EdgeRule) GetEffect() func(*Node) *Node {
 	return r.Effect
 }
 
+// GetOutcomes ルールの結果の一覧を取得
+// 結果を定義していないルールは、名前が空でEffectを持つ結果1つとして返す
+func (r *EdgeRule) GetOutcomes() []*Outcome {
+	if len(r.Outcomes) > 0 {
+		return r.Outcomes
+	}
+	return []*Outcome{{Effect: r.Effect, Weight: 1}}
+}
+
+// GetOutcome 名前から結果を取得
+func (r *EdgeRule) GetOutcome(name string) (*Outcome, bool) {
+	for _, outcome := range r.GetOutcomes() {
+		if outcome.Name == name {
+			return outcome, true
+		}
+	}
+	return nil, false
+}
+
+// outcomeShare 結果の重みが、ルールのすべての結果の重みに占める割合
+func (r *EdgeRule) outcomeShare(outcome *Outcome) float64 {
+	total := 0.0
+	for _, o := range r.GetOutcomes() {
+		total += o.Weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return outcome.Weight / total
+}
+
 // GetFireCondition 発火条件関数を取得
 func (r *EdgeRule) GetFireCondition() func(*Node) bool {
 	return r.FireCondition
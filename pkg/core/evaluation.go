@@ -0,0 +1,56 @@
+package core
+
+// RuleEvaluation ある状態に対するルールの評価結果
+type RuleEvaluation struct {
+	Rule  *EdgeRule
+	Fire  bool // 発火条件の評価結果
+	Block bool // ブロック条件の評価結果
+}
+
+// Enabled ルールが実行可能（発火条件がtrueかつブロック条件がfalse）かどうか
+func (e RuleEvaluation) Enabled() bool {
+	return e.Fire && !e.Block
+}
+
+// EvaluateRules 指定されたノードに対して各ルールの発火条件・ブロック条件を評価する
+// BlockConditionはFireConditionがtrueの場合のみ評価し、それ以外はfalseとする
+func EvaluateRules(node *Node, rules []*EdgeRule) []RuleEvaluation {
+	evaluations := make([]RuleEvaluation, 0, len(rules))
+	for _, rule := range rules {
+		fire := rule.GetFireCondition()(node)
+		block := false
+		if fire {
+			block = rule.GetBlockCondition()(node)
+		}
+		evaluations = append(evaluations, RuleEvaluation{Rule: rule, Fire: fire, Block: block})
+	}
+	return evaluations
+}
+
+// SelectByPriority 実行可能なルールのうち、最も優先度が高いものとそれ以外に分ける
+// suppressedには実行可能だが優先度が低いために遷移を生成しないルールが入る
+func SelectByPriority(evaluations []RuleEvaluation) (selected []RuleEvaluation, suppressed []RuleEvaluation) {
+	maxPriority := 0
+	found := false
+	for _, evaluation := range evaluations {
+		if !evaluation.Enabled() {
+			continue
+		}
+		if !found || evaluation.Rule.GetPriority() > maxPriority {
+			maxPriority = evaluation.Rule.GetPriority()
+			found = true
+		}
+	}
+
+	for _, evaluation := range evaluations {
+		if !evaluation.Enabled() {
+			continue
+		}
+		if evaluation.Rule.GetPriority() == maxPriority {
+			selected = append(selected, evaluation)
+		} else {
+			suppressed = append(suppressed, evaluation)
+		}
+	}
+	return selected, suppressed
+}
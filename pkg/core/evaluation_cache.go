@@ -0,0 +1,181 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// evaluationCacheVersion 評価キャッシュの形式のバージョン
+const evaluationCacheVersion = 1
+
+// EvaluationCache 次回の生成のために保存する、ノードごとのルールの発火条件・ブロック条件の評価結果
+// ノードのリソースは保存せず、評価結果をノードIDとルールの定義のハッシュ（EdgeRule.Digest）で引く。
+// 次回の生成では、定義が変わっていないルールの条件の評価だけを省く。状態空間の探索と効果の適用はすべてやり直すため、
+// 省けるのは条件の評価の費用のみで、効果やリソースの形式によらず使える。
+type EvaluationCache struct {
+	Version int                         `json:"version"`
+	Rules   map[string]string           `json:"rules"` // ルール名ごとの定義のハッシュ
+	Nodes   map[string]CachedEvaluation `json:"nodes"` // 展開したノードのIDごとの評価結果
+}
+
+// CachedEvaluation キャッシュに保存する、1つのノードでのルールの評価結果
+type CachedEvaluation struct {
+	Fire  []string `json:"fire,omitempty"`  // 発火条件がtrueだったルールの名前
+	Block []string `json:"block,omitempty"` // ブロック条件がtrueだったルールの名前（発火条件がtrueのもののみ）
+}
+
+// EvaluationCacheStats 評価キャッシュを使った生成の効果
+type EvaluationCacheStats struct {
+	ReusedNodes    int64    // キャッシュの評価結果を再利用したノードの数
+	EvaluatedNodes int64    // キャッシュになく、すべてのルールを評価したノードの数
+	ChangedRules   []string // 定義が追加・変更されたため、評価し直したルールの名前
+}
+
+// RuleDigest ルールの定義（パーサーが読み込んだ構造体など）から、キャッシュのキーに使うハッシュを求める
+// JSONに変換できない場合は空文字列を返し、そのルールは常に変更されたものとして扱われる
+func RuleDigest(definition any) string {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadEvaluationCache JSON形式の評価キャッシュを読み込む
+func ReadEvaluationCache(r io.Reader) (*EvaluationCache, error) {
+	var cache EvaluationCache
+	if err := json.NewDecoder(r).Decode(&cache); err != nil {
+		return nil, fmt.Errorf("キャッシュの読み込みに失敗: %w", err)
+	}
+	if cache.Version != evaluationCacheVersion {
+		return nil, fmt.Errorf("未対応のキャッシュのバージョンです: %d", cache.Version)
+	}
+	return &cache, nil
+}
+
+// Write 評価キャッシュをJSON形式で書き込む
+func (c *EvaluationCache) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// evaluationCacheState ジェネレーターが使う、前回の評価結果と今回の評価結果
+type evaluationCacheState struct {
+	previous  map[string]CachedEvaluation
+	unchanged map[*EdgeRule]bool // 前回から定義が変わっていないルール
+	current   map[string]CachedEvaluation
+	stats     EvaluationCacheStats
+}
+
+// SetEvaluationCache 前回の生成で保存したキャッシュを設定する（nilの場合はキャッシュを使わない）
+// 空のキャッシュ（&EvaluationCache{}）を設定すると、すべてのルールを評価し、次回のための評価結果だけを記録する。
+// 名前と定義のハッシュが前回と同じルールは評価を省き、Digestが空のルールや名前が重複したルールは常に評価し直す。
+// 前回のノードのうち今回到達しなかったものは、EvaluationCacheで取得するキャッシュに含まれない。
+func (g *Generator) SetEvaluationCache(cache *EvaluationCache) {
+	if cache == nil {
+		g.evaluationCache = nil
+		return
+	}
+	state := &evaluationCacheState{
+		previous:  cache.Nodes,
+		unchanged: make(map[*EdgeRule]bool, len(g.edgeRules)),
+		current:   make(map[string]CachedEvaluation),
+	}
+	names := make(map[string]int, len(g.edgeRules))
+	for _, rule := range g.edgeRules {
+		names[rule.GetName()]++
+	}
+	for _, rule := range g.edgeRules {
+		if rule.Digest != "" && names[rule.GetName()] == 1 && cache.Rules[rule.GetName()] == rule.Digest {
+			state.unchanged[rule] = true
+			continue
+		}
+		state.stats.ChangedRules = append(state.stats.ChangedRules, rule.GetName())
+	}
+	slog.Debug("[CACHE] 評価キャッシュ", "nodes", len(cache.Nodes), "changedRules", state.stats.ChangedRules)
+	g.evaluationCache = state
+}
+
+// EvaluationCache 今回の生成での評価結果を、次回の再生成のためのキャッシュとして取得
+// 展開したノードの評価結果のみを含む。SetEvaluationCacheを呼んでいない場合はnilを返す。
+func (g *Generator) EvaluationCache() *EvaluationCache {
+	if g.evaluationCache == nil {
+		return nil
+	}
+	cache := &EvaluationCache{
+		Version: evaluationCacheVersion,
+		Rules:   make(map[string]string, len(g.edgeRules)),
+		Nodes:   make(map[string]CachedEvaluation, len(g.processedNodes)),
+	}
+	for _, rule := range g.edgeRules {
+		if rule.Digest != "" {
+			cache.Rules[rule.GetName()] = rule.Digest
+		}
+	}
+	for id := range g.processedNodes {
+		if evaluation, exists := g.evaluationCache.current[id]; exists {
+			cache.Nodes[id] = evaluation
+		}
+	}
+	return cache
+}
+
+// GetEvaluationCacheStats キャッシュを使った再生成の効果を取得
+func (g *Generator) GetEvaluationCacheStats() EvaluationCacheStats {
+	if g.evaluationCache == nil {
+		return EvaluationCacheStats{}
+	}
+	return g.evaluationCache.stats
+}
+
+// evaluateRules ノードでの各ルールの評価結果を求める（キャッシュを設定している場合は再利用する）
+func (g *Generator) evaluateRules(node *Node) []RuleEvaluation {
+	if g.evaluationCache == nil {
+		return EvaluateRules(node, g.edgeRules)
+	}
+	return g.evaluationCache.evaluate(node, g.edgeRules)
+}
+
+// evaluate 前回の評価結果があるノードでは、変更されたルールだけを評価する
+func (c *evaluationCacheState) evaluate(node *Node, rules []*EdgeRule) []RuleEvaluation {
+	id := (*node).GetID()
+	var evaluations []RuleEvaluation
+	if cached, exists := c.previous[id]; exists {
+		fire := make(map[string]bool, len(cached.Fire))
+		for _, name := range cached.Fire {
+			fire[name] = true
+		}
+		block := make(map[string]bool, len(cached.Block))
+		for _, name := range cached.Block {
+			block[name] = true
+		}
+		evaluations = make([]RuleEvaluation, 0, len(rules))
+		for _, rule := range rules {
+			if c.unchanged[rule] {
+				evaluations = append(evaluations, RuleEvaluation{Rule: rule, Fire: fire[rule.GetName()], Block: block[rule.GetName()]})
+				continue
+			}
+			evaluations = append(evaluations, EvaluateRules(node, []*EdgeRule{rule})...)
+		}
+		c.stats.ReusedNodes++
+	} else {
+		evaluations = EvaluateRules(node, rules)
+		c.stats.EvaluatedNodes++
+	}
+
+	var record CachedEvaluation
+	for _, evaluation := range evaluations {
+		if evaluation.Fire {
+			record.Fire = append(record.Fire, evaluation.Rule.GetName())
+		}
+		if evaluation.Block {
+			record.Block = append(record.Block, evaluation.Rule.GetName())
+		}
+	}
+	c.current[id] = record
+	return evaluations
+}
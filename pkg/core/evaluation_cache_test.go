@@ -0,0 +1,86 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestEvaluationCache(t *testing.T) {
+	// 0 -> 1 -> 2 -> 3, 0 -> 4。bの定義を変更して1 -> 5に遷移させると、2と3には到達しなくなる
+	evaluated := make(map[string]int)
+	newRules := func(bTo int) []*EdgeRule {
+		rules := []*EdgeRule{
+			newTestRule(t, "a", 0, 1),
+			newTestRule(t, "b", 1, bTo),
+			newTestRule(t, "c", 2, 3),
+			newTestRule(t, "d", 0, 4),
+		}
+		for _, rule := range rules {
+			fire := rule.FireCondition
+			name := rule.GetName()
+			rule.FireCondition = func(n *Node) bool {
+				evaluated[name]++
+				return fire(n)
+			}
+			rule.Digest = RuleDigest(name)
+		}
+		rules[1].Digest = RuleDigest(fmt.Sprintf("b to %d", bTo))
+		return rules
+	}
+
+	first := NewGenerator(newTestNode, newTestNode(0), newRules(2), nil)
+	first.SetEvaluationCache(&EvaluationCache{})
+	if err := first.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	var saved bytes.Buffer
+	if err := first.EvaluationCache().Write(&saved); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+	cache, err := ReadEvaluationCache(&saved)
+	if err != nil {
+		t.Fatalf("failed to read cache: %v", err)
+	}
+	if len(cache.Nodes) != 5 {
+		t.Errorf("expected evaluations of 5 nodes, got %d", len(cache.Nodes))
+	}
+
+	clear(evaluated)
+	second := NewGenerator(newTestNode, newTestNode(0), newRules(5), nil)
+	second.SetEvaluationCache(cache)
+	if err := second.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if evaluated["a"] != 1 || evaluated["b"] != 4 {
+		t.Errorf("expected a to be evaluated only at the new node and b at every node, got %v", evaluated)
+	}
+	fresh := NewGenerator(newTestNode, newTestNode(0), newRules(5), nil)
+	if err := fresh.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if len(second.GetNodes()) != len(fresh.GetNodes()) || len(second.GetEdges()) != len(fresh.GetEdges()) {
+		t.Errorf("expected the same graph as a fresh generation, got %d nodes and %d edges (fresh %d and %d)",
+			len(second.GetNodes()), len(second.GetEdges()), len(fresh.GetNodes()), len(fresh.GetEdges()))
+	}
+
+	stats := second.GetEvaluationCacheStats()
+	if len(stats.ChangedRules) != 1 || stats.ChangedRules[0] != "b" {
+		t.Errorf("expected only b to be changed, got %v", stats.ChangedRules)
+	}
+	// n0, n1, n4は前回の評価を再利用し、新しいn5だけをすべてのルールで評価する
+	if stats.ReusedNodes != 3 || stats.EvaluatedNodes != 1 {
+		t.Errorf("expected 3 reused and 1 evaluated nodes, got %+v", stats)
+	}
+
+	// 到達しなくなったノードはキャッシュから取り除く
+	pruned := second.EvaluationCache()
+	for _, id := range []string{"n2", "n3"} {
+		if _, exists := pruned.Nodes[id]; exists {
+			t.Errorf("expected unreachable node %s to be pruned from the cache", id)
+		}
+	}
+	if len(pruned.Nodes) != 4 {
+		t.Errorf("expected evaluations of 4 nodes, got %d", len(pruned.Nodes))
+	}
+}
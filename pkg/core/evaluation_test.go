@@ -0,0 +1,27 @@
+package core
+
+import "testing"
+
+func TestPriority(t *testing.T) {
+	normal := newTestRule(t, "normal", 0, 1)
+	handler := newTestRule(t, "handler", 0, 2)
+	handler.Priority = 10
+	generator := NewGenerator(newTestNode, newTestNode(0), []*EdgeRule{normal, handler}, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	edges := generator.GetEdges()
+	if len(edges) != 1 || edges[0].GetRule() != handler {
+		t.Fatalf("expected only the high priority rule to produce an edge, got %v", edges)
+	}
+	suppressed := generator.GetSuppressedRules()
+	if len(suppressed) != 1 || suppressed[0].Rule != normal || suppressed[0].ByPriority != 10 {
+		t.Errorf("expected normal to be suppressed by priority 10, got %+v", suppressed)
+	}
+
+	coverage := generator.GetRuleCoverage()
+	if coverage[0].Fired != 0 || coverage[0].Suppressed != 1 || coverage[1].Fired != 1 {
+		t.Errorf("unexpected coverage: %+v", coverage)
+	}
+}
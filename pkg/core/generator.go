@@ -1,19 +1,45 @@
 package core
 
 import (
+	"context"
 	"log/slog"
 	"sort"
 )
 
 // Generator ステートマシン生成器
 type Generator struct {
-	newNode        func(resources any) Node
-	startResources Node
-	edgeRules      []*EdgeRule
-	nodes          map[string]Node // インターフェースを使用
-	edges          []*Edge
-	processedNodes map[string]bool
-	limit          *int64
+	newNode         func(resources any) Node
+	startResources  Node
+	edgeRules       []*EdgeRule
+	nodes           map[string]Node // インターフェースを使用
+	edges           []*Edge
+	processedNodes  map[string]bool
+	queue           []*Node        // 展開を待っているノード（中断した探索の再開に使用）
+	depths          map[string]int // ノードIDごとの開始ノードからの深さ
+	suppressed      []SuppressedRule
+	limit           *int64
+	iterations      int64 // 反復回数の累計（チェックポイントから再開した場合は保存時の回数から数える）
+	strategy        SearchStrategy
+	maxDepth        *int
+	bound           int // 反復深化の現在の深さの上限
+	reduction       *reduction
+	evaluationCache *evaluationCacheState
+	observers       []GeneratorObserver
+	grouper         func(node *Node) []string
+}
+
+// SuppressedRule 実行可能だったが、より優先度の高いルールによって抑制されたルール
+type SuppressedRule struct {
+	Node       *Node     // 抑制が起きた状態
+	Rule       *EdgeRule // 抑制されたルール
+	ByPriority int       // 遷移を生成したルールの優先度
+}
+
+// RuleCoverage ルールごとの適用状況
+type RuleCoverage struct {
+	Rule       *EdgeRule
+	Fired      int // 遷移を生成した回数
+	Suppressed int // 優先度によって抑制された回数
 }
 
 // NewGenerator 新しいジェネレーターを作成
@@ -30,66 +56,139 @@ func NewGenerator(
 		nodes:          make(map[string]Node),
 		edges:          make([]*Edge, 0),
 		processedNodes: make(map[string]bool),
+		depths:         make(map[string]int),
 		limit:          limit,
 	}
 }
 
 // Generate ステートマシンを生成
 func (g *Generator) Generate() error {
-	startNode := g.newNode(g.startResources.GetResources())
-	startNodePtr := g.addOrGetNode(&startNode)
-	queue := []*Node{startNodePtr}
+	return g.GenerateContext(context.Background())
+}
 
-	slog.Debug("[START] 開始ノード", "resources", (*startNodePtr).GetResources(), "id", (*startNodePtr).GetID())
+// GenerateContext ステートマシンを生成（ctxがキャンセルされた場合は探索を中断する）
+// 中断した場合はctx.Err()を返す。それまでに生成したノードとエッジはそのまま取得でき、Checkpointで保存できる。
+// 中断後やRestoreの後に再び呼ぶと、展開を待っているノードから探索を続ける。
+// 反復回数の上限は、中断・再開をまたいだ反復回数の累計に対して適用する。
+// 探索の順序はSetStrategy、展開する深さの上限はSetMaxDepthで設定する。
+func (g *Generator) GenerateContext(ctx context.Context) error {
+	if len(g.nodes) == 0 {
+		startNode := g.newNode(g.startResources.GetResources())
+		startNodePtr := g.addOrGetNode(&startNode)
+		g.recordDepth((*startNodePtr).GetID(), 0)
+		g.queue = []*Node{startNodePtr}
+		slog.Debug("[START] 開始ノード", "resources", logResources(startNodePtr), "id", (*startNodePtr).GetID(), "strategy", g.strategy.String())
+	} else {
+		g.requeueDeferred()
+		slog.Debug("[RESUME] 探索を再開", "nodes", len(g.nodes), "queueSize", len(g.queue))
+	}
+
+	var err error
+	if g.strategy == StrategyIterativeDeepening {
+		g.queue = nil
+		err = g.generateIterativeDeepening(ctx)
+	} else {
+		err = g.generateQueue(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("[COMPLETE]", "iterations", g.iterations, "nodes", len(g.nodes), "edges", len(g.edges))
 
-	var iterationCount int64 = 0
-	for len(queue) > 0 {
-		iterationCount++
-		if g.limit != nil && iterationCount > *g.limit {
+	// デバッグ: すべてのノードを出力
+	slog.Debug("[DEBUG] 生成されたノード一覧")
+	for id, node := range g.nodes {
+		slog.Debug("ノード情報", "id", id, "resources", logResources(&node), "depth", g.depths[id])
+	}
+
+	return nil
+}
+
+// generateQueue 展開を待っているノードのキューを使って探索する（幅優先探索と深さ優先探索）
+// 深さの上限がある深さ優先探索では、展開済みのノードへより浅い経路が見つかった場合に、その先のノードへ深さを伝え直す。
+// 伝え直さないと、先に深い経路で見つけたノードの先が上限で打ち切られたままになるため。
+func (g *Generator) generateQueue(ctx context.Context) error {
+	var outgoing map[string][]*Edge
+	if g.strategy == StrategyDFS && g.maxDepth != nil {
+		outgoing = g.getOutgoingEdges()
+	}
+
+	for len(g.queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			slog.Warn("[INTERRUPT] 探索を中断しました", "nodes", len(g.nodes), "queueSize", len(g.queue))
+			return err
+		}
+		if g.limit != nil && g.iterations >= *g.limit {
 			slog.Error("[ERROR] 反復回数が上限を超えました。強制終了します。")
+			for _, observer := range g.observers {
+				observer.OnLimitReached(*g.limit)
+			}
 			break
 		}
+		g.iterations++
 
-		currentNode := queue[0]
-		queue = queue[1:]
+		currentNode := g.popQueue()
 		nodeID := (*currentNode).GetID()
+		depth := g.depths[nodeID]
 
-		slog.Debug("[ITERATION]", "count", iterationCount, "resources", (*currentNode).GetResources(), "id", nodeID, "queueSize", len(queue))
+		slog.Debug("[ITERATION]", "count", g.iterations, "resources", logResources(currentNode), "id", nodeID, "depth", depth, "queueSize", len(g.queue))
 
 		if g.processedNodes[nodeID] {
+			if outgoing != nil {
+				g.pushQueue(g.propagateDepth(outgoing[nodeID], depth))
+			}
 			slog.Debug("[SKIP] すでに処理済み", "id", nodeID)
 			continue
 		}
+		if !g.withinMaxDepth(depth) {
+			slog.Debug("[DEPTH_LIMIT] 深さの上限のため展開しない", "id", nodeID, "depth", depth)
+			continue
+		}
 
 		g.processedNodes[nodeID] = true
 		newEdges := g.generateEdgesFromNode(currentNode)
+		if outgoing != nil {
+			outgoing[nodeID] = newEdges
+		}
 
 		slog.Debug("[EDGES]", "count", len(newEdges))
 
+		var next []*Node
 		for _, edge := range newEdges {
 			g.edges = append(g.edges, edge)
 			targetNodeID := (*edge.GetTo()).GetID()
+			improved := g.recordDepth(targetNodeID, depth+1)
 			slog.Debug("[EDGE_ADD]", "edge", edge.String())
-			if !g.processedNodes[targetNodeID] {
-				queue = append(queue, edge.GetTo())
-				slog.Debug("[QUEUE_ADD] キューに追加", "resources", (*edge.GetTo()).GetResources(), "id", (*edge.GetTo()).GetID())
+			for _, observer := range g.observers {
+				observer.OnEdgeAdded(edge)
+			}
+			if !g.processedNodes[targetNodeID] || (outgoing != nil && improved) {
+				next = append(next, edge.GetTo())
+				slog.Debug("[QUEUE_ADD] キューに追加", "resources", logResources(edge.GetTo()), "id", targetNodeID)
 			} else {
-				slog.Debug("[QUEUE_SKIP] すでに処理済みのためキューに追加しない", "resources", (*edge.GetTo()).GetResources(), "id", (*edge.GetTo()).GetID())
+				slog.Debug("[QUEUE_SKIP] すでに処理済みのためキューに追加しない", "resources", logResources(edge.GetTo()), "id", targetNodeID)
 			}
 		}
+		g.pushQueue(next)
+		for _, observer := range g.observers {
+			observer.OnNodeExpanded(currentNode, newEdges, len(g.queue))
+		}
 
-		slog.Debug("[QUEUE_STATUS]", "size", len(queue))
+		slog.Debug("[QUEUE_STATUS]", "size", len(g.queue))
 	}
+	return nil
+}
 
-	slog.Debug("[COMPLETE]", "iterations", iterationCount, "nodes", len(g.nodes), "edges", len(g.edges))
-
-	// デバッグ: すべてのノードを出力
-	slog.Debug("[DEBUG] 生成されたノード一覧")
-	for id, node := range g.nodes {
-		slog.Debug("ノード情報", "id", id, "resources", node.GetResources())
+// propagateDepth 展開済みのノードの深さが浅くなった場合に、遷移先の深さを更新し、浅くなったノードを返す
+func (g *Generator) propagateDepth(edges []*Edge, depth int) []*Node {
+	var improved []*Node
+	for _, edge := range edges {
+		if g.recordDepth((*edge.GetTo()).GetID(), depth+1) {
+			improved = append(improved, edge.GetTo())
+		}
 	}
-
-	return nil
+	return improved
 }
 
 // GetNodes 生成されたノードを取得
@@ -125,35 +224,172 @@ func (g *Generator) GetStartNode() *Node {
 	return nil
 }
 
+// GetIterations 反復回数の累計を取得（チェックポイントから再開した場合は保存時までの回数を含む）
+func (g *Generator) GetIterations() int64 {
+	return g.iterations
+}
+
+// GetSuppressedRules 優先度によって抑制されたルールを取得
+func (g *Generator) GetSuppressedRules() []SuppressedRule {
+	return g.suppressed
+}
+
+// GetRuleCoverage ルールごとの適用状況を、ルールの定義順に取得
+func (g *Generator) GetRuleCoverage() []RuleCoverage {
+	fired := make(map[*EdgeRule]int)
+	for _, edge := range g.edges {
+		fired[edge.GetRule()]++
+	}
+	suppressed := make(map[*EdgeRule]int)
+	for _, s := range g.suppressed {
+		suppressed[s.Rule]++
+	}
+
+	coverage := make([]RuleCoverage, 0, len(g.edgeRules))
+	for _, rule := range g.edgeRules {
+		coverage = append(coverage, RuleCoverage{Rule: rule, Fired: fired[rule], Suppressed: suppressed[rule]})
+	}
+	return coverage
+}
+
+// GetDeadlockNodes 出力エッジを持たない（どのルールも実行できない）ノードを取得
+// 反復回数の上限や中断により展開されなかったノード（GetFrontierNodes）は含まない
+// GetNodesと同様にノードIDでソートして返す
+func (g *Generator) GetDeadlockNodes() []*Node {
+	outgoing := g.getOutgoingEdges()
+	var deadlocks []*Node
+	for _, node := range g.GetNodes() {
+		if len(outgoing[(*node).GetID()]) == 0 && !g.IsFrontier(node) {
+			deadlocks = append(deadlocks, node)
+		}
+	}
+	return deadlocks
+}
+
+// GetFrontierNodes 発見されたが、反復回数の上限や中断により展開されなかった（遷移先を調べていない）ノードを取得
+// GetNodesと同様にノードIDでソートして返す
+func (g *Generator) GetFrontierNodes() []*Node {
+	var frontier []*Node
+	for _, node := range g.GetNodes() {
+		if g.IsFrontier(node) {
+			frontier = append(frontier, node)
+		}
+	}
+	return frontier
+}
+
+// IsFrontier ノードが発見されたが展開されていないかどうか
+func (g *Generator) IsFrontier(node *Node) bool {
+	id := (*node).GetID()
+	_, exists := g.nodes[id]
+	return exists && !g.processedNodes[id]
+}
+
+// IsPartial 反復回数の上限や中断により、展開されていないノードが残っている（グラフが不完全）かどうか
+func (g *Generator) IsPartial() bool {
+	for id := range g.nodes {
+		if !g.processedNodes[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// getOutgoingEdges ノードIDごとの出力エッジを取得
+func (g *Generator) getOutgoingEdges() map[string][]*Edge {
+	outgoing := make(map[string][]*Edge)
+	for _, edge := range g.edges {
+		fromID := (*edge.GetFrom()).GetID()
+		outgoing[fromID] = append(outgoing[fromID], edge)
+	}
+	return outgoing
+}
+
 // addOrGetNode ノードを追加または取得
 func (g *Generator) addOrGetNode(node *Node) *Node {
 	id := (*node).GetID()
-	slog.Debug("[NODE_DEBUG]", "resources", (*node).GetResources(), "id", id)
+	slog.Debug("[NODE_DEBUG]", "resources", logResources(node), "id", id)
 	if existingNode, exists := g.nodes[id]; exists {
 		slog.Debug("[NODE_REUSE] 既存ノードを再利用", "id", id)
 		return &existingNode
 	}
 	g.nodes[id] = *node
-	slog.Debug("[NODE_CREATE] 新しいノードを作成", "id", id, "resources", (*node).GetResources())
+	slog.Debug("[NODE_CREATE] 新しいノードを作成", "id", id, "resources", logResources(node))
+	for _, observer := range g.observers {
+		observer.OnNodeDiscovered(node)
+	}
 	return node
 }
 
 // generateEdgesFromNode 指定されたノードから適用可能なエッジを生成
 func (g *Generator) generateEdgesFromNode(node *Node) []*Edge {
-	var edges []*Edge
+	edges, suppressed := g.expand(node, func(id string) bool {
+		_, exists := g.nodes[id]
+		return exists
+	})
+	for _, rule := range suppressed {
+		g.suppressed = append(g.suppressed, SuppressedRule{Node: node, Rule: rule, ByPriority: edges[0].GetRule().GetPriority()})
+	}
+	for _, edge := range edges {
+		edge.to = g.addOrGetNode(edge.GetTo())
+	}
+	return edges
+}
 
-	for _, rule := range g.edgeRules {
-		fire := rule.GetFireCondition()(node)
-		block := rule.GetBlockCondition()(node)
-		slog.Debug("[CHECK]", "resources", (*node).GetResources(), "rule", rule.GetName(), "fire", fire, "block", block)
-		if fire && !block {
-			newNode := rule.GetEffect()(node)
-			slog.Debug("[EFFECT]", "resources", (*node).GetResources(), "rule", rule.GetName(), "newResources", (*newNode).GetResources(), "newId", (*newNode).GetID())
-			actualNewNode := g.addOrGetNode(newNode)
-			edge := NewEdge(node, actualNewNode, rule)
-			edges = append(edges, edge)
+// successors 指定されたノードから遷移可能なエッジを求める（遷移先はジェネレーターに追加しない）
+// 複数の結果を持つルールは、結果ごとにエッジを生成する
+// 最も優先度が高い実行可能なルールだけがエッジを生成し、それより低いルールはsuppressedとして返す
+func (g *Generator) successors(node *Node) (edges []*Edge, suppressed []*EdgeRule) {
+	enabled, suppressed := g.enabledRules(node)
+	return g.applyRules(node, enabled), suppressed
+}
+
+// enabledRules 指定されたノードで遷移を生成するルールと、優先度によって抑制されたルールを求める
+func (g *Generator) enabledRules(node *Node) (enabled []*EdgeRule, suppressed []*EdgeRule) {
+	evaluations := g.evaluateRules(node)
+	for _, evaluation := range evaluations {
+		slog.Debug("[CHECK]", "resources", logResources(node), "rule", evaluation.Rule.GetName(), "fire", evaluation.Fire, "block", evaluation.Block)
+		for _, observer := range g.observers {
+			observer.OnRuleEvaluated(node, evaluation.Rule, evaluation.Fire, evaluation.Block)
 		}
 	}
 
+	selected, lower := SelectByPriority(evaluations)
+	for _, evaluation := range selected {
+		enabled = append(enabled, evaluation.Rule)
+	}
+	for _, evaluation := range lower {
+		slog.Debug("[SUPPRESS] 優先度の高いルールにより抑制", "resources", logResources(node), "rule", evaluation.Rule.GetName(), "priority", evaluation.Rule.GetPriority())
+		suppressed = append(suppressed, evaluation.Rule)
+	}
+	return enabled, suppressed
+}
+
+// applyRules ルールを適用してエッジを生成する（複数の結果を持つルールは、結果ごとにエッジを生成する）
+func (g *Generator) applyRules(node *Node, rules []*EdgeRule) []*Edge {
+	var edges []*Edge
+	for _, rule := range rules {
+		for _, outcome := range rule.GetOutcomes() {
+			newNode := outcome.Effect(node)
+			slog.Debug("[EFFECT]", "resources", logResources(node), "rule", rule.GetName(), "outcome", outcome.Name, "newResources", logResources(newNode), "newId", (*newNode).GetID())
+			edges = append(edges, NewOutcomeEdge(node, newNode, rule, outcome))
+		}
+	}
 	return edges
 }
+
+// resourcesLogValue ログに出力するときだけノードのリソースを取得する値
+// デバッグログが無効な場合に、ログのためだけにリソースを組み立てないようにする
+type resourcesLogValue struct {
+	node *Node
+}
+
+// LogValue slog.LogValuerの実装
+func (v resourcesLogValue) LogValue() slog.Value {
+	return slog.AnyValue((*v.node).GetResources())
+}
+
+// logResources ノードのリソースをログの属性の値として渡す
+func logResources(node *Node) slog.LogValuer {
+	return resourcesLogValue{node: node}
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testNode テスト用の整数1つを状態とするノード
+type testNode int
+
+func (n testNode) GetID() string                { return fmt.Sprintf("n%d", int(n)) }
+func (n testNode) Equals(other Node) bool       { return n.GetID() == other.GetID() }
+func (n testNode) GetResources() any            { return int(n) }
+func (n testNode) GetResourcesString() []string { return []string{n.GetID()} }
+
+func newTestNode(resources any) Node {
+	return testNode(resources.(int))
+}
+
+// newTestRule fromの状態でのみ発火し、toの状態へ遷移するルールを作成
+func newTestRule(t *testing.T, name string, from, to int) *EdgeRule {
+	t.Helper()
+	rule, err := NewEdgeRule(
+		name,
+		func(n *Node) *Node {
+			next := newTestNode(to)
+			return &next
+		},
+		func(n *Node) bool { return (*n).GetResources().(int) == from },
+		func(n *Node) bool { return false },
+	)
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	return rule
+}
+
+func TestFrontierNodes(t *testing.T) {
+	// 0 -> 1 -> 3(終端), 0 -> 2(終端) を、3を展開する前に打ち切る
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 0, 2),
+		newTestRule(t, "c", 1, 3),
+	}
+	limit := int64(3)
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, &limit)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	if !generator.IsPartial() {
+		t.Fatal("expected the graph to be partial")
+	}
+	frontier := generator.GetFrontierNodes()
+	if len(frontier) != 1 || (*frontier[0]).GetID() != "n3" {
+		t.Errorf("expected only n3 to be a frontier node, got %v", frontier)
+	}
+	deadlocks := generator.GetDeadlockNodes()
+	if len(deadlocks) != 1 || (*deadlocks[0]).GetID() != "n2" {
+		t.Errorf("expected only n2 to be a deadlock (n3 was never expanded), got %v", deadlocks)
+	}
+
+	complete := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	if err := complete.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if complete.IsPartial() || len(complete.GetFrontierNodes()) != 0 || len(complete.GetDeadlockNodes()) != 2 {
+		t.Errorf("expected a complete graph with deadlocks n2 and n3")
+	}
+}
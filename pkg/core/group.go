@@ -0,0 +1,84 @@
+package core
+
+import "sort"
+
+// GroupedNode 自身が属するグループを返せるノード
+// パーサーがグループ分けの指定（cudのgroup_byなど）を持つ場合に実装する
+type GroupedNode interface {
+	Node
+	// GetGroup 外側から順に、ノードが属するグループの名前を返す（どのグループにも属さない場合は空）
+	GetGroup() []string
+}
+
+// NodeGroup 出力時にノードを入れ子にまとめるグループ
+type NodeGroup struct {
+	Name     string       // グループの名前（最上位は空文字）
+	Nodes    []*Node      // このグループに直接属するノード（開始ノードが先頭、以降はノードID順）
+	Children []*NodeGroup // 入れ子のグループ（名前順）
+}
+
+// SetNodeGrouper ノードのグループ分けを設定する
+// 設定した場合、ノードがGroupedNodeを実装していてもこちらを優先する
+func (g *Generator) SetNodeGrouper(grouper func(node *Node) []string) {
+	g.grouper = grouper
+}
+
+// GetNodeGroup ノードが属するグループを、外側から順に取得
+func (g *Generator) GetNodeGroup(node *Node) []string {
+	if g.grouper != nil {
+		return g.grouper(node)
+	}
+	if grouped, ok := (*node).(GroupedNode); ok {
+		return grouped.GetGroup()
+	}
+	return nil
+}
+
+// GetNodeGroupTree ノードをグループの木にまとめて取得
+// グループ分けがない場合は、すべてのノードが最上位のNodesに入る
+func (g *Generator) GetNodeGroupTree() *NodeGroup {
+	root := &NodeGroup{}
+	startNode := g.GetStartNode()
+	nodes := g.GetNodes()
+	if startNode != nil {
+		ordered := []*Node{startNode}
+		for _, node := range nodes {
+			if (*node).GetID() != (*startNode).GetID() {
+				ordered = append(ordered, node)
+			}
+		}
+		nodes = ordered
+	}
+
+	for _, node := range nodes {
+		group := root
+		for _, name := range g.GetNodeGroup(node) {
+			group = group.child(name)
+		}
+		group.Nodes = append(group.Nodes, node)
+	}
+	root.sortChildren()
+	return root
+}
+
+// child 名前に一致する子グループを取得し、なければ作成
+func (n *NodeGroup) child(name string) *NodeGroup {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &NodeGroup{Name: name}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// sortChildren 子グループを名前順に並べ替える
+func (n *NodeGroup) sortChildren() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, child := range n.Children {
+		child.sortChildren()
+	}
+}
@@ -0,0 +1,41 @@
+package core
+
+import "testing"
+
+func TestNodeGroupTree(t *testing.T) {
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 2),
+		newTestRule(t, "c", 2, 3),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	// 0はどのグループにも属さず、1は odd、2は even、3は odd の中の large にまとめる
+	generator.SetNodeGrouper(func(n *Node) []string {
+		value := (*n).GetResources().(int)
+		switch {
+		case value == 0:
+			return nil
+		case value == 3:
+			return []string{"odd", "large"}
+		case value%2 == 1:
+			return []string{"odd"}
+		default:
+			return []string{"even"}
+		}
+	})
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	tree := generator.GetNodeGroupTree()
+	if len(tree.Nodes) != 1 || (*tree.Nodes[0]).GetID() != "n0" {
+		t.Errorf("expected the start node at the top level, got %v", tree.Nodes)
+	}
+	if len(tree.Children) != 2 || tree.Children[0].Name != "even" || tree.Children[1].Name != "odd" {
+		t.Fatalf("expected groups even and odd, got %+v", tree.Children)
+	}
+	odd := tree.Children[1]
+	if len(odd.Nodes) != 1 || len(odd.Children) != 1 || odd.Children[0].Name != "large" || len(odd.Children[0].Nodes) != 1 {
+		t.Errorf("unexpected nesting in odd: %+v", odd)
+	}
+}
@@ -10,3 +10,24 @@ type Parser interface {
 		err error,
 	)
 }
+
+// SourcePathSetter 入力ファイルのパスを受け取れるParser
+// include など入力ファイルからの相対パスを解決する形式で実装する
+type SourcePathSetter interface {
+	// SetSourcePath はParseの前に入力ファイルのパスを設定する（標準入力の場合は空文字）
+	SetSourcePath(path string)
+}
+
+// IncludeDisabler 他のファイルの取り込みを無効にできるParser
+// HTTPでアップロードされたルールなど、信頼できない入力からローカルのファイルを読ませないために使う
+type IncludeDisabler interface {
+	// DisableInclude はParseの前に呼び、取り込みを指定した入力をエラーにする
+	DisableInclude()
+}
+
+// SourceFilesReporter 入力ファイルのほかに読み込んだファイルを報告できるParser
+// 監視モードでの変更の検出や、チェックポイントと同じルールかの確認に使う
+type SourceFilesReporter interface {
+	// SourceFiles はParseの後に呼び、取り込んだファイルのパスを返す（入力ファイル自体は含まない）
+	SourceFiles() []string
+}
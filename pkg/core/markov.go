@@ -0,0 +1,450 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// markovTolerance 反復計算を収束とみなす変化量
+const markovTolerance = 1e-12
+
+var (
+	// markovDirectLimit 連立一次方程式を直接（ガウスの消去法で）解く未知数の上限。これを超える場合は反復計算で解く
+	markovDirectLimit = 500
+	// markovMaxIterations 反復計算の最大回数。収束しなかった場合は、その時点の値を収束していない印とともに返す
+	markovMaxIterations = 10000
+)
+
+// MarkovChain 生成済みのグラフを離散時間マルコフ連鎖として扱う
+// 各状態からの遷移確率は、その状態で実行可能なルールの重み（複数の結果を持つルールは結果の重みで分配）の比で定める
+type MarkovChain struct {
+	states      []*Node
+	index       map[string]int
+	start       int
+	transitions [][]markovTransition
+	frontier    []bool // 展開されなかった（遷移先を調べていない）状態
+}
+
+// markovTransition 遷移先の状態と遷移確率
+type markovTransition struct {
+	to          int
+	probability float64
+}
+
+// RecurrentClass 吸収状態以外の再帰類（抜け出せない強連結成分）とその定常分布
+type RecurrentClass struct {
+	States     []*Node
+	Stationary []float64 // Statesと同じ順序の定常確率
+	Converged  bool      // 定常分布の計算が収束したかどうか（falseの場合、Stationaryは近似値）
+}
+
+// NewMarkovChain 生成済みのグラフからマルコフ連鎖を作成
+// 出力エッジの重みの合計が0の状態や、負の重みを持つルールがある場合はエラーを返す
+func NewMarkovChain(generator *Generator) (*MarkovChain, error) {
+	states := generator.GetNodes()
+	chain := &MarkovChain{
+		states:      states,
+		index:       make(map[string]int, len(states)),
+		start:       -1,
+		transitions: make([][]markovTransition, len(states)),
+		frontier:    make([]bool, len(states)),
+	}
+	for i, node := range states {
+		chain.index[(*node).GetID()] = i
+		chain.frontier[i] = generator.IsFrontier(node)
+	}
+	if start := generator.GetStartNode(); start != nil {
+		chain.start = chain.index[(*start).GetID()]
+	}
+
+	for id, edges := range generator.getOutgoingEdges() {
+		from := chain.index[id]
+		total := 0.0
+		for _, edge := range edges {
+			weight := edge.GetWeight()
+			if weight < 0 {
+				return nil, fmt.Errorf("transition %s has negative weight: %v", edge.GetLabel(), weight)
+			}
+			total += weight
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("all outgoing weights are zero at state %v", (*chain.states[from]).GetResourcesString())
+		}
+
+		// 同じ遷移先へのエッジは確率を合算する
+		merged := make(map[int]int)
+		for _, edge := range edges {
+			to := chain.index[(*edge.GetTo()).GetID()]
+			probability := edge.GetWeight() / total
+			if i, ok := merged[to]; ok {
+				chain.transitions[from][i].probability += probability
+				continue
+			}
+			merged[to] = len(chain.transitions[from])
+			chain.transitions[from] = append(chain.transitions[from], markovTransition{to: to, probability: probability})
+		}
+	}
+	return chain, nil
+}
+
+// GetStates 連鎖の状態を取得（GetNodesと同じ順序）
+func (c *MarkovChain) GetStates() []*Node {
+	return c.states
+}
+
+// GetStartIndex 開始状態のインデックスを取得
+func (c *MarkovChain) GetStartIndex() int {
+	return c.start
+}
+
+// IsAbsorbing 出力エッジを持たない（デッドロック）状態かどうか
+// 展開されなかった状態も出力エッジを持たないため吸収状態として扱われる。区別にはIsFrontierを使う。
+func (c *MarkovChain) IsAbsorbing(i int) bool {
+	return len(c.transitions[i]) == 0
+}
+
+// IsFrontier 探索を打ち切ったため展開されず、吸収状態として扱われている状態かどうか
+func (c *MarkovChain) IsFrontier(i int) bool {
+	return c.frontier[i]
+}
+
+// Probability 状態fromから状態toへ1ステップで遷移する確率
+func (c *MarkovChain) Probability(from, to int) float64 {
+	for _, t := range c.transitions[from] {
+		if t.to == to {
+			return t.probability
+		}
+	}
+	return 0
+}
+
+// ReachProbability 各状態から、targetを満たす状態にいずれ到達する確率を求める
+// 2つ目の戻り値は計算が収束したかどうか（falseの場合、確率は反復計算を打ち切った時点の近似値）
+func (c *MarkovChain) ReachProbability(target func(*Node) bool) ([]float64, bool) {
+	isTarget := make([]bool, len(c.states))
+	for i, node := range c.states {
+		isTarget[i] = target(node)
+	}
+	return c.reachProbability(isTarget)
+}
+
+// AbsorptionProbability 各状態から、吸収状態（デッドロック）にいずれ到達する確率を求める
+// 2つ目の戻り値は計算が収束したかどうか
+func (c *MarkovChain) AbsorptionProbability() ([]float64, bool) {
+	isAbsorbing := make([]bool, len(c.states))
+	for i := range c.states {
+		isAbsorbing[i] = c.IsAbsorbing(i)
+	}
+	return c.reachProbability(isAbsorbing)
+}
+
+// ExpectedSteps 各状態から吸収状態に至るまでの期待ステップ数を求める
+// 確率1で吸収されない状態は+Infとなる。2つ目の戻り値は計算が収束したかどうか
+func (c *MarkovChain) ExpectedSteps() ([]float64, bool) {
+	absorption, converged := c.AbsorptionProbability()
+	steps := make([]float64, len(c.states))
+	var transient []int
+	for i := range c.states {
+		switch {
+		case c.IsAbsorbing(i):
+		case absorption[i] < 1-1e-9:
+			steps[i] = math.Inf(1)
+		default:
+			transient = append(transient, i)
+		}
+	}
+
+	// 確率1で吸収される状態の遷移先もまた確率1で吸収されるため、+Infは混入しない
+	return steps, c.solve(transient, steps, 1) && converged
+}
+
+// RecurrentClasses 吸収状態以外の再帰類と、それぞれの定常分布を求める
+func (c *MarkovChain) RecurrentClasses() []RecurrentClass {
+	var classes []RecurrentClass
+	for _, component := range c.bottomComponents() {
+		if len(component) == 1 && c.IsAbsorbing(component[0]) {
+			continue
+		}
+		class := RecurrentClass{}
+		class.Stationary, class.Converged = c.stationary(component)
+		for _, i := range component {
+			class.States = append(class.States, c.states[i])
+		}
+		classes = append(classes, class)
+	}
+	return classes
+}
+
+// reachProbability 各状態から、isTargetの状態にいずれ到達する確率を求める
+func (c *MarkovChain) reachProbability(isTarget []bool) ([]float64, bool) {
+	probabilities := make([]float64, len(c.states))
+
+	// 目標状態に到達し得ない状態は確率0に固定し、残りの状態だけを計算する
+	reachable := c.canReach(isTarget)
+	var unknown []int
+	for i := range c.states {
+		switch {
+		case isTarget[i]:
+			probabilities[i] = 1
+		case reachable[i]:
+			unknown = append(unknown, i)
+		}
+	}
+
+	return probabilities, c.solve(unknown, probabilities, 0)
+}
+
+// solve valuesのうちindicesの値を、連立一次方程式 x_i = constant + Σ_j P(i, j) x_j の解で更新する
+// indices以外の状態の値は既知としてvaluesの値を使う。indicesのどの状態からもindices以外へ抜け出せることを前提とする。
+// 未知数がmarkovDirectLimit以下の場合はガウスの消去法で直接解き、それを超える場合はGauss-Seidel法で反復計算する。
+// 反復計算がmarkovMaxIterations回で収束しなかった場合はfalseを返す
+func (c *MarkovChain) solve(indices []int, values []float64, constant float64) bool {
+	if len(indices) == 0 {
+		return true
+	}
+	position := make(map[int]int, len(indices))
+	for k, i := range indices {
+		position[i] = k
+	}
+
+	if len(indices) <= markovDirectLimit {
+		// (I - Q) x = constant + R v（Qは未知の状態間、Rは既知の状態への遷移確率）
+		matrix := make([][]float64, len(indices))
+		vector := make([]float64, len(indices))
+		for k, i := range indices {
+			matrix[k] = make([]float64, len(indices))
+			matrix[k][k] = 1
+			vector[k] = constant
+			for _, t := range c.transitions[i] {
+				if j, ok := position[t.to]; ok {
+					matrix[k][j] -= t.probability
+				} else {
+					vector[k] += t.probability * values[t.to]
+				}
+			}
+		}
+		if solution, ok := solveDense(matrix, vector); ok {
+			for k, i := range indices {
+				values[i] = solution[k]
+			}
+			return true
+		}
+	}
+
+	for iteration := 0; iteration < markovMaxIterations; iteration++ {
+		delta := 0.0
+		for _, i := range indices {
+			value := constant
+			for _, t := range c.transitions[i] {
+				value += t.probability * values[t.to]
+			}
+			delta = math.Max(delta, math.Abs(value-values[i]))
+			values[i] = value
+		}
+		if delta < markovTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// solveDense 連立一次方程式 matrix x = vector を部分ピボット選択付きのガウスの消去法で解く
+// matrixとvectorは書き換える。係数行列が（数値的に）特異な場合はfalseを返す
+func solveDense(matrix [][]float64, vector []float64) ([]float64, bool) {
+	n := len(vector)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(matrix[row][col]) > math.Abs(matrix[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(matrix[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		matrix[col], matrix[pivot] = matrix[pivot], matrix[col]
+		vector[col], vector[pivot] = vector[pivot], vector[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := matrix[row][col] / matrix[col][col]
+			if factor == 0 {
+				continue
+			}
+			for k := col; k < n; k++ {
+				matrix[row][k] -= factor * matrix[col][k]
+			}
+			vector[row] -= factor * vector[col]
+		}
+	}
+
+	solution := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := vector[row]
+		for k := row + 1; k < n; k++ {
+			sum -= matrix[row][k] * solution[k]
+		}
+		solution[row] = sum / matrix[row][row]
+	}
+	return solution, true
+}
+
+// canReach 各状態から、isTargetの状態へ到達する経路があるかどうか
+func (c *MarkovChain) canReach(isTarget []bool) []bool {
+	incoming := make([][]int, len(c.states))
+	for from, transitions := range c.transitions {
+		for _, t := range transitions {
+			incoming[t.to] = append(incoming[t.to], from)
+		}
+	}
+
+	reachable := make([]bool, len(c.states))
+	var queue []int
+	for i, target := range isTarget {
+		if target {
+			reachable[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, from := range incoming[current] {
+			if !reachable[from] {
+				reachable[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+	return reachable
+}
+
+// bottomComponents 外へ出るエッジを持たない強連結成分を求める（Tarjanのアルゴリズム）
+func (c *MarkovChain) bottomComponents() [][]int {
+	n := len(c.states)
+	indices := make([]int, n)
+	lowlinks := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range indices {
+		indices[i] = -1
+	}
+	var stack []int
+	var components [][]int
+	next := 0
+
+	var connect func(v int)
+	connect = func(v int) {
+		indices[v] = next
+		lowlinks[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, t := range c.transitions[v] {
+			if indices[t.to] == -1 {
+				connect(t.to)
+				lowlinks[v] = min(lowlinks[v], lowlinks[t.to])
+			} else if onStack[t.to] {
+				lowlinks[v] = min(lowlinks[v], indices[t.to])
+			}
+		}
+
+		if lowlinks[v] == indices[v] {
+			var component []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	for v := 0; v < n; v++ {
+		if indices[v] == -1 {
+			connect(v)
+		}
+	}
+
+	var bottoms [][]int
+	for _, component := range components {
+		members := make(map[int]bool, len(component))
+		for _, i := range component {
+			members[i] = true
+		}
+		closed := true
+		for _, i := range component {
+			for _, t := range c.transitions[i] {
+				if !members[t.to] {
+					closed = false
+				}
+			}
+		}
+		if closed {
+			// GetNodesと同じ順序に揃える
+			sort.Ints(component)
+			bottoms = append(bottoms, component)
+		}
+	}
+	return bottoms
+}
+
+// stationary 閉じた強連結成分の定常分布を求める
+// 状態数がmarkovDirectLimit以下の場合は π(P - I) = 0, Σπ = 1 を直接解き、それを超える場合はべき乗法で求める。
+// 周期的な成分でも収束するよう、べき乗法では自己遷移を加えた連鎖（(P+I)/2）で計算する。
+// 2つ目の戻り値は計算が収束したかどうか
+func (c *MarkovChain) stationary(component []int) ([]float64, bool) {
+	position := make(map[int]int, len(component))
+	for k, i := range component {
+		position[i] = k
+	}
+
+	if len(component) <= markovDirectLimit {
+		// 転置した方程式の最後の行を正規化条件に置き換える（既約な連鎖では解は一意）
+		n := len(component)
+		matrix := make([][]float64, n)
+		for k := range matrix {
+			matrix[k] = make([]float64, n)
+			matrix[k][k] = -1
+		}
+		for k, i := range component {
+			for _, t := range c.transitions[i] {
+				matrix[position[t.to]][k] += t.probability
+			}
+		}
+		vector := make([]float64, n)
+		for k := range matrix[n-1] {
+			matrix[n-1][k] = 1
+		}
+		vector[n-1] = 1
+		if solution, ok := solveDense(matrix, vector); ok {
+			return solution, true
+		}
+	}
+
+	distribution := make([]float64, len(component))
+	for k := range distribution {
+		distribution[k] = 1 / float64(len(component))
+	}
+	for iteration := 0; iteration < markovMaxIterations; iteration++ {
+		next := make([]float64, len(component))
+		for k, i := range component {
+			next[k] += distribution[k] / 2
+			for _, t := range c.transitions[i] {
+				next[position[t.to]] += distribution[k] * t.probability / 2
+			}
+		}
+		delta := 0.0
+		for k := range next {
+			delta += math.Abs(next[k] - distribution[k])
+		}
+		distribution = next
+		if delta < markovTolerance {
+			return distribution, true
+		}
+	}
+	return distribution, false
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMarkovChain(t *testing.T) {
+	// 0 -> 1(成功, 重み3) / 0 -> 2(失敗, 重み1) / 0 -> 0(再試行, 重み4)
+	success := newTestRule(t, "success", 0, 1)
+	success.Weight = 3
+	failure := newTestRule(t, "failure", 0, 2)
+	retry := newTestRule(t, "retry", 0, 0)
+	retry.Weight = 4
+	generator := NewGenerator(newTestNode, newTestNode(0), []*EdgeRule{success, failure, retry}, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	chain, err := NewMarkovChain(generator)
+	if err != nil {
+		t.Fatalf("failed to create markov chain: %v", err)
+	}
+
+	start := chain.GetStartIndex()
+	isFailed := func(n *Node) bool { return (*n).GetResources().(int) == 2 }
+	failed, converged := chain.ReachProbability(isFailed)
+	if math.Abs(failed[start]-0.25) > 1e-9 || !converged {
+		t.Errorf("expected failure probability 0.25, got %v (converged: %v)", failed[start], converged)
+	}
+	// 1ステップで吸収される確率は1/2のため、期待ステップ数は2
+	if steps, converged := chain.ExpectedSteps(); math.Abs(steps[start]-2) > 1e-9 || !converged {
+		t.Errorf("expected 2 steps to absorption, got %v (converged: %v)", steps[start], converged)
+	}
+
+	// 直接解かずに反復計算しても同じ値になり、反復回数が足りない場合は収束していないと報告する
+	directLimit, maxIterations := markovDirectLimit, markovMaxIterations
+	defer func() { markovDirectLimit, markovMaxIterations = directLimit, maxIterations }()
+	markovDirectLimit = 0
+	if failed, converged := chain.ReachProbability(isFailed); math.Abs(failed[start]-0.25) > 1e-9 || !converged {
+		t.Errorf("expected failure probability 0.25 by iteration, got %v (converged: %v)", failed[start], converged)
+	}
+	markovMaxIterations = 1
+	if _, converged := chain.ReachProbability(isFailed); converged {
+		t.Error("expected the iteration to be reported as not converged")
+	}
+	markovDirectLimit, markovMaxIterations = directLimit, maxIterations
+
+	// 3 <-> 4 の閉じたループは吸収されない再帰類
+	loop := []*EdgeRule{newTestRule(t, "go", 3, 4), newTestRule(t, "back", 4, 3)}
+	generator = NewGenerator(newTestNode, newTestNode(3), loop, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	chain, err = NewMarkovChain(generator)
+	if err != nil {
+		t.Fatalf("failed to create markov chain: %v", err)
+	}
+	if steps, _ := chain.ExpectedSteps(); !math.IsInf(steps[chain.GetStartIndex()], 1) {
+		t.Errorf("expected infinite steps for a non-absorbing loop, got %v", steps[chain.GetStartIndex()])
+	}
+	classes := chain.RecurrentClasses()
+	if len(classes) != 1 || len(classes[0].States) != 2 || math.Abs(classes[0].Stationary[0]-0.5) > 1e-9 || !classes[0].Converged {
+		t.Errorf("expected one recurrent class with uniform distribution, got %+v", classes)
+	}
+
+	// 3だけを展開した場合、4は吸収状態として扱われるが、展開されなかった状態として区別できる
+	limit := int64(1)
+	generator = NewGenerator(newTestNode, newTestNode(3), loop, &limit)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	chain, err = NewMarkovChain(generator)
+	if err != nil {
+		t.Fatalf("failed to create markov chain: %v", err)
+	}
+	for i, state := range chain.GetStates() {
+		frontier := (*state).GetResources().(int) == 4
+		if chain.IsAbsorbing(i) != frontier || chain.IsFrontier(i) != frontier {
+			t.Errorf("state %v: expected absorbing and frontier to be %v", (*state).GetResources(), frontier)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+// GeneratorObserver ジェネレーターの探索の進み具合を受け取るインターフェース
+// AddObserverで登録すると、Generate（GenerateContext）の探索中に呼び出される。
+// 独自の検査や進捗の表示に使う。探索と同じゴルーチンで呼び出すため、重い処理は探索を遅くする。
+// 一部のメソッドだけを使う場合は、NopObserverを埋め込む。
+type GeneratorObserver interface {
+	// OnNodeDiscovered 新しいノードを発見した（開始ノードを含む）
+	OnNodeDiscovered(node *Node)
+	// OnNodeExpanded ノードを展開した。edgesはそのノードから生成したエッジ、queuedは展開を待っているノードの数
+	OnNodeExpanded(node *Node, edges []*Edge, queued int)
+	// OnRuleEvaluated ノードでルールの条件を評価した（blockはfireがtrueの場合のみ評価し、それ以外はfalse）
+	// SearchReachやWalkなど、グラフを生成しない探索での評価も通知する
+	OnRuleEvaluated(node *Node, rule *EdgeRule, fire bool, block bool)
+	// OnEdgeAdded エッジをグラフに追加した
+	OnEdgeAdded(edge *Edge)
+	// OnLimitReached 反復回数の上限に達し、探索を打ち切った
+	OnLimitReached(iterations int64)
+}
+
+// NopObserver 何もしないGeneratorObserver（埋め込んで必要なメソッドだけを実装する）
+type NopObserver struct{}
+
+func (NopObserver) OnNodeDiscovered(*Node)                       {}
+func (NopObserver) OnNodeExpanded(*Node, []*Edge, int)           {}
+func (NopObserver) OnRuleEvaluated(*Node, *EdgeRule, bool, bool) {}
+func (NopObserver) OnEdgeAdded(*Edge)                            {}
+func (NopObserver) OnLimitReached(int64)                         {}
+
+// AddObserver 探索の進み具合を受け取るオブザーバーを登録する（登録した順に呼び出す）
+func (g *Generator) AddObserver(observer GeneratorObserver) {
+	g.observers = append(g.observers, observer)
+}
@@ -0,0 +1,61 @@
+package core
+
+import "testing"
+
+// recordingObserver 通知された回数を数えるGeneratorObserver
+type recordingObserver struct {
+	NopObserver
+	discovered, expanded, evaluated, fired, edges int
+	limit                                         int64
+}
+
+func (o *recordingObserver) OnNodeDiscovered(*Node)             { o.discovered++ }
+func (o *recordingObserver) OnNodeExpanded(*Node, []*Edge, int) { o.expanded++ }
+func (o *recordingObserver) OnEdgeAdded(*Edge)                  { o.edges++ }
+func (o *recordingObserver) OnLimitReached(iterations int64)    { o.limit = iterations }
+
+func (o *recordingObserver) OnRuleEvaluated(_ *Node, _ *EdgeRule, fire bool, _ bool) {
+	o.evaluated++
+	if fire {
+		o.fired++
+	}
+}
+
+func TestObserver(t *testing.T) {
+	// 0 -> 1 -> 2(終端), 0 -> 3 -> 0 のループ
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 2),
+		newTestRule(t, "c", 0, 3),
+		newTestRule(t, "d", 3, 0),
+	}
+	for _, strategy := range []SearchStrategy{StrategyBFS, StrategyIterativeDeepening} {
+		generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+		generator.SetStrategy(strategy)
+		observer := &recordingObserver{}
+		generator.AddObserver(observer)
+		if err := generator.Generate(); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		if observer.discovered != 4 || observer.expanded != 4 || observer.edges != 4 {
+			t.Errorf("%s: expected 4 discovered, 4 expanded and 4 edges, got %+v", strategy, observer)
+		}
+		if observer.evaluated != 16 || observer.fired != 4 {
+			t.Errorf("%s: expected 16 evaluations with 4 fired, got %+v", strategy, observer)
+		}
+		if observer.limit != 0 {
+			t.Errorf("%s: expected no limit, got %d", strategy, observer.limit)
+		}
+	}
+
+	limit := int64(2)
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, &limit)
+	observer := &recordingObserver{}
+	generator.AddObserver(observer)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if observer.limit != 2 || observer.expanded != 2 {
+		t.Errorf("expected the limit to be reported after 2 expansions, got %+v", observer)
+	}
+}
@@ -0,0 +1,58 @@
+package core
+
+// FindPath 開始ノードから、条件を満たすノードまでの最短のパスを生成済みのグラフ上で求める
+// 開始ノード自体が条件を満たす場合は空のパスを返す。見つからない場合はfalseを返す
+func (g *Generator) FindPath(match func(*Node) bool) (TransitionPath, bool) {
+	paths := g.findPaths(match, 1)
+	if len(paths) == 0 {
+		return nil, false
+	}
+	return paths[0], true
+}
+
+// FindAllPaths 条件を満たすすべてのノードについて、開始ノードからの最短のパスを求める
+// 結果は開始ノードから近い順に並ぶ
+func (g *Generator) FindAllPaths(match func(*Node) bool) []TransitionPath {
+	return g.findPaths(match, -1)
+}
+
+// findPaths 幅優先探索で条件を満たすノードへのパスを最大max件求める（maxが負の場合は無制限）
+func (g *Generator) findPaths(match func(*Node) bool, max int) []TransitionPath {
+	startNode := g.GetStartNode()
+	if startNode == nil {
+		return nil
+	}
+
+	type visit struct {
+		node *Node
+		path TransitionPath
+	}
+	outgoing := g.getOutgoingEdges()
+	visited := map[string]bool{(*startNode).GetID(): true}
+	queue := []visit{{node: startNode}}
+
+	var paths []TransitionPath
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if match(current.node) {
+			paths = append(paths, current.path)
+			if max >= 0 && len(paths) >= max {
+				return paths
+			}
+		}
+
+		for _, edge := range outgoing[(*current.node).GetID()] {
+			toID := (*edge.GetTo()).GetID()
+			if visited[toID] {
+				continue
+			}
+			visited[toID] = true
+			path := make(TransitionPath, len(current.path), len(current.path)+1)
+			copy(path, current.path)
+			queue = append(queue, visit{node: edge.GetTo(), path: append(path, edge)})
+		}
+	}
+	return paths
+}
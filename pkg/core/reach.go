@@ -0,0 +1,156 @@
+package core
+
+import (
+	"container/heap"
+	"fmt"
+	"log/slog"
+)
+
+// ReachOptions 目標の状態へ向かう探索（最良優先探索、A*）の設定
+type ReachOptions struct {
+	Target func(node *Node) bool // 目標の状態かどうか
+	// Heuristic 目標の状態までの遷移数の見積もり（小さいほど目標に近い）
+	// nilの場合は常に0とみなし、A*は幅優先探索と同じ順で展開する
+	Heuristic func(node *Node) float64
+	// Greedy trueの場合は見積もりだけで順位を付ける最良優先探索を行う
+	// falseの場合は開始ノードからの遷移数と見積もりの和で順位を付けるA*で、見積もりが実際の遷移数を超えなければ最短のパスを返す
+	Greedy bool
+}
+
+// ReachResult 目標の状態へ向かう探索の結果
+// 状態とエッジはジェネレーターに追加せず、見つけたパスのみを返す
+type ReachResult struct {
+	Found      bool           // 目標の状態が見つかったかどうか
+	Node       *Node          // 見つかった目標の状態
+	Path       TransitionPath // 開始ノードからNodeまでのエッジ（開始ノード自体が目標の場合は空）
+	Expanded   int64          // 展開した状態の数
+	Discovered int64          // 発見した状態の数
+	Truncated  bool           // 反復回数の上限により探索を打ち切ったかどうか
+}
+
+// reachEntry 優先度付きキューの要素
+type reachEntry struct {
+	node     *Node
+	steps    int     // 開始ノードからの遷移数
+	priority float64 // 小さいほど先に展開する
+	order    int64   // 同じ優先度の場合は先に追加したものから展開する
+}
+
+// reachQueue reachEntryの優先度付きキュー（container/heapの実装）
+type reachQueue []*reachEntry
+
+func (q reachQueue) Len() int { return len(q) }
+func (q reachQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].order < q[j].order
+}
+func (q reachQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *reachQueue) Push(x any)   { *q = append(*q, x.(*reachEntry)) }
+func (q *reachQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// SearchReach 目標の状態を、見積もりの小さい状態から優先して探す
+// 列挙しきれない大きさのモデルでも、目標に近づく遷移を優先して辿ることで「いつかXに到達できるか」を確かめられる。
+// 目標の状態を展開する順に取り出した時点で探索を終え、開始ノードからのパスを返す。
+// 見積もりが小さくなるほど良い経路が見つかった状態は再び展開する。
+// ジェネレーターの生成結果（GetNodes, GetEdges）には影響しない。
+func (g *Generator) SearchReach(options ReachOptions) (*ReachResult, error) {
+	if options.Target == nil {
+		return nil, fmt.Errorf("目標の状態の条件を指定してください")
+	}
+	heuristic := options.Heuristic
+	if heuristic == nil {
+		heuristic = func(*Node) float64 { return 0 }
+	}
+	priority := func(node *Node, steps int) float64 {
+		if options.Greedy {
+			return heuristic(node)
+		}
+		return float64(steps) + heuristic(node)
+	}
+
+	result := &ReachResult{}
+	startNode := g.newNode(g.startResources.GetResources())
+	parents := make(map[string]*Edge) // ノードIDごとの、最も短い経路での直前のエッジ
+	steps := map[string]int{startNode.GetID(): 0}
+	closed := make(map[string]bool)
+	var order int64
+	queue := &reachQueue{{node: &startNode, priority: priority(&startNode, 0)}}
+	result.Discovered = 1
+
+	for queue.Len() > 0 {
+		entry := heap.Pop(queue).(*reachEntry)
+		id := (*entry.node).GetID()
+		if closed[id] || entry.steps > steps[id] {
+			continue
+		}
+		if options.Target(entry.node) {
+			result.Found = true
+			result.Node = entry.node
+			result.Path = reachPath(parents, id)
+			slog.Debug("[REACH] 目標の状態に到達", "id", id, "steps", entry.steps, "expanded", result.Expanded)
+			return result, nil
+		}
+
+		result.Expanded++
+		if g.limit != nil && result.Expanded > *g.limit {
+			slog.Error("[ERROR] 反復回数が上限を超えました。強制終了します。")
+			result.Truncated = true
+			return result, nil
+		}
+		closed[id] = true
+		slog.Debug("[REACH] 展開", "id", id, "steps", entry.steps, "priority", entry.priority)
+
+		edges, _ := g.expand(entry.node, func(id string) bool {
+			_, exists := steps[id]
+			return exists
+		})
+		for _, edge := range edges {
+			toID := (*edge.GetTo()).GetID()
+			known, exists := steps[toID]
+			if exists && known <= entry.steps+1 {
+				continue
+			}
+			if !exists {
+				result.Discovered++
+			}
+			// より短い経路が見つかった状態は、展開済みでも再び展開する
+			delete(closed, toID)
+			steps[toID] = entry.steps + 1
+			parents[toID] = edge
+			order++
+			heap.Push(queue, &reachEntry{
+				node:     edge.GetTo(),
+				steps:    entry.steps + 1,
+				priority: priority(edge.GetTo(), entry.steps+1),
+				order:    order,
+			})
+		}
+	}
+	slog.Debug("[REACH] 目標の状態に到達できません", "expanded", result.Expanded)
+	return result, nil
+}
+
+// reachPath 直前のエッジを辿って、開始ノードからのパスを復元する
+func reachPath(parents map[string]*Edge, id string) TransitionPath {
+	var reversed TransitionPath
+	for {
+		edge, exists := parents[id]
+		if !exists {
+			break
+		}
+		reversed = append(reversed, edge)
+		id = (*edge.GetFrom()).GetID()
+	}
+	path := make(TransitionPath, len(reversed))
+	for i, edge := range reversed {
+		path[len(reversed)-1-i] = edge
+	}
+	return path
+}
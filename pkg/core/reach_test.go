@@ -0,0 +1,78 @@
+package core
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSearchReach(t *testing.T) {
+	// 0 -> 1 -> 2 -> 3 -> 4（目標）と、目標から遠ざかる 0 -> 10 -> 11 -> 12、および近道 1 -> 3
+	rules := []*EdgeRule{
+		newTestRule(t, "away", 0, 10),
+		newTestRule(t, "away2", 10, 11),
+		newTestRule(t, "away3", 11, 12),
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 2),
+		newTestRule(t, "c", 2, 3),
+		newTestRule(t, "d", 3, 4),
+		newTestRule(t, "shortcut", 1, 3),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	target := func(n *Node) bool { return (*n).GetResources().(int) == 4 }
+	heuristic := func(n *Node) float64 { return math.Abs(float64(4 - (*n).GetResources().(int))) }
+	labels := func(path TransitionPath) string {
+		var names []string
+		for _, edge := range path {
+			names = append(names, edge.GetLabel())
+		}
+		return strings.Join(names, ",")
+	}
+
+	blind, err := generator.SearchReach(ReachOptions{Target: target})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	astar, err := generator.SearchReach(ReachOptions{Target: target, Heuristic: heuristic})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	for _, result := range []*ReachResult{blind, astar} {
+		if !result.Found || (*result.Node).GetID() != "n4" {
+			t.Fatalf("expected to reach n4")
+		}
+		if path := labels(result.Path); path != "a,shortcut,d" {
+			t.Errorf("expected the shortest path a,shortcut,d, got %s", path)
+		}
+	}
+	if astar.Expanded >= blind.Expanded {
+		t.Errorf("expected the heuristic to expand fewer states: %d >= %d", astar.Expanded, blind.Expanded)
+	}
+
+	greedy, err := generator.SearchReach(ReachOptions{Target: target, Heuristic: heuristic, Greedy: true})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if !greedy.Found || (*greedy.Node).GetID() != "n4" {
+		t.Errorf("expected the greedy search to reach n4")
+	}
+
+	// 到達できない目標と、開始ノード自体が目標の場合
+	unreachable, err := generator.SearchReach(ReachOptions{Target: func(n *Node) bool { return (*n).GetResources().(int) == 5 }})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if unreachable.Found || unreachable.Expanded != 8 {
+		t.Errorf("expected all 8 states to be expanded without reaching the target, got %d", unreachable.Expanded)
+	}
+	start, err := generator.SearchReach(ReachOptions{Target: func(n *Node) bool { return (*n).GetResources().(int) == 0 }})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if !start.Found || len(start.Path) != 0 {
+		t.Errorf("expected an empty path to the start node")
+	}
+	if len(generator.GetNodes()) != 0 {
+		t.Error("SearchReach should not add nodes to the generator")
+	}
+}
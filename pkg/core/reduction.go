@@ -0,0 +1,247 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ReductionOptions 半順序削減の設定
+type ReductionOptions struct {
+	// Visible 結果を保存したい条件（不変条件や目標の状態の式など）が参照するキー
+	// これらのキーを変更するルールを削減の対象から外し、削減によって条件を満たさない状態を見落とさないようにする。
+	// 空の場合はデッドロックのみを保存する。
+	Visible []string
+	// VisibleAll 条件がリソース全体を参照する（キーを特定できない）場合はtrue
+	// 何らかのキーを変更するルールはすべて削減の対象から外す。
+	VisibleAll bool
+}
+
+// ReductionStats 半順序削減の効果
+type ReductionStats struct {
+	ReducedStates int64 // 実行可能なルールの一部だけを展開した状態の数
+	PrunedRules   int64 // 削減により展開しなかった実行可能なルールの数（状態ごとの合計）
+}
+
+// SetPartialOrderReduction 半順序削減（stubborn set）を設定する（nilの場合は削減しない）
+// 独立なルール（参照・変更するキーが重ならないルール）の実行順の入れ替えをすべて探索する代わりに、
+// 各状態で実行可能なルールのうち、stubborn setに含まれるものだけを展開する。
+// デッドロックはすべて保存され、options.Visibleのキーに関する不変条件の違反も見落とさない。
+// ルールのAccessがnilの場合は、そのルールが他のすべてのルールに依存するとみなすため、削減が効かなくなる。
+// 生成されるグラフは元のグラフの一部になるため、すべての遷移を列挙したい用途（テストケースの生成やマルコフ連鎖など）には使わないこと。
+func (g *Generator) SetPartialOrderReduction(options *ReductionOptions) {
+	if options == nil {
+		g.reduction = nil
+		return
+	}
+	var unknown []string
+	for _, rule := range g.edgeRules {
+		if rule.Access == nil {
+			unknown = append(unknown, rule.GetName())
+		}
+	}
+	if len(unknown) > 0 {
+		slog.Warn("[REDUCTION] 参照・変更するキーが不明なルールは他のすべてのルールに依存するとみなすため、削減が効かない場合があります", "rules", unknown)
+	}
+	g.reduction = newReduction(g.edgeRules, options)
+}
+
+// GetReductionStats 半順序削減の効果を取得
+func (g *Generator) GetReductionStats() ReductionStats {
+	if g.reduction == nil {
+		return ReductionStats{}
+	}
+	return g.reduction.stats
+}
+
+// reduction ルール間の依存関係を事前に求めた、半順序削減の状態
+type reduction struct {
+	index      map[*EdgeRule]int
+	dependents [][]int // ルールごとの、独立でない（実行順を入れ替えられない）ルール
+	enablers   [][]int // ルールごとの、実行可否を変える可能性のあるルール
+	visible    []bool  // 保存したい条件が参照するキーを変更する可能性があるか
+	proviso    bool    // 循環の条件（削減した遷移の先が発見済みの場合はすべて展開する）を適用するか
+	stats      ReductionStats
+}
+
+// keySet ルールが参照・変更するキーの集合（allの場合はすべてのキー）
+type keySet struct {
+	keys map[string]bool
+	all  bool
+}
+
+// intersects 2つの集合が重なるかどうか
+func (s keySet) intersects(other keySet) bool {
+	if (s.all && (other.all || len(other.keys) > 0)) || (other.all && len(s.keys) > 0) {
+		return true
+	}
+	for key := range s.keys {
+		if other.keys[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// union 2つの集合の和
+func (s keySet) union(other keySet) keySet {
+	result := keySet{keys: make(map[string]bool, len(s.keys)+len(other.keys)), all: s.all || other.all}
+	for key := range s.keys {
+		result.keys[key] = true
+	}
+	for key := range other.keys {
+		result.keys[key] = true
+	}
+	return result
+}
+
+// newKeySet キーのリストから集合を作成
+func newKeySet(keys []string, all bool) keySet {
+	set := keySet{keys: make(map[string]bool, len(keys)), all: all}
+	for _, key := range keys {
+		set.keys[key] = true
+	}
+	return set
+}
+
+// newReduction ルールの参照・変更するキーから依存関係を求める
+// 優先度の高いルールが実行可能な場合は低いルールが抑制されるため、ルールの実効的な実行可否は、
+// 自身より優先度の高いルールが参照するキーにも依存するとみなす。
+func newReduction(rules []*EdgeRule, options *ReductionOptions) *reduction {
+	reads := make([]keySet, len(rules))
+	writes := make([]keySet, len(rules))
+	for i, rule := range rules {
+		if rule.Access == nil {
+			reads[i] = keySet{all: true}
+			writes[i] = keySet{all: true}
+			continue
+		}
+		reads[i] = newKeySet(rule.Access.Reads, rule.Access.ReadsAll)
+		writes[i] = newKeySet(rule.Access.Writes, false)
+	}
+	effectiveReads := make([]keySet, len(rules))
+	for i, rule := range rules {
+		effectiveReads[i] = reads[i]
+		for j, other := range rules {
+			if other.GetPriority() > rule.GetPriority() {
+				effectiveReads[i] = effectiveReads[i].union(reads[j])
+			}
+		}
+	}
+
+	r := &reduction{
+		index:      make(map[*EdgeRule]int, len(rules)),
+		dependents: make([][]int, len(rules)),
+		enablers:   make([][]int, len(rules)),
+		visible:    make([]bool, len(rules)),
+		proviso:    len(options.Visible) > 0 || options.VisibleAll,
+	}
+	visible := newKeySet(options.Visible, options.VisibleAll)
+	for i, rule := range rules {
+		r.index[rule] = i
+		r.visible[i] = writes[i].intersects(visible)
+		for j := range rules {
+			if i == j {
+				continue
+			}
+			// 一方が変更するキーを、他方が参照または変更する場合は独立でない
+			if writes[i].intersects(effectiveReads[j].union(writes[j])) || writes[j].intersects(effectiveReads[i].union(writes[i])) {
+				r.dependents[i] = append(r.dependents[i], j)
+			}
+			// jが変更するキーをiが参照する場合、jの実行でiが実行可能になりうる
+			if writes[j].intersects(effectiveReads[i]) {
+				r.enablers[i] = append(r.enablers[i], j)
+			}
+		}
+	}
+	return r
+}
+
+// candidates 実行可能なルールの部分集合のうち、展開してよいもの（stubborn setに含まれるもの）を小さい順に求める
+// 実行可能なルールそれぞれを起点にstubborn setを作り、含まれる実行可能なルールの数で並べる（すべてを含むものは除く）。
+// stubborn setは、実行可能なルールについては依存するルールを、実行できないルールについてはそれを実行可能にしうるルールを含むように閉包をとる。
+// 保存したい条件に関わる実行可能なルールを含むstubborn setは候補にしない。
+func (r *reduction) candidates(enabled []*EdgeRule) [][]*EdgeRule {
+	isEnabled := make(map[int]bool, len(enabled))
+	for _, rule := range enabled {
+		isEnabled[r.index[rule]] = true
+	}
+
+	var result [][]*EdgeRule
+	seen := make(map[string]bool)
+	for _, seed := range enabled {
+		inSet := map[int]bool{r.index[seed]: true}
+		stack := []int{r.index[seed]}
+		size := 1
+		rejected := false
+		for len(stack) > 0 && !rejected {
+			rule := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			next := r.enablers[rule]
+			if isEnabled[rule] {
+				if r.visible[rule] {
+					rejected = true
+					break
+				}
+				next = r.dependents[rule]
+			}
+			for _, other := range next {
+				if inSet[other] {
+					continue
+				}
+				inSet[other] = true
+				stack = append(stack, other)
+				if isEnabled[other] {
+					size++
+					if size >= len(enabled) {
+						rejected = true
+						break
+					}
+				}
+			}
+		}
+		if rejected {
+			continue
+		}
+
+		selected := make([]*EdgeRule, 0, size)
+		var key strings.Builder
+		for _, rule := range enabled {
+			if inSet[r.index[rule]] {
+				selected = append(selected, rule)
+				fmt.Fprintf(&key, "%d,", r.index[rule])
+			}
+		}
+		if !seen[key.String()] {
+			seen[key.String()] = true
+			result = append(result, selected)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return len(result[i]) < len(result[j]) })
+	return result
+}
+
+// expand ノードから展開するエッジを求める（半順序削減を設定している場合は削減する）
+// discoveredは、循環の条件の判定に使う、ノードが発見済みかどうかを返す関数
+// 循環の条件を適用する場合は、遷移の先がすべて未発見になる最も小さい候補を選び、なければすべて展開する。
+func (g *Generator) expand(node *Node, discovered func(id string) bool) (edges []*Edge, suppressed []*EdgeRule) {
+	enabled, suppressed := g.enabledRules(node)
+	if g.reduction == nil || len(enabled) <= 1 {
+		return g.applyRules(node, enabled), suppressed
+	}
+
+	for _, selected := range g.reduction.candidates(enabled) {
+		edges = g.applyRules(node, selected)
+		// 削減した遷移の先が発見済みの場合は、循環の中で展開されないルールが残らないよう、この候補は使わない
+		if g.reduction.proviso && slices.ContainsFunc(edges, func(edge *Edge) bool { return discovered((*edge.GetTo()).GetID()) }) {
+			continue
+		}
+		g.reduction.stats.ReducedStates++
+		g.reduction.stats.PrunedRules += int64(len(enabled) - len(selected))
+		slog.Debug("[REDUCE] 半順序削減", "id", (*node).GetID(), "enabled", len(enabled), "expanded", len(selected))
+		return edges, suppressed
+	}
+	return g.applyRules(node, enabled), suppressed
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPartialOrderReduction(t *testing.T) {
+	// ビットごとのフラグを状態とする。aとcは互いを無効にし、bとdは他と独立
+	// デッドロックは {a, b, d} と {b, c, d} の2つ
+	flag := func(name string, bit int, reads ...int) *EdgeRule {
+		rule, err := NewEdgeRule(
+			name,
+			func(n *Node) *Node {
+				next := newTestNode((*n).GetResources().(int) | 1<<bit)
+				return &next
+			},
+			func(n *Node) bool {
+				for _, read := range append(reads, bit) {
+					if (*n).GetResources().(int)&(1<<read) != 0 {
+						return false
+					}
+				}
+				return true
+			},
+			func(n *Node) bool { return false },
+		)
+		if err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+		access := &RuleAccess{Reads: []string{fmt.Sprintf("b%d", bit)}, Writes: []string{fmt.Sprintf("b%d", bit)}}
+		for _, read := range reads {
+			access.Reads = append(access.Reads, fmt.Sprintf("b%d", read))
+		}
+		rule.Access = access
+		return rule
+	}
+	newRules := func() []*EdgeRule {
+		return []*EdgeRule{flag("a", 0, 2), flag("b", 1), flag("c", 2, 0), flag("d", 3)}
+	}
+	deadlocks := func(generator *Generator) []string {
+		var ids []string
+		for _, node := range generator.GetDeadlockNodes() {
+			ids = append(ids, (*node).GetID())
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	full := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+	if err := full.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	reduced := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+	reduced.SetPartialOrderReduction(&ReductionOptions{})
+	if err := reduced.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if len(full.GetNodes()) != 12 {
+		t.Errorf("expected 12 states without reduction, got %d", len(full.GetNodes()))
+	}
+	if len(reduced.GetNodes()) >= len(full.GetNodes()) {
+		t.Errorf("expected fewer states with reduction, got %d (full %d)", len(reduced.GetNodes()), len(full.GetNodes()))
+	}
+	if got, want := deadlocks(reduced), deadlocks(full); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected the same deadlocks %v, got %v", want, got)
+	}
+	if stats := reduced.GetReductionStats(); stats.ReducedStates == 0 || stats.PrunedRules == 0 {
+		t.Errorf("expected reduction stats to be recorded, got %+v", stats)
+	}
+
+	// 保存したい条件がすべてのキーを参照する場合は削減しない
+	visible := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+	visible.SetPartialOrderReduction(&ReductionOptions{Visible: []string{"b0", "b1", "b2", "b3"}})
+	if err := visible.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if len(visible.GetNodes()) != len(full.GetNodes()) {
+		t.Errorf("expected no reduction when every key is visible, got %d states", len(visible.GetNodes()))
+	}
+
+	// 参照・変更するキーが不明なルールは他のすべてのルールに依存するとみなす
+	unknown := newRules()
+	for _, rule := range unknown {
+		rule.Access = nil
+	}
+	conservative := NewGenerator(newTestNode, newTestNode(0), unknown, nil)
+	conservative.SetPartialOrderReduction(&ReductionOptions{})
+	if err := conservative.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if len(conservative.GetNodes()) != len(full.GetNodes()) {
+		t.Errorf("expected no reduction without access sets, got %d states", len(conservative.GetNodes()))
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ParserRegistration パーサーの登録情報
+type ParserRegistration struct {
+	Name        string                    // -input で指定する名前
+	Description string                    // ヘルプなどに表示する説明
+	Extensions  []string                  // 対応するファイル拡張子（例: ".yaml"）
+	New         func() (Parser, error)    // パーサーを作成する関数
+	Detect      func(content string) bool // 内容がこの形式らしい場合にtrueを返す関数（任意、拡張子で判定できない場合に使用）
+}
+
+// FormatterRegistration フォーマッターの登録情報
+type FormatterRegistration struct {
+	Name        string                    // -output で指定する名前
+	Description string                    // ヘルプなどに表示する説明
+	Extensions  []string                  // 出力ファイルの拡張子（例: ".dot"）
+	New         func() (Formatter, error) // フォーマッターを作成する関数
+}
+
+var (
+	registryMu sync.RWMutex
+	parsers    = make(map[string]ParserRegistration)
+	formatters = make(map[string]FormatterRegistration)
+)
+
+// RegisterParser パーサーを名前で登録する
+// 同じ名前がすでに登録されている場合はエラーを返す
+func RegisterParser(registration ParserRegistration) error {
+	if registration.Name == "" {
+		return fmt.Errorf("parser name cannot be empty")
+	}
+	if registration.New == nil {
+		return fmt.Errorf("parser factory cannot be nil: %s", registration.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := parsers[registration.Name]; exists {
+		return fmt.Errorf("parser already registered: %s", registration.Name)
+	}
+	parsers[registration.Name] = registration
+	return nil
+}
+
+// LookupParser 名前で登録されたパーサーを取得
+func LookupParser(name string) (ParserRegistration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	registration, ok := parsers[name]
+	return registration, ok
+}
+
+// RegisteredParsers 登録されたパーサーを名前順に取得
+func RegisteredParsers() []ParserRegistration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	registrations := make([]ParserRegistration, 0, len(parsers))
+	for _, registration := range parsers {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
+
+// RegisterFormatter フォーマッターを名前で登録する
+// 同じ名前がすでに登録されている場合はエラーを返す
+func RegisterFormatter(registration FormatterRegistration) error {
+	if registration.Name == "" {
+		return fmt.Errorf("formatter name cannot be empty")
+	}
+	if registration.New == nil {
+		return fmt.Errorf("formatter factory cannot be nil: %s", registration.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := formatters[registration.Name]; exists {
+		return fmt.Errorf("formatter already registered: %s", registration.Name)
+	}
+	formatters[registration.Name] = registration
+	return nil
+}
+
+// LookupFormatter 名前で登録されたフォーマッターを取得
+func LookupFormatter(name string) (FormatterRegistration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	registration, ok := formatters[name]
+	return registration, ok
+}
+
+// RegisteredFormatters 登録されたフォーマッターを名前順に取得
+func RegisteredFormatters() []FormatterRegistration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	registrations := make([]FormatterRegistration, 0, len(formatters))
+	for _, registration := range formatters {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
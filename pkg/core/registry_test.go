@@ -0,0 +1,22 @@
+package core
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	registration := ParserRegistration{
+		Name: "test-registry-parser",
+		New:  func() (Parser, error) { return nil, nil },
+	}
+	if err := RegisterParser(registration); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	if err := RegisterParser(registration); err == nil {
+		t.Error("expected error for duplicate registration")
+	}
+	if _, ok := LookupParser("test-registry-parser"); !ok {
+		t.Error("registered parser not found")
+	}
+	if err := RegisterFormatter(FormatterRegistration{Name: "test-registry-formatter"}); err == nil {
+		t.Error("expected error for nil factory")
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+// TraceEvent 実システムで観測された1件のイベント
+type TraceEvent struct {
+	Rule      string // 実行されたルール名（複数の結果を持つルールは「ルール名/結果名」、結果名は省略可）
+	Resources Node   // 実行後に観測されたリソース（nilの場合は照合しない）
+}
+
+// MismatchKind モデルと実行ログの食い違いの種類
+type MismatchKind string
+
+const (
+	// MismatchForbiddenTransition モデル上で許可されていない遷移が実行された
+	MismatchForbiddenTransition MismatchKind = "forbidden_transition"
+	// MismatchUnexpectedResources 遷移は許可されているが、観測されたリソースをモデルが予測しない
+	MismatchUnexpectedResources MismatchKind = "unexpected_resources"
+	// MismatchUnexplored イベント直前に取りうる状態に、探索を打ち切ったため遷移先を調べていない状態が含まれ、モデルの一致・不一致を判断できない
+	MismatchUnexplored MismatchKind = "unexplored"
+)
+
+// ReplayMismatch モデルと実行ログが最初に食い違った箇所
+type ReplayMismatch struct {
+	Step     int          // 食い違ったイベントの番号（1始まり）
+	Event    TraceEvent   // 食い違ったイベント
+	Kind     MismatchKind // 食い違いの種類
+	Current  []*Node      // イベント直前にモデル上で取りうる状態
+	Expected []*Node      // ルールは許可されていた場合の、モデルが予測する遷移先
+	Allowed  []string     // イベント直前の状態から実行可能なルール名（エッジのラベル）
+}
+
+// Replay 生成済みのグラフ上で実行ログを辿り、最初に食い違ったイベントを返す
+// すべてのイベントがモデルと一致した場合はnilを返す
+//
+// 同じ状態から同名のルールで複数の遷移先がありうる場合は、取りうる状態の集合として追跡する。
+// 食い違ったイベントの直前に取りうる状態に展開されなかったノードが含まれる場合は、MismatchUnexploredを返す。
+func (g *Generator) Replay(events []TraceEvent) *ReplayMismatch {
+	startNode := g.GetStartNode()
+	if startNode == nil {
+		return nil
+	}
+
+	outgoing := g.getOutgoingEdges()
+	current := []*Node{startNode}
+
+	for i, event := range events {
+		var candidates []*Node
+		seen := make(map[string]bool)
+		allowed := make(map[string]bool)
+		var allowedNames []string
+
+		for _, node := range current {
+			for _, edge := range outgoing[(*node).GetID()] {
+				label := edge.GetLabel()
+				if !allowed[label] {
+					allowed[label] = true
+					allowedNames = append(allowedNames, label)
+				}
+				// 結果名を省略したイベントは、そのルールのどの結果とも一致する
+				if label != event.Rule && edge.GetRule().GetName() != event.Rule {
+					continue
+				}
+				toID := (*edge.GetTo()).GetID()
+				if !seen[toID] {
+					seen[toID] = true
+					candidates = append(candidates, edge.GetTo())
+				}
+			}
+		}
+
+		if len(candidates) == 0 {
+			return &ReplayMismatch{
+				Step:    i + 1,
+				Event:   event,
+				Kind:    g.mismatchKind(current, MismatchForbiddenTransition),
+				Current: current,
+				Allowed: allowedNames,
+			}
+		}
+
+		if event.Resources != nil {
+			observedID := event.Resources.GetID()
+			var matched []*Node
+			for _, candidate := range candidates {
+				if (*candidate).GetID() == observedID {
+					matched = append(matched, candidate)
+				}
+			}
+			if len(matched) == 0 {
+				return &ReplayMismatch{
+					Step:     i + 1,
+					Event:    event,
+					Kind:     g.mismatchKind(current, MismatchUnexpectedResources),
+					Current:  current,
+					Expected: candidates,
+					Allowed:  allowedNames,
+				}
+			}
+			candidates = matched
+		}
+
+		current = candidates
+	}
+
+	return nil
+}
+
+// mismatchKind 取りうる状態に展開されなかったノードが含まれる場合は、食い違いの種類をMismatchUnexploredに置き換える
+// 展開されなかったノードの遷移先はグラフにないため、実際には許可された遷移である可能性がある
+func (g *Generator) mismatchKind(current []*Node, kind MismatchKind) MismatchKind {
+	for _, node := range current {
+		if g.IsFrontier(node) {
+			return MismatchUnexplored
+		}
+	}
+	return kind
+}
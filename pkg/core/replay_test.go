@@ -0,0 +1,39 @@
+package core
+
+import "testing"
+
+func TestReplay(t *testing.T) {
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 1, 2),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	if mismatch := generator.Replay([]TraceEvent{{Rule: "a", Resources: newTestNode(1)}, {Rule: "b"}}); mismatch != nil {
+		t.Errorf("expected trace to conform, got mismatch at step %d (%s)", mismatch.Step, mismatch.Kind)
+	}
+
+	mismatch := generator.Replay([]TraceEvent{{Rule: "a"}, {Rule: "a"}})
+	if mismatch == nil || mismatch.Step != 2 || mismatch.Kind != MismatchForbiddenTransition {
+		t.Errorf("expected forbidden transition at step 2, got %+v", mismatch)
+	}
+
+	mismatch = generator.Replay([]TraceEvent{{Rule: "a", Resources: newTestNode(2)}})
+	if mismatch == nil || mismatch.Step != 1 || mismatch.Kind != MismatchUnexpectedResources {
+		t.Errorf("expected unexpected resources at step 1, got %+v", mismatch)
+	}
+
+	// 開始ノードだけを展開した場合、1の先はモデルにないため禁止された遷移とはみなさない
+	limit := int64(1)
+	partial := NewGenerator(newTestNode, newTestNode(0), rules, &limit)
+	if err := partial.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	mismatch = partial.Replay([]TraceEvent{{Rule: "a"}, {Rule: "b"}})
+	if mismatch == nil || mismatch.Step != 2 || mismatch.Kind != MismatchUnexplored {
+		t.Errorf("expected unexplored at step 2, got %+v", mismatch)
+	}
+}
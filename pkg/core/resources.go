@@ -0,0 +1,26 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// DecodeResources JSONで表現されたリソースを、likeと同じ型の値として復元する
+// likeには開始ノードのGetResources()などを渡し、newNodeに渡せる型で値を得るために使用する
+func DecodeResources(data []byte, like any) (any, error) {
+	resourceType := reflect.TypeOf(like)
+	if resourceType == nil {
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to decode resources: %w", err)
+		}
+		return value, nil
+	}
+
+	ptr := reflect.New(resourceType)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode resources as %s: %w", resourceType, err)
+	}
+	return ptr.Elem().Interface(), nil
+}
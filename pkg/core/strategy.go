@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// SearchStrategy 状態空間を探索する順序
+type SearchStrategy int
+
+const (
+	// StrategyBFS 幅優先探索（デフォルト）。開始ノードに近い状態から順に展開し、各ノードの深さは最短の遷移数になる
+	StrategyBFS SearchStrategy = iota
+	// StrategyDFS 深さ優先探索。展開を待つノードが現在の経路の分だけで済み、深いところにある反例を早く見つけやすい
+	StrategyDFS
+	// StrategyIterativeDeepening 反復深化。深さの上限を0から1ずつ増やしながら深さ優先探索を繰り返す
+	// 浅い部分は上限を増やすたびに辿り直すが、ルールの評価は各ノードで一度だけ行う
+	StrategyIterativeDeepening
+)
+
+// strategyNames 探索戦略の名前（CLIやチェックポイントで使用）
+var strategyNames = map[SearchStrategy]string{
+	StrategyBFS:                "bfs",
+	StrategyDFS:                "dfs",
+	StrategyIterativeDeepening: "iddfs",
+}
+
+// String 探索戦略の名前を取得
+func (s SearchStrategy) String() string {
+	if name, exists := strategyNames[s]; exists {
+		return name
+	}
+	return fmt.Sprintf("SearchStrategy(%d)", int(s))
+}
+
+// ParseSearchStrategy 名前（bfs, dfs, iddfs）から探索戦略を取得
+func ParseSearchStrategy(name string) (SearchStrategy, error) {
+	for strategy, strategyName := range strategyNames {
+		if strategyName == name {
+			return strategy, nil
+		}
+	}
+	return StrategyBFS, fmt.Errorf("未対応の探索戦略です: %s (bfs, dfs, iddfs)", name)
+}
+
+// SetStrategy 探索の順序を設定する（Generateの前に呼ぶ）
+func (g *Generator) SetStrategy(strategy SearchStrategy) {
+	g.strategy = strategy
+}
+
+// GetStrategy 探索の順序を取得
+func (g *Generator) GetStrategy() SearchStrategy {
+	return g.strategy
+}
+
+// SetMaxDepth 展開するノードの深さの上限を設定する（nilの場合は無制限）
+// 開始ノードからmaxDepth回の遷移で到達できる状態までを発見し、深さがmaxDepthのノードは展開せずに残す。
+// 残したノードは反復回数の上限で打ち切った場合と同様にGetFrontierNodesで取得でき、
+// 上限を大きくしてチェックポイントから再開すると、その続きを探索する。
+func (g *Generator) SetMaxDepth(maxDepth *int) {
+	g.maxDepth = maxDepth
+}
+
+// GetDepth 開始ノードからノードまでの深さ（遷移数）を取得
+// 探索中に見つけた経路のうち最も短いものの深さで、幅優先探索と反復深化では最短の遷移数になる。
+// 深さの上限がない深さ優先探索では、後から見つけた短い経路を展開済みのノードの先へは伝えないため、最短とは限らない。
+func (g *Generator) GetDepth(node *Node) (int, bool) {
+	depth, exists := g.depths[(*node).GetID()]
+	return depth, exists
+}
+
+// recordDepth ノードの深さを記録する。これまでより浅い場合（初めて見つけた場合を含む）はtrueを返す
+func (g *Generator) recordDepth(id string, depth int) bool {
+	if current, exists := g.depths[id]; exists && current <= depth {
+		return false
+	}
+	g.depths[id] = depth
+	return true
+}
+
+// withinMaxDepth 指定した深さのノードを展開できるかどうか
+func (g *Generator) withinMaxDepth(depth int) bool {
+	return g.maxDepth == nil || depth < *g.maxDepth
+}
+
+// popQueue 探索戦略に従って、展開を待っているノードを1つ取り出す
+func (g *Generator) popQueue() *Node {
+	if g.strategy == StrategyDFS {
+		node := g.queue[len(g.queue)-1]
+		g.queue = g.queue[:len(g.queue)-1]
+		return node
+	}
+	node := g.queue[0]
+	g.queue = g.queue[1:]
+	return node
+}
+
+// pushQueue 探索戦略に従って、展開を待つノードを追加する
+// nodesは先に展開したい順に渡す（深さ優先探索ではスタックに逆順に積む）
+func (g *Generator) pushQueue(nodes []*Node) {
+	if g.strategy == StrategyDFS {
+		for i := len(nodes) - 1; i >= 0; i-- {
+			g.queue = append(g.queue, nodes[i])
+		}
+		return
+	}
+	g.queue = append(g.queue, nodes...)
+}
+
+// requeueDeferred 深さの上限によって展開されずにキューから外れたノードを、再開時にキューへ戻す
+// 上限を大きくして再開した場合に、その続きを探索するために使う
+func (g *Generator) requeueDeferred() {
+	if g.strategy == StrategyIterativeDeepening {
+		return
+	}
+	queued := make(map[string]bool, len(g.queue))
+	for _, node := range g.queue {
+		queued[(*node).GetID()] = true
+	}
+	var deferred []*Node
+	for _, node := range g.GetFrontierNodes() {
+		if !queued[(*node).GetID()] {
+			deferred = append(deferred, node)
+		}
+	}
+	sort.SliceStable(deferred, func(i, j int) bool {
+		return g.depths[(*deferred[i]).GetID()] < g.depths[(*deferred[j]).GetID()]
+	})
+	g.pushQueue(deferred)
+}
+
+// depthEntry 反復深化の深さ優先探索のスタックの1段
+type depthEntry struct {
+	node  *Node
+	depth int
+}
+
+// generateIterativeDeepening 反復深化で探索する
+// 深さの上限（g.bound）ごとに開始ノードから深さ優先探索を行い、上限で打ち切ったノードがなくなるか、
+// SetMaxDepthの上限に達するまで上限を1ずつ増やす。展開済みのノードは生成済みのエッジを辿り直す。
+func (g *Generator) generateIterativeDeepening(ctx context.Context) error {
+	startNode := g.GetStartNode()
+	outgoing := g.getOutgoingEdges()
+	for {
+		slog.Debug("[DEEPENING] 深さの上限", "bound", g.bound)
+		cutoff := false
+		visited := make(map[string]int)
+		stack := []depthEntry{{node: startNode, depth: 0}}
+		for len(stack) > 0 {
+			if err := ctx.Err(); err != nil {
+				slog.Warn("[INTERRUPT] 探索を中断しました", "nodes", len(g.nodes), "bound", g.bound)
+				return err
+			}
+			if g.limit != nil && g.iterations >= *g.limit {
+				slog.Error("[ERROR] 反復回数が上限を超えました。強制終了します。")
+				for _, observer := range g.observers {
+					observer.OnLimitReached(*g.limit)
+				}
+				return nil
+			}
+			g.iterations++
+
+			entry := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			nodeID := (*entry.node).GetID()
+			if depth, exists := visited[nodeID]; exists && depth <= entry.depth {
+				continue
+			}
+			visited[nodeID] = entry.depth
+			if entry.depth >= g.bound {
+				if !g.processedNodes[nodeID] {
+					cutoff = true
+				}
+				continue
+			}
+
+			edges, processed := outgoing[nodeID]
+			if !processed && !g.processedNodes[nodeID] {
+				slog.Debug("[ITERATION]", "count", g.iterations, "resources", logResources(entry.node), "id", nodeID, "depth", entry.depth)
+				g.processedNodes[nodeID] = true
+				edges = g.generateEdgesFromNode(entry.node)
+				outgoing[nodeID] = edges
+				for _, edge := range edges {
+					g.edges = append(g.edges, edge)
+					g.recordDepth((*edge.GetTo()).GetID(), entry.depth+1)
+					slog.Debug("[EDGE_ADD]", "edge", edge.String())
+					for _, observer := range g.observers {
+						observer.OnEdgeAdded(edge)
+					}
+				}
+				for _, observer := range g.observers {
+					observer.OnNodeExpanded(entry.node, edges, len(stack))
+				}
+			}
+			for i := len(edges) - 1; i >= 0; i-- {
+				stack = append(stack, depthEntry{node: edges[i].GetTo(), depth: entry.depth + 1})
+			}
+		}
+
+		if !cutoff {
+			return nil
+		}
+		if !g.withinMaxDepth(g.bound) {
+			slog.Debug("[DEPTH_LIMIT] 深さの上限に達しました", "maxDepth", *g.maxDepth)
+			return nil
+		}
+		g.bound++
+	}
+}
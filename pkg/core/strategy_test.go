@@ -0,0 +1,110 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchStrategies(t *testing.T) {
+	// 0 -> 1 -> 2 -> 3 -> 4 -> 6 と、近道 0 -> 5 -> 3
+	// 深さ優先探索では3を先に深さ3で展開し、後から近道で深さ2に更新されるため、その先の4と6へ深さを伝え直す必要がある
+	newRules := func() []*EdgeRule {
+		return []*EdgeRule{
+			newTestRule(t, "a", 0, 1),
+			newTestRule(t, "b", 1, 2),
+			newTestRule(t, "c", 2, 3),
+			newTestRule(t, "d", 3, 4),
+			newTestRule(t, "g", 4, 6),
+			newTestRule(t, "e", 0, 5),
+			newTestRule(t, "f", 5, 3),
+		}
+	}
+	ruleOrder := func(generator *Generator) string {
+		var names []string
+		for _, edge := range generator.GetEdges() {
+			names = append(names, edge.GetRule().GetName())
+		}
+		return strings.Join(names, ",")
+	}
+	expectedDepths := map[int]int{0: 0, 1: 1, 5: 1, 2: 2, 3: 2, 4: 3, 6: 4}
+
+	tests := []struct {
+		strategy SearchStrategy
+		order    string
+	}{
+		{StrategyBFS, "a,e,b,f,c,d,g"},
+		{StrategyDFS, "a,e,b,c,d,g,f"},
+		{StrategyIterativeDeepening, "a,e,b,f,c,d,g"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy.String(), func(t *testing.T) {
+			generator := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+			generator.SetStrategy(tt.strategy)
+			if err := generator.Generate(); err != nil {
+				t.Fatalf("failed to generate: %v", err)
+			}
+			if order := ruleOrder(generator); order != tt.order {
+				t.Errorf("expected edges %s, got %s", tt.order, order)
+			}
+			if generator.IsPartial() {
+				t.Error("expected a complete graph")
+			}
+
+			// 深さ4のノード6は発見されるが展開されない
+			maxDepth := 4
+			bounded := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+			bounded.SetStrategy(tt.strategy)
+			bounded.SetMaxDepth(&maxDepth)
+			if err := bounded.Generate(); err != nil {
+				t.Fatalf("failed to generate: %v", err)
+			}
+			if len(bounded.GetNodes()) != len(expectedDepths) {
+				t.Errorf("expected %d nodes, got %d", len(expectedDepths), len(bounded.GetNodes()))
+			}
+			for resources, expected := range expectedDepths {
+				node := newTestNode(resources)
+				if depth, exists := bounded.GetDepth(&node); !exists || depth != expected {
+					t.Errorf("%s: expected depth %d, got %d (exists: %v)", node.GetID(), expected, depth, exists)
+				}
+			}
+			frontier := bounded.GetFrontierNodes()
+			if len(frontier) != 1 || (*frontier[0]).GetID() != "n6" {
+				t.Errorf("expected only n6 to be a frontier node, got %d nodes", len(frontier))
+			}
+
+			// 深さの上限を外してチェックポイントから再開すると、続きを探索する
+			resumed := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+			resumed.SetStrategy(tt.strategy)
+			if err := resumed.Restore(bounded.Checkpoint()); err != nil {
+				t.Fatalf("failed to restore: %v", err)
+			}
+			if err := resumed.Generate(); err != nil {
+				t.Fatalf("failed to resume: %v", err)
+			}
+			if resumed.IsPartial() || len(resumed.GetEdges()) != len(generator.GetEdges()) {
+				t.Errorf("expected the resumed graph to be complete with %d edges, got %d", len(generator.GetEdges()), len(resumed.GetEdges()))
+			}
+		})
+	}
+
+	// 深さ0では開始ノードも展開しない
+	maxDepth := 0
+	generator := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+	generator.SetMaxDepth(&maxDepth)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if len(generator.GetNodes()) != 1 || len(generator.GetEdges()) != 0 || !generator.IsPartial() {
+		t.Errorf("expected only the unexpanded start node")
+	}
+
+	// 探索戦略が異なるチェックポイントからは再開できない
+	dfs := NewGenerator(newTestNode, newTestNode(0), newRules(), nil)
+	dfs.SetStrategy(StrategyDFS)
+	if err := dfs.Restore(generator.Checkpoint()); err == nil {
+		t.Error("expected an error when the strategy differs from the checkpoint")
+	}
+	if _, err := ParseSearchStrategy("random"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
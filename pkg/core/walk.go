@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math/rand/v2"
+)
+
+// WalkOptions ランダムウォーク（モンテカルロシミュレーション）の設定
+type WalkOptions struct {
+	Runs   int              // ウォークの回数
+	Steps  int              // 1回のウォークの最大の遷移数
+	Seed   uint64           // 乱数の種（同じ種とルールからは同じ結果になる）
+	Target func(*Node) bool // 到達したウォークを数える条件（nilの場合は数えない）
+}
+
+// WalkResult ランダムウォークの結果
+// 状態とエッジは保持せず、集計と代表的なパスのみを返す
+type WalkResult struct {
+	Runs       int
+	Steps      int
+	Seed       uint64
+	TotalSteps int64 // すべてのウォークの遷移数の合計
+
+	// DistinctStates 訪問した異なる状態の数
+	// ノードIDの64ビットのハッシュで数えるため、非常に多くの状態では衝突により少なく数える可能性がある
+	DistinctStates int
+
+	RuleFirings []RuleFiring    // ルールごとの実行回数（ルールの定義順）
+	Deadlocks   []*WalkDeadlock // ウォークが行き着いた、どのルールも実行できない状態（初めて到達した順）
+	Reached     []*WalkReach    // 条件を満たす状態に到達したウォーク（ウォークの番号順）
+	Shortest    *WalkReach      // 条件を満たす状態に最も少ない遷移数で到達したウォーク
+}
+
+// RuleFiring ランダムウォークでルールが実行された回数
+type RuleFiring struct {
+	Rule  *EdgeRule
+	Count int64
+}
+
+// WalkDeadlock ランダムウォークが行き着いたデッドロックの状態
+type WalkDeadlock struct {
+	Node *Node          // デッドロックの状態
+	Path TransitionPath // 最初に到達したウォークでの、開始ノードからのエッジ
+	Hits int            // この状態で終わったウォークの数
+}
+
+// WalkReach 条件を満たす状態に到達したウォーク
+type WalkReach struct {
+	Run   int            // ウォークの番号（0から）
+	Steps int            // 初めて条件を満たす状態に到達するまでの遷移数
+	Node  *Node          // 初めて到達した条件を満たす状態（Shortestのみ設定する）
+	Path  TransitionPath // 開始ノードからNodeまでのエッジ（Shortestのみ設定する）
+}
+
+// Walk 開始ノードから実行可能なルールを無作為に選んで遷移するウォークを繰り返し、統計を集める
+// 各状態からの遷移はマルコフ連鎖の解析と同様に、ルールの重み（複数の結果を持つルールは結果の重み）の比で選ぶ。
+// すべての重みが0の場合は等確率で選ぶ。ウォークはデッドロックに行き着くか、最大の遷移数に達すると終わる。
+// 終了しない状態空間でも、反復回数の上限による幅優先探索の一部分より偏りの少ない統計が得られる。
+// 各ウォークは種とウォークの番号から作る乱数を使うため、番号ごとに再現できる。
+// ジェネレーターの生成結果（GetNodes, GetEdges）には影響しない。
+func (g *Generator) Walk(options WalkOptions) (*WalkResult, error) {
+	if options.Runs <= 0 {
+		return nil, fmt.Errorf("ウォークの回数は1以上を指定してください: %d", options.Runs)
+	}
+	if options.Steps < 0 {
+		return nil, fmt.Errorf("最大の遷移数は0以上を指定してください: %d", options.Steps)
+	}
+
+	result := &WalkResult{Runs: options.Runs, Steps: options.Steps, Seed: options.Seed}
+	visited := make(map[uint64]struct{})
+	firings := make(map[*EdgeRule]int64)
+	deadlocks := make(map[string]*WalkDeadlock)
+	startNode := g.newNode(g.startResources.GetResources())
+
+	for run := 0; run < options.Runs; run++ {
+		random := rand.New(rand.NewPCG(options.Seed, uint64(run)))
+		node := &startNode
+		var path TransitionPath
+		reached := false
+		for step := 0; ; step++ {
+			visited[walkStateHash((*node).GetID())] = struct{}{}
+			if !reached && options.Target != nil && options.Target(node) {
+				reached = true
+				result.Reached = append(result.Reached, &WalkReach{Run: run, Steps: step})
+				if result.Shortest == nil || step < result.Shortest.Steps {
+					result.Shortest = &WalkReach{Run: run, Steps: step, Node: node, Path: append(TransitionPath(nil), path...)}
+				}
+			}
+			if step >= options.Steps {
+				break
+			}
+
+			edges, _ := g.successors(node)
+			if len(edges) == 0 {
+				id := (*node).GetID()
+				if deadlock, exists := deadlocks[id]; exists {
+					deadlock.Hits++
+				} else {
+					deadlocks[id] = &WalkDeadlock{Node: node, Path: append(TransitionPath(nil), path...), Hits: 1}
+					result.Deadlocks = append(result.Deadlocks, deadlocks[id])
+				}
+				slog.Debug("[WALK] デッドロック", "run", run, "step", step, "id", id)
+				break
+			}
+
+			edge := chooseWalkEdge(random, edges)
+			firings[edge.GetRule()]++
+			result.TotalSteps++
+			path = append(path, edge)
+			node = edge.GetTo()
+		}
+	}
+
+	result.DistinctStates = len(visited)
+	for _, rule := range g.edgeRules {
+		result.RuleFirings = append(result.RuleFirings, RuleFiring{Rule: rule, Count: firings[rule]})
+	}
+	slog.Debug("[WALK] 完了", "runs", options.Runs, "totalSteps", result.TotalSteps, "states", result.DistinctStates)
+	return result, nil
+}
+
+// chooseWalkEdge 重みの比でエッジを1つ選ぶ（重みの合計が0の場合は等確率）
+func chooseWalkEdge(random *rand.Rand, edges []*Edge) *Edge {
+	total := 0.0
+	for _, edge := range edges {
+		total += edge.GetWeight()
+	}
+	if total <= 0 {
+		return edges[random.IntN(len(edges))]
+	}
+	threshold := random.Float64() * total
+	for _, edge := range edges {
+		threshold -= edge.GetWeight()
+		if threshold < 0 {
+			return edge
+		}
+	}
+	return edges[len(edges)-1]
+}
+
+// walkStateHash 訪問した状態を数えるためのノードIDのハッシュ
+func walkStateHash(id string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	return hash.Sum64()
+}
@@ -0,0 +1,65 @@
+package core
+
+import "testing"
+
+func TestWalk(t *testing.T) {
+	// 0 -> 1 -> 0 のループと、0 -> 2（デッドロック）
+	rules := []*EdgeRule{
+		newTestRule(t, "a", 0, 1),
+		newTestRule(t, "b", 0, 2),
+		newTestRule(t, "c", 1, 0),
+		newTestRule(t, "unused", 5, 6),
+	}
+	generator := NewGenerator(newTestNode, newTestNode(0), rules, nil)
+	options := WalkOptions{
+		Runs:   200,
+		Steps:  100,
+		Seed:   42,
+		Target: func(n *Node) bool { return (*n).GetResources().(int) == 1 },
+	}
+	result, err := generator.Walk(options)
+	if err != nil {
+		t.Fatalf("failed to walk: %v", err)
+	}
+
+	if result.DistinctStates != 3 {
+		t.Errorf("expected 3 distinct states, got %d", result.DistinctStates)
+	}
+	if len(result.Deadlocks) != 1 || (*result.Deadlocks[0].Node).GetID() != "n2" || result.Deadlocks[0].Hits != options.Runs {
+		t.Fatalf("expected every walk to end at the deadlock n2, got %v", result.Deadlocks)
+	}
+	if path := result.Deadlocks[0].Path; len(path) == 0 || (*path[len(path)-1].GetTo()).GetID() != "n2" {
+		t.Errorf("expected the deadlock path to end at n2")
+	}
+	counts := make(map[string]int64)
+	var total int64
+	for _, firing := range result.RuleFirings {
+		counts[firing.Rule.GetName()] = firing.Count
+		total += firing.Count
+	}
+	if counts["b"] != int64(options.Runs) || counts["a"] != counts["c"] || counts["unused"] != 0 || total != result.TotalSteps {
+		t.Errorf("unexpected rule firings: %v (total steps %d)", counts, result.TotalSteps)
+	}
+	// ルールaとbは同じ重みのため、およそ半数のウォークが1に到達する
+	if len(result.Reached) < options.Runs/4 || len(result.Reached) > options.Runs*3/4 {
+		t.Errorf("expected about half of the walks to reach n1, got %d", len(result.Reached))
+	}
+	if result.Shortest == nil || result.Shortest.Steps != 1 || len(result.Shortest.Path) != 1 {
+		t.Errorf("expected the shortest walk to reach n1 in one step, got %+v", result.Shortest)
+	}
+
+	// 同じ種からは同じ結果になる
+	again, err := generator.Walk(options)
+	if err != nil {
+		t.Fatalf("failed to walk: %v", err)
+	}
+	if again.TotalSteps != result.TotalSteps || len(again.Reached) != len(result.Reached) {
+		t.Error("expected the same result for the same seed")
+	}
+	if len(generator.GetNodes()) != 0 {
+		t.Error("Walk should not add nodes to the generator")
+	}
+	if _, err := generator.Walk(WalkOptions{Runs: 0, Steps: 10}); err == nil {
+		t.Error("expected an error for zero runs")
+	}
+}
@@ -1,8 +1,17 @@
 package cud
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
 )
 
 func TestCudYamlParser(t *testing.T) {
@@ -163,3 +172,460 @@ func TestCudNodeOperations(t *testing.T) {
 		t.Errorf("Expected 3 resource strings, got %d", len(resourceStrings))
 	}
 }
+
+// TestCudNodeIDCompatibility キャッシュしたエンコードから求めたIDが、リソース全体のJSONのmd5と一致することを確認
+func TestCudNodeIDCompatibility(t *testing.T) {
+	cases := []map[string]any{
+		{"status": "running"},
+		{"b": 1, "a": 2.5, "c": -3},
+		{"html": "<a href=\"x\">&</a>", "unicode": "日本語\t\u2028"},
+		{"nested": map[string]any{"z": []any{1, "two", nil}, "a": true}, "flag": false, "none": nil},
+		{"big": uint64(18446744073709551615), "small": int64(-9223372036854775808)},
+	}
+	for _, resources := range cases {
+		marshaled, err := json.Marshal(resources)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		hash := md5.Sum(marshaled)
+		expected := hex.EncodeToString(hash[:])
+		if id := newCudNode(resources).GetID(); id != expected {
+			t.Errorf("resources %v: expected ID %s (%s), got %s", resources, expected, marshaled, id)
+		}
+		// ライブラリとして直接作成したCudNodeも同じIDになる
+		if id := CudNode(resources).GetID(); id != expected {
+			t.Errorf("resources %v: expected CudNode ID %s, got %s", resources, expected, id)
+		}
+	}
+
+	// 同じキーと値のエントリは共有される
+	a := newCudNode(map[string]any{"shared": "value", "x": 1})
+	b := newCudNode(map[string]any{"shared": "value", "x": 2})
+	if a.entries[0] != b.entries[0] {
+		t.Error("entries with the same key and value should be shared")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"yaml", "start_resources:\n  a: 1\nedge_rules: []\n", true},
+		{"json with effect", `{"start_resources": {}, "edge_rules": [{"name": "x", "effect": []}]}`, true},
+		{"stringlist", `{"start_resources": [], "edge_rules": [{"name": "x", "action": "create"}]}`, false},
+		{"broken", "start_resources: [", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if detect(tt.content) != tt.expected {
+				t.Errorf("expected %v for %s", tt.expected, tt.content)
+			}
+		})
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	writeFile("payment.yaml", `
+start_resources:
+  payment: "none"
+edge_rules:
+  - name: pay
+    effect:
+      - action: update
+        resource:
+          key: payment
+          value: "paid"
+    fire_condition: payment == "none"
+    block_condition: ""
+`)
+	mainPath := writeFile("main.yaml", `
+include:
+  - payment.yaml
+start_resources:
+  order: "open"
+edge_rules:
+  - name: close
+    effect:
+      - action: update
+        resource:
+          key: order
+          value: "closed"
+    fire_condition: payment == "paid"
+    block_condition: ""
+`)
+
+	parser := &CudYaml{}
+	parser.SetSourcePath(mainPath)
+	content, _ := os.ReadFile(mainPath)
+	firstResource, _, edgeRules, err := parser.Parse(string(content))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	resources := firstResource.GetResources().(map[string]any)
+	if resources["payment"] != "none" || resources["order"] != "open" {
+		t.Errorf("start resources are not merged: %v", resources)
+	}
+	if len(edgeRules) != 2 || edgeRules[0].Name != "pay" || edgeRules[1].Name != "close" {
+		t.Errorf("rules are not merged in include order: %v", edgeRules)
+	}
+
+	// ルール名の重複
+	duplicatePath := writeFile("duplicate.yaml", `
+include:
+  - payment.yaml
+edge_rules:
+  - name: pay
+    effect:
+      - action: delete
+        resource:
+          key: payment
+    fire_condition: ""
+    block_condition: ""
+`)
+	parser.SetSourcePath(duplicatePath)
+	content, _ = os.ReadFile(duplicatePath)
+	if _, _, _, err := parser.Parse(string(content)); err == nil || !strings.Contains(err.Error(), "duplicate rule name") {
+		t.Errorf("expected duplicate rule name error, got %v", err)
+	}
+
+	// 循環したinclude
+	cyclePath := writeFile("cycle.yaml", "include:\n  - cycle.yaml\n")
+	parser.SetSourcePath(cyclePath)
+	if _, _, _, err := parser.Parse("include:\n  - cycle.yaml\n"); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("expected include cycle error, got %v", err)
+	}
+
+	// 菱形のinclude（billing.yamlとshipping.yamlがどちらもpayment.yamlを取り込む）
+	writeFile("billing.yaml", "include:\n  - payment.yaml\n")
+	writeFile("shipping.yaml", "include:\n  - payment.yaml\n")
+	diamondPath := writeFile("diamond.yaml", "include:\n  - billing.yaml\n  - shipping.yaml\n")
+	parser.SetSourcePath(diamondPath)
+	content, _ = os.ReadFile(diamondPath)
+	_, _, edgeRules, err = parser.Parse(string(content))
+	if err != nil {
+		t.Fatalf("failed to parse diamond include: %v", err)
+	}
+	if len(edgeRules) != 1 || edgeRules[0].Name != "pay" {
+		t.Errorf("expected the shared file to be merged once, got %v", edgeRules)
+	}
+
+	// invariantsはファイルには書けない
+	invariantPath := writeFile("invariant.yaml", "include:\n  - payment.yaml\ninvariants:\n  - payment != \"\"\n")
+	parser.SetSourcePath(invariantPath)
+	content, _ = os.ReadFile(invariantPath)
+	if _, _, _, err := parser.Parse(string(content)); err == nil || !strings.Contains(err.Error(), "invariants cannot be defined") {
+		t.Errorf("expected invariants to be rejected, got %v", err)
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	input := `
+start_resources:
+  payment: "pending"
+edge_rules:
+  - name: call_payment_api
+    fire_condition: payment == "pending"
+    outcomes:
+      - name: success
+        weight: 8
+        effect:
+          - action: update
+            resource:
+              key: payment
+              value: "paid"
+      - name: timeout
+        effect:
+          - action: create
+            resource:
+              key: retry
+              value: true
+      - name: failure
+        effect:
+          - action: update
+            resource:
+              key: payment
+              value: "failed"
+`
+	parser := &CudYaml{}
+	firstResource, newNode, edgeRules, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	outcomes := edgeRules[0].GetOutcomes()
+	if len(outcomes) != 3 || outcomes[0].Weight != 8 || outcomes[1].Weight != 1 {
+		t.Fatalf("unexpected outcomes: %+v", outcomes)
+	}
+
+	generator := core.NewGenerator(newNode, firstResource, edgeRules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	labels := make(map[string]bool)
+	for _, edge := range generator.GetEdges() {
+		labels[edge.GetLabel()] = true
+	}
+	// timeoutの後もpaymentはpendingのため、もう一度API呼び出しが可能
+	for _, label := range []string{"call_payment_api/success", "call_payment_api/timeout", "call_payment_api/failure"} {
+		if !labels[label] {
+			t.Errorf("edge %s not found in %v", label, labels)
+		}
+	}
+	if len(generator.GetNodes()) != 6 {
+		t.Errorf("expected 6 nodes, got %d", len(generator.GetNodes()))
+	}
+
+	if _, _, _, err := parser.Parse(`
+start_resources: {}
+edge_rules:
+  - name: both
+    effect:
+      - action: create
+        resource: {key: a, value: 1}
+    outcomes:
+      - name: x
+        effect:
+          - action: create
+            resource: {key: b, value: 1}
+`); err == nil {
+		t.Error("expected error when both effect and outcomes are given")
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	input := `
+group_by: [phase, step]
+start_resources:
+  phase: "build"
+  step: "compile"
+edge_rules:
+  - name: release
+    fire_condition: phase == "build"
+    effect:
+      - action: update
+        resource:
+          key: phase
+          value: "release"
+      - action: delete
+        resource:
+          key: step
+`
+	parser := &CudYaml{}
+	firstResource, newNode, edgeRules, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	generator := core.NewGenerator(newNode, firstResource, edgeRules, nil)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	start := generator.GetStartNode()
+	if group := generator.GetNodeGroup(start); len(group) != 2 || group[0] != "phase: build" || group[1] != "step: compile" {
+		t.Errorf("unexpected group for start node: %v", group)
+	}
+	// stepを削除した状態はphaseの階層で打ち切られる
+	tree := generator.GetNodeGroupTree()
+	if len(tree.Children) != 2 || tree.Children[1].Name != "phase: release" || len(tree.Children[1].Nodes) != 1 {
+		t.Errorf("unexpected group tree: %+v", tree.Children)
+	}
+}
+
+func TestInferAccess(t *testing.T) {
+	input := `
+start_resources:
+  payment: "pending"
+  stock: 1
+edge_rules:
+  - name: pay
+    fire_condition: payment == "pending" && order != nil
+    block_condition: stock < 1
+    effect:
+      - action: update
+        resource: {key: payment, value: "paid"}
+      - action: delete
+        resource: {key: order}
+  - name: reset
+    effect:
+      - action: create
+        resource: {key: payment, value: "pending"}
+  - name: env
+    fire_condition: $env["payment"] == "paid"
+    effect:
+      - action: create
+        resource: {key: shipped, value: true}
+`
+	parser := &CudYaml{}
+	_, _, edgeRules, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	pay := edgeRules[0].Access
+	if pay == nil || pay.ReadsAll || strings.Join(pay.Reads, ",") != "payment,order,stock" || strings.Join(pay.Writes, ",") != "payment,order" {
+		t.Errorf("unexpected access for pay: %+v", pay)
+	}
+	// 空の発火条件と$envは、すべてのキーを参照するとみなす
+	for _, rule := range edgeRules[1:] {
+		if rule.Access == nil || !rule.Access.ReadsAll {
+			t.Errorf("expected %s to read all keys, got %+v", rule.GetName(), rule.Access)
+		}
+	}
+
+	// 独立に作成するフラグの組み合わせは削減され、デッドロックと保存したい条件に関わる状態は残る
+	var flags strings.Builder
+	flags.WriteString("start_resources: {}\nedge_rules:\n")
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&flags, "  - name: set_%[1]d\n    fire_condition: flag_%[1]d == nil\n    effect:\n      - action: create\n        resource: {key: flag_%[1]d, value: true}\n", i)
+	}
+	generate := func(options *core.ReductionOptions) *core.Generator {
+		firstResource, newNode, edgeRules, err := parser.Parse(flags.String())
+		if err != nil {
+			t.Fatalf("failed to parse: %v", err)
+		}
+		generator := core.NewGenerator(newNode, firstResource, edgeRules, nil)
+		generator.SetPartialOrderReduction(options)
+		if err := generator.Generate(); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		return generator
+	}
+	full := generate(nil)
+	reduced := generate(&core.ReductionOptions{Visible: []string{"flag_3", "flag_4"}})
+	if len(full.GetNodes()) != 64 || len(reduced.GetNodes()) >= len(full.GetNodes()) {
+		t.Errorf("expected fewer than 64 states with reduction, got %d (full %d)", len(reduced.GetNodes()), len(full.GetNodes()))
+	}
+	if deadlocks := reduced.GetDeadlockNodes(); len(deadlocks) != 1 || len((*deadlocks[0]).GetResources().(map[string]any)) != 6 {
+		t.Errorf("expected the deadlock with every flag to remain, got %d deadlocks", len(deadlocks))
+	}
+	found := false
+	for _, node := range reduced.GetNodes() {
+		if resources := (*node).GetResources().(map[string]any); resources["flag_3"] != nil && resources["flag_4"] == nil {
+			found = true
+		}
+	}
+	// 不変条件 !(flag_3 != nil && flag_4 == nil) の違反は削減しても見つかる
+	if !found {
+		t.Error("expected a state with flag_3 but without flag_4 to remain after reduction")
+	}
+}
+
+func TestRuleDigest(t *testing.T) {
+	parse := func(condition string) []*core.EdgeRule {
+		input := fmt.Sprintf(`
+start_resources: {a: 0}
+edge_rules:
+  - name: x
+    fire_condition: %s
+    effect:
+      - action: update
+        resource: {key: a, value: 1}
+  - name: y
+    fire_condition: a == 1
+    effect:
+      - action: delete
+        resource: {key: a}
+`, condition)
+		parser := &CudYaml{}
+		_, _, edgeRules, err := parser.Parse(input)
+		if err != nil {
+			t.Fatalf("failed to parse: %v", err)
+		}
+		return edgeRules
+	}
+	before := parse("a == 0")
+	same := parse("a == 0")
+	changed := parse("a >= 0")
+	if before[0].Digest == "" || before[0].Digest != same[0].Digest || before[1].Digest != same[1].Digest {
+		t.Errorf("expected stable digests, got %q and %q", before[0].Digest, same[0].Digest)
+	}
+	if before[0].Digest == changed[0].Digest || before[1].Digest != changed[1].Digest {
+		t.Error("expected only the edited rule to change its digest")
+	}
+}
+
+// benchmarkRules n個のフラグをそれぞれ独立に作成・削除できる、2^n状態のルール
+func benchmarkRules(n int) string {
+	var rules strings.Builder
+	rules.WriteString("start_resources:\n  status: \"running\"\n  owner: \"team-a\"\nedge_rules:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&rules, `  - name: set_%[1]d
+    effect:
+      - action: create
+        resource:
+          key: flag_%[1]d
+          value: "on"
+    fire_condition: flag_%[1]d == nil
+  - name: clear_%[1]d
+    effect:
+      - action: delete
+        resource:
+          key: flag_%[1]d
+          value: ""
+    fire_condition: flag_%[1]d != nil
+`, i)
+	}
+	return rules.String()
+}
+
+// newBenchmarkGenerator benchmarkRulesのルールでジェネレーターを作成
+func newBenchmarkGenerator(b *testing.B, n int) *core.Generator {
+	b.Helper()
+	parser, err := NewCudYamlParser()
+	if err != nil {
+		b.Fatalf("failed to create parser: %v", err)
+	}
+	firstResource, newNode, edgeRules, err := parser.Parse(benchmarkRules(n))
+	if err != nil {
+		b.Fatalf("failed to parse: %v", err)
+	}
+	return core.NewGenerator(newNode, firstResource, edgeRules, nil)
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	var states int
+	for i := 0; i < b.N; i++ {
+		generator := newBenchmarkGenerator(b, 8)
+		if err := generator.Generate(); err != nil {
+			b.Fatalf("failed to generate: %v", err)
+		}
+		states = len(generator.GetNodes())
+	}
+	b.ReportMetric(float64(states)*float64(b.N)/b.Elapsed().Seconds(), "states/s")
+}
+
+func BenchmarkMemoryPerState(b *testing.B) {
+	var perState float64
+	for i := 0; i < b.N; i++ {
+		generator := newBenchmarkGenerator(b, 10)
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		if err := generator.Generate(); err != nil {
+			b.Fatalf("failed to generate: %v", err)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		perState = float64(after.HeapAlloc-before.HeapAlloc) / float64(len(generator.GetNodes()))
+		runtime.KeepAlive(generator)
+	}
+	b.ReportMetric(perState, "B/state")
+}
+
+func BenchmarkGetID(b *testing.B) {
+	node := newCudNode(map[string]any{"status": "running", "owner": "team-a", "count": 3, "flag_1": "on", "flag_2": "on"})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node.GetID()
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ids/s")
+}
@@ -0,0 +1,123 @@
+package cud
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadDocument YAMLをパースし、includeで指定されたファイルを再帰的に取り込んだ結果を返す
+//
+// 取り込んだファイルのstart_resourcesとedge_rulesは、取り込み元より前に順番にマージする。
+// group_byは取り込み元の指定を優先する。
+// ファイル間でstart_resourcesのキーやルール名が重複した場合はエラーとする。
+// 複数のファイルから取り込まれる同じファイル（菱形のinclude）は、最初の1回だけマージする。
+// sourcePathが空の場合（標準入力など）は、カレントディレクトリを基準にincludeを解決する。
+// includingは取り込み中のファイル（循環の検出用）、loadedはマージ済みのファイルの絶対パス。
+func loadDocument(input string, sourcePath string, including []string, loaded map[string]bool) (*CudYaml, error) {
+	var document CudYaml
+	if err := yaml.Unmarshal([]byte(input), &document); err != nil {
+		if sourcePath != "" {
+			return nil, fmt.Errorf("%s: %w", sourcePath, err)
+		}
+		return nil, err
+	}
+	if len(document.Invariants) > 0 {
+		name := sourcePath
+		if name == "" {
+			name = "<input>"
+		}
+		return nil, fmt.Errorf("%s: invariants cannot be defined in cud files; pass them with -invariant", name)
+	}
+	if len(document.Include) == 0 {
+		return &document, nil
+	}
+
+	baseDir := "."
+	if sourcePath != "" {
+		baseDir = filepath.Dir(sourcePath)
+	}
+	if sourcePath != "" {
+		absolute, err := filepath.Abs(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		including = append(including, absolute)
+	}
+
+	merged := &CudYaml{}
+	sources := make(map[string]string) // キー・ルール名 -> 定義元のファイル
+	for _, include := range document.Include {
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(including, absolute) {
+			return nil, fmt.Errorf("include cycle detected: %s", path)
+		}
+		if loaded[absolute] {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read include: %w", err)
+		}
+		included, err := loadDocument(string(content), path, including, loaded)
+		if err != nil {
+			return nil, err
+		}
+		loaded[absolute] = true
+		if err := mergeDocument(merged, included, path, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	name := sourcePath
+	if name == "" {
+		name = "<input>"
+	}
+	document.Include = nil
+	if err := mergeDocument(merged, &document, name, sources); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// mergeDocument srcのstart_resourcesとedge_rulesをdstへマージする
+// sourcesには定義済みのキー・ルール名と定義元を記録し、重複を検出する
+func mergeDocument(dst *CudYaml, src *CudYaml, srcName string, sources map[string]string) error {
+	for key, value := range src.StartResources {
+		if definedIn, exists := sources["resource:"+key]; exists {
+			return fmt.Errorf("duplicate start resource key %q in %s (already defined in %s)", key, srcName, definedIn)
+		}
+		sources["resource:"+key] = srcName
+		if dst.StartResources == nil {
+			dst.StartResources = make(map[string]any)
+		}
+		dst.StartResources[key] = value
+	}
+
+	names := make(map[string]bool)
+	for _, rule := range src.EdgeRules {
+		if definedIn, exists := sources["rule:"+rule.Name]; exists && !names[rule.Name] {
+			return fmt.Errorf("duplicate rule name %q in %s (already defined in %s)", rule.Name, srcName, definedIn)
+		}
+		names[rule.Name] = true
+		sources["rule:"+rule.Name] = srcName
+	}
+	dst.EdgeRules = append(dst.EdgeRules, src.EdgeRules...)
+
+	// group_byは後からマージしたもの（最終的には取り込み元のファイル）を優先する
+	if len(src.GroupBy) > 0 {
+		dst.GroupBy = src.GroupBy
+	}
+	return nil
+}
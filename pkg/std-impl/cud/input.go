@@ -2,32 +2,82 @@ package cud
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/expr-lang/expr"
 	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/query"
 	"gopkg.in/yaml.v3"
 )
 
 type CudYaml struct {
+	Include        []string       `yaml:"include"`  // 取り込むYAMLファイル（このファイルからの相対パス）
+	GroupBy        GroupKeys      `yaml:"group_by"` // 出力時にノードを入れ子にまとめるキー（外側から順に）
 	StartResources map[string]any `yaml:"start_resources"`
 	EdgeRules      []struct {
-		Name   string `yaml:"name"`
-		Effect []struct {
-			Action   string `yaml:"action"` // create, update, delete
-			Resource struct {
-				Key   string `yaml:"key"`
-				Value any    `yaml:"value"`
-			} `yaml:"resource"`
-		} `yaml:"effect"`
-		FireCondition  string `yaml:"fire_condition"`  // expr-lang expression
-		BlockCondition string `yaml:"block_condition"` // expr-lang expression
+		Name           string       `yaml:"name"`
+		Effect         []CudEffect  `yaml:"effect"`
+		Outcomes       []CudOutcome `yaml:"outcomes"`        // 複数の結果（effectの代わりに指定する）
+		FireCondition  string       `yaml:"fire_condition"`  // expr-lang expression
+		BlockCondition string       `yaml:"block_condition"` // expr-lang expression
+		Priority       int          `yaml:"priority"`        // 優先度（高いルールが実行可能な場合、低いルールは遷移しない）
+		Weight         *float64     `yaml:"weight"`          // 重み（省略時は1、マルコフ連鎖の解析で遷移確率の比として使用）
 	} `yaml:"edge_rules"`
+	Invariants []string `yaml:"invariants"` // 対応していない（bitstateの-invariantで指定する）。指定した場合はエラーにする
+
+	sourcePath      string   // includeの相対パスの基準となる入力ファイルのパス
+	includeDisabled bool     // includeを指定した入力をエラーにする（DisableInclude）
+	sourceFiles     []string // 直前のParseでincludeにより取り込んだファイルの絶対パス（パス順）
+}
+
+// GroupKeys group_byの指定。1つのキーの文字列、または外側から順に並べたキーのリスト
+type GroupKeys []string
+
+// UnmarshalYAML 文字列1つの指定とリストの指定の両方を受け付ける
+func (k *GroupKeys) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var key string
+		if err := value.Decode(&key); err != nil {
+			return err
+		}
+		*k = GroupKeys{key}
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// CudEffect リソースに対する1つの操作
+type CudEffect struct {
+	Action   string `yaml:"action"` // create, update, delete
+	Resource struct {
+		Key   string `yaml:"key"`
+		Value any    `yaml:"value"`
+	} `yaml:"resource"`
+}
+
+// CudOutcome ルールの結果の1つ（結果ごとにeffectを持つ）
+type CudOutcome struct {
+	Name   string      `yaml:"name"`
+	Effect []CudEffect `yaml:"effect"`
+	Weight *float64    `yaml:"weight"` // 同じルールの結果の中での重み（省略時は1）
 }
 
 func createFireConditionFunc(conditionExpr string) func(*core.Node) bool {
 	if conditionExpr == "" {
 		return func(n *core.Node) bool {
-			resources, ok := (*n).GetResources().(map[string]any)
+			// cudNodeはマップを作らずにリソースの数を調べる
+			switch node := (*n).(type) {
+			case cudNode:
+				return len(node.entries) == 0
+			case groupedCudNode:
+				return len(node.entries) == 0
+			}
+			resources, ok := nodeResources(n)
 			if !ok {
 				panic(fmt.Sprintf("node resources is not map[string]any: %v", n))
 			}
@@ -41,7 +91,7 @@ func createFireConditionFunc(conditionExpr string) func(*core.Node) bool {
 	}
 
 	return func(n *core.Node) bool {
-		resources, ok := (*n).GetResources().(map[string]any)
+		resources, ok := nodeResources(n)
 		if !ok {
 			panic(fmt.Sprintf("node resources is not map[string]any: %v", n))
 		}
@@ -73,7 +123,7 @@ func createBlockConditionFunc(conditionExpr string) func(*core.Node) bool {
 	}
 
 	return func(n *core.Node) bool {
-		resources, ok := (*n).GetResources().(map[string]any)
+		resources, ok := nodeResources(n)
 		if !ok {
 			panic(fmt.Sprintf("node resources is not map[string]any: %v", n))
 		}
@@ -92,25 +142,175 @@ func createBlockConditionFunc(conditionExpr string) func(*core.Node) bool {
 	}
 }
 
+// createEffectFunc effect配列を順番に適用するエフェクト関数を作成
+// cudNodeにはキーと値の組を直接差し替えて適用し、リソースのマップを経由しない
+func createEffectFunc(ruleName string, effects []CudEffect, newNode func(any) core.Node) func(*core.Node) *core.Node {
+	// create, updateで設定するキーと値の組は、ルールごとに一度だけインターンしておく
+	prepared := make([]*cudEntry, len(effects))
+	for i, effect := range effects {
+		if effect.Resource.Key != "" && effect.Resource.Value != nil {
+			prepared[i] = newCudEntry(effect.Resource.Key, effect.Resource.Value)
+		}
+	}
+
+	return func(n *core.Node) *core.Node {
+		var current cudNode
+		switch node := (*n).(type) {
+		case cudNode:
+			current = node
+		case groupedCudNode:
+			current = node.cudNode
+		default:
+			resources, ok := nodeResources(n)
+			if !ok {
+				panic(fmt.Sprintf("node resources is not map[string]any: %v", n))
+			}
+			current = newCudNode(resources)
+		}
+
+		// 既存のエントリをコピーして、各effectを順番に適用
+		entries := make([]*cudEntry, len(current.entries), len(current.entries)+len(effects))
+		copy(entries, current.entries)
+		for i, effect := range effects {
+			if effect.Resource.Key == "" {
+				panic(fmt.Sprintf("resource key cannot be empty for rule: %s", ruleName))
+			}
+
+			switch effect.Action {
+			case "create":
+				if effect.Resource.Value == nil {
+					panic(fmt.Sprintf("resource value cannot be nil for create action in rule: %s", ruleName))
+				}
+				entries = setCudEntry(entries, prepared[i])
+
+			case "update":
+				if _, exists := findCudEntry(entries, effect.Resource.Key); !exists {
+					panic(fmt.Sprintf("rule: %s, key %s not found in current resources %v", ruleName, effect.Resource.Key, cudNode{entries: entries}.GetResources()))
+				}
+				if effect.Resource.Value == nil {
+					panic(fmt.Sprintf("resource value cannot be nil for update action in rule: %s", ruleName))
+				}
+				entries = setCudEntry(entries, prepared[i])
+
+			case "delete":
+				entries = deleteCudEntry(entries, effect.Resource.Key)
+
+			default:
+				panic(fmt.Sprintf("unknown action: %s in rule: %s", effect.Action, ruleName))
+			}
+		}
+		next := cudNode{entries: entries, id: cudNodeID(entries)}
+
+		var result core.Node
+		switch node := (*n).(type) {
+		case cudNode:
+			result = next
+		case groupedCudNode:
+			node.cudNode = next
+			result = node
+		default:
+			result = newNode(next.GetResources())
+		}
+		return &result
+	}
+}
+
+// inferAccess 条件式と効果から、ルールが参照・変更するキーを推定する（推定できない場合はnil）
+// 条件式が参照する変数がそのままキーになる。空の発火条件はリソースが空かどうかを見るため、すべてのキーを参照する。
+// updateはキーが存在することを前提とするため、変更するキーに加えて参照するキーにも含める。
+func inferAccess(fireCondition, blockCondition string, effects []CudEffect) *core.RuleAccess {
+	access := &core.RuleAccess{ReadsAll: fireCondition == ""}
+	reads := make(map[string]bool)
+	writes := make(map[string]bool)
+	for _, condition := range []string{fireCondition, blockCondition} {
+		if condition == "" {
+			continue
+		}
+		names, err := query.Identifiers(condition)
+		if err != nil {
+			return nil
+		}
+		for _, name := range names {
+			if name == "$env" {
+				access.ReadsAll = true
+				continue
+			}
+			if !reads[name] {
+				reads[name] = true
+				access.Reads = append(access.Reads, name)
+			}
+		}
+	}
+	for _, effect := range effects {
+		key := effect.Resource.Key
+		if effect.Action == "update" && !reads[key] {
+			reads[key] = true
+			access.Reads = append(access.Reads, key)
+		}
+		if !writes[key] {
+			writes[key] = true
+			access.Writes = append(access.Writes, key)
+		}
+	}
+	return access
+}
+
 func NewCudYamlParser() (core.Parser, error) {
 	return &CudYaml{}, nil
 }
 
+// SetSourcePath 入力ファイルのパスを設定（includeの相対パスの解決に使用）
+func (c *CudYaml) SetSourcePath(path string) {
+	c.sourcePath = path
+}
+
+// DisableInclude includeを無効にする（core.IncludeDisablerの実装）
+func (c *CudYaml) DisableInclude() {
+	c.includeDisabled = true
+}
+
+// SourceFiles includeで取り込んだファイルの絶対パスを取得（core.SourceFilesReporterの実装）
+func (c *CudYaml) SourceFiles() []string {
+	return c.sourceFiles
+}
+
 func (c *CudYaml) Parse(input string) (
 	firstResource core.Node,
 	newNode func(any) core.Node,
 	edgeRules []*core.EdgeRule,
 	err error,
 ) {
-	var cudYaml CudYaml
-	err = yaml.Unmarshal([]byte(input), &cudYaml)
+	if c.includeDisabled {
+		var document struct {
+			Include []string `yaml:"include"`
+		}
+		if err := yaml.Unmarshal([]byte(input), &document); err != nil {
+			return nil, nil, nil, err
+		}
+		if len(document.Include) > 0 {
+			return nil, nil, nil, fmt.Errorf("include is not allowed for this input")
+		}
+	}
+	loaded := make(map[string]bool)
+	cudYaml, err := loadDocument(input, c.sourcePath, nil, loaded)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	c.sourceFiles = make([]string, 0, len(loaded))
+	for path := range loaded {
+		c.sourceFiles = append(c.sourceFiles, path)
+	}
+	sort.Strings(c.sourceFiles)
 
 	newNode = func(resources any) core.Node {
 		return newCudNode(resources.(map[string]any))
 	}
+	if len(cudYaml.GroupBy) > 0 {
+		groupBy := cudYaml.GroupBy
+		newNode = func(resources any) core.Node {
+			return groupedCudNode{cudNode: newCudNode(resources.(map[string]any)), groupBy: groupBy}
+		}
+	}
 
 	for _, rule := range cudYaml.EdgeRules {
 		// クロージャー内で使用するためにルールをコピー
@@ -118,63 +318,58 @@ func (c *CudYaml) Parse(input string) (
 		fireCondition := createFireConditionFunc(currentRule.FireCondition)
 		blockCondition := createBlockConditionFunc(currentRule.BlockCondition)
 
-		// effect配列の処理
-		if len(currentRule.Effect) == 0 {
-			return nil, nil, nil, fmt.Errorf("effect cannot be empty for rule: %s", currentRule.Name)
-		}
-
-		edgeRule, err := core.NewEdgeRule(
-			currentRule.Name,
-			func(n *core.Node) *core.Node {
-				currentResources, ok := (*n).GetResources().(map[string]any)
-				if !ok {
-					panic(fmt.Sprintf("node resources is not map[string]any: %v", n))
+		var edgeRule *core.EdgeRule
+		effects := currentRule.Effect
+		switch {
+		case len(currentRule.Effect) > 0 && len(currentRule.Outcomes) > 0:
+			return nil, nil, nil, fmt.Errorf("effect and outcomes cannot be used together for rule: %s", currentRule.Name)
+		case len(currentRule.Outcomes) > 0:
+			outcomes := make([]*core.Outcome, 0, len(currentRule.Outcomes))
+			for _, cudOutcome := range currentRule.Outcomes {
+				effects = append(effects, cudOutcome.Effect...)
+				if len(cudOutcome.Effect) == 0 {
+					return nil, nil, nil, fmt.Errorf("effect cannot be empty for outcome %s of rule: %s", cudOutcome.Name, currentRule.Name)
 				}
-				newResources := make(map[string]any)
-				// 既存のリソースをコピー
-				for k, v := range currentResources {
-					newResources[k] = v
+				outcome, err := core.NewOutcome(cudOutcome.Name, createEffectFunc(currentRule.Name, cudOutcome.Effect, newNode))
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("rule %s: %w", currentRule.Name, err)
 				}
-
-				// 各effectを順番に適用
-				for _, effect := range currentRule.Effect {
-					if effect.Resource.Key == "" {
-						panic(fmt.Sprintf("resource key cannot be empty for rule: %s", currentRule.Name))
-					}
-
-					switch effect.Action {
-					case "create":
-						if effect.Resource.Value == nil {
-							panic(fmt.Sprintf("resource value cannot be nil for create action in rule: %s", currentRule.Name))
-						}
-						newResources[effect.Resource.Key] = effect.Resource.Value
-
-					case "update":
-						if _, exists := newResources[effect.Resource.Key]; !exists {
-							panic(fmt.Sprintf("rule: %v, key %s not found in current resources %v", currentRule, effect.Resource.Key, newResources))
-						}
-						if effect.Resource.Value == nil {
-							panic(fmt.Sprintf("resource value cannot be nil for update action in rule: %s", currentRule.Name))
-						}
-						newResources[effect.Resource.Key] = effect.Resource.Value
-
-					case "delete":
-						delete(newResources, effect.Resource.Key)
-
-					default:
-						panic(fmt.Sprintf("unknown action: %s in rule: %s", effect.Action, currentRule.Name))
+				if cudOutcome.Weight != nil {
+					if *cudOutcome.Weight < 0 {
+						return nil, nil, nil, fmt.Errorf("weight cannot be negative for outcome %s of rule: %s", cudOutcome.Name, currentRule.Name)
 					}
+					outcome.Weight = *cudOutcome.Weight
 				}
-
-				newNode := newNode(newResources)
-				return &newNode
-			},
-			fireCondition,
-			blockCondition,
-		)
+				outcomes = append(outcomes, outcome)
+			}
+			edgeRule, err = core.NewEdgeRuleWithOutcomes(currentRule.Name, outcomes, fireCondition, blockCondition)
+		case len(currentRule.Effect) > 0:
+			edgeRule, err = core.NewEdgeRule(
+				currentRule.Name,
+				createEffectFunc(currentRule.Name, currentRule.Effect, newNode),
+				fireCondition,
+				blockCondition,
+			)
+		default:
+			return nil, nil, nil, fmt.Errorf("effect cannot be empty for rule: %s", currentRule.Name)
+		}
 		if err != nil {
 			return nil, nil, nil, err
 		}
+		edgeRule.FireConditionText = currentRule.FireCondition
+		if edgeRule.FireConditionText == "" {
+			edgeRule.FireConditionText = "empty"
+		}
+		edgeRule.BlockConditionText = currentRule.BlockCondition
+		edgeRule.Access = inferAccess(currentRule.FireCondition, currentRule.BlockCondition, effects)
+		edgeRule.Digest = core.RuleDigest(currentRule)
+		edgeRule.Priority = currentRule.Priority
+		if currentRule.Weight != nil {
+			if *currentRule.Weight < 0 {
+				return nil, nil, nil, fmt.Errorf("weight cannot be negative for rule: %s", currentRule.Name)
+			}
+			edgeRule.Weight = *currentRule.Weight
+		}
 		edgeRules = append(edgeRules, edgeRule)
 	}
 
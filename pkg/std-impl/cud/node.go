@@ -2,14 +2,18 @@ package cud
 
 import (
 	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"sort"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/intern"
 )
 
 // CudNode キーバリューペアのリソースの状態を表現
+// ライブラリとして直接ノードを作る場合に使う。パーサーはよりコンパクトなcudNodeを生成するが、
+// 同じリソースであれば同じIDになるため、混在させてもよい。
 type CudNode map[string]any
 
 // GetID ノードの一意な識別子を生成
@@ -64,18 +68,198 @@ func (n CudNode) GetResourcesString() []string {
 	return result
 }
 
-func newCudNode(resources map[string]any) CudNode {
-	// nilチェックとコピー作成
-	if resources == nil {
-		return CudNode{}
-	}
+// cudNode パーサーが生成する、キーバリューペアのリソースの状態のコンパクトな表現
+// キーと値の組はインターンして多くのノードで共有し、ノードはキーの順に並べた組へのポインタとIDだけを持つ。
+// IDは作成時に、組ごとにキャッシュしたJSONとしての表現をつなげて一度だけ求める（CudNodeのIDと同じ値になる）。
+type cudNode struct {
+	entries []*cudEntry // キーの順に並べたリソース
+	id      string
+}
+
+// cudEntry リソースの1つのキーと値の組（同じ組は1つのインスタンスを共有する）
+type cudEntry struct {
+	key          string
+	value        any
+	encodedValue string // 値をjson.Marshalした結果
+	encoded      string // マップをjson.Marshalしたときのこの組の部分（"キー":値）
+}
+
+// cudEntryKey インターンの表のキー
+// 比較可能な値はそのまま使い、マップやスライスなどはJSONとしての表現（encodedValue型）を使う
+type cudEntryKey struct {
+	key   string
+	value any
+}
+
+// encodedValue 比較できない値の代わりにcudEntryKeyで使うJSONとしての表現
+type encodedValue string
+
+// cudEntries キーと値の組のインターンの表
+var cudEntries = intern.NewPool[cudEntryKey, cudEntry]()
 
-	result := make(CudNode, len(resources))
-	for k, v := range resources {
-		if k != "" { // 空キーは除外
-			result[k] = v
+// newCudEntry キーと値の組をインターンして取得
+func newCudEntry(key string, value any) *cudEntry {
+	var encoded string
+	poolKey := cudEntryKey{key: key, value: value}
+	switch value.(type) {
+	case nil, bool, int, int64, uint64, float64, string:
+	default:
+		var err error
+		encoded, err = intern.Encode(value)
+		if err != nil {
+			panic(fmt.Sprintf("failed to marshal node: %v", err))
+		}
+		poolKey.value = encodedValue(encoded)
+	}
+	return cudEntries.Get(poolKey, func() *cudEntry {
+		if encoded == "" {
+			var err error
+			encoded, err = intern.Encode(value)
+			if err != nil {
+				panic(fmt.Sprintf("failed to marshal node: %v", err))
+			}
 		}
+		return &cudEntry{
+			key:          key,
+			value:        value,
+			encodedValue: encoded,
+			encoded:      intern.QuoteString(key) + ":" + encoded,
+		}
+	})
+}
+
+// GetID ノードの一意な識別子を取得
+// リソースのマップをjson.Marshalした結果（空の場合は空文字列）のMD5
+func (n cudNode) GetID() string {
+	return n.id
+}
+
+// Equals ノードが同じかどうかを判定
+func (n cudNode) Equals(other core.Node) bool {
+	return n.GetID() == other.GetID()
+}
+
+// GetResources リソースのマップを取得
+// 呼び出すたびに新しいマップを作成するため、変更しても他のノードには影響しない
+func (n cudNode) GetResources() any {
+	resources := make(map[string]any, len(n.entries))
+	for _, entry := range n.entries {
+		resources[entry.key] = entry.value
 	}
+	return resources
+}
 
+// GetResourcesString リソースのリストを文字列で取得
+func (n cudNode) GetResourcesString() []string {
+	if len(n.entries) == 0 {
+		return []string{"empty"}
+	}
+
+	// キーの順に「キー:JSON形式の値」で出力
+	result := make([]string, len(n.entries))
+	for i, entry := range n.entries {
+		result[i] = entry.key + ":" + entry.encodedValue
+	}
 	return result
 }
+
+// nodeResources 条件の評価で使うリソースのマップを取得
+// 呼び出すたびに新しいマップを作成するため、複数のゴルーチンから同時に呼び出してもよい
+func nodeResources(n *core.Node) (map[string]any, bool) {
+	resources, ok := (*n).GetResources().(map[string]any)
+	return resources, ok
+}
+
+// get キーの値を取得
+func (n cudNode) get(key string) (any, bool) {
+	if i, exists := findCudEntry(n.entries, key); exists {
+		return n.entries[i].value, true
+	}
+	return nil, false
+}
+
+// findCudEntry キーの順に並んだentriesからキーの位置を探す（ない場合は挿入する位置とfalseを返す）
+func findCudEntry(entries []*cudEntry, key string) (int, bool) {
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].key >= key })
+	return i, i < len(entries) && entries[i].key == key
+}
+
+// setCudEntry キーの順を保ったまま、同じキーの組を置き換える（ない場合は挿入する）
+func setCudEntry(entries []*cudEntry, entry *cudEntry) []*cudEntry {
+	i, exists := findCudEntry(entries, entry.key)
+	if exists {
+		entries[i] = entry
+		return entries
+	}
+	entries = append(entries, nil)
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	return entries
+}
+
+// deleteCudEntry キーの組を取り除く
+func deleteCudEntry(entries []*cudEntry, key string) []*cudEntry {
+	if i, exists := findCudEntry(entries, key); exists {
+		return append(entries[:i], entries[i+1:]...)
+	}
+	return entries
+}
+
+// newCudNode リソースのマップからコンパクトな表現のノードを作成
+func newCudNode(resources map[string]any) cudNode {
+	// 空キーは除外してキーの順に並べる
+	keys := make([]string, 0, len(resources))
+	for k := range resources {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	entries := make([]*cudEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = newCudEntry(k, resources[k])
+	}
+	return cudNode{entries: entries, id: cudNodeID(entries)}
+}
+
+// cudNodeID キャッシュした組の表現から、マップをjson.Marshalした結果と同じ表現を組み立ててMD5を求める
+// json.Marshalはマップのキーを辞書順に並べるため、キーの順に並べたentriesをそのままつなげばよい
+func cudNodeID(entries []*cudEntry) string {
+	size := 2
+	for _, entry := range entries {
+		size += len(entry.encoded) + 1
+	}
+	encoded := make([]byte, 0, size)
+	if len(entries) > 0 {
+		encoded = append(encoded, '{')
+		for i, entry := range entries {
+			if i > 0 {
+				encoded = append(encoded, ',')
+			}
+			encoded = append(encoded, entry.encoded...)
+		}
+		encoded = append(encoded, '}')
+	}
+	sum := md5.Sum(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// groupedCudNode group_byで指定したキーの値でグループ分けされるcudNode
+type groupedCudNode struct {
+	cudNode
+	groupBy []string
+}
+
+// GetGroup group_byのキーの値を外側から順に「キー: 値」として返す（キーがない階層で打ち切る）
+func (n groupedCudNode) GetGroup() []string {
+	var group []string
+	for _, key := range n.groupBy {
+		value, exists := n.cudNode.get(key)
+		if !exists {
+			break
+		}
+		group = append(group, fmt.Sprintf("%s: %v", key, value))
+	}
+	return group
+}
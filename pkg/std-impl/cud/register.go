@@ -0,0 +1,52 @@
+package cud
+
+import (
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	err := core.RegisterParser(core.ParserRegistration{
+		Name:        "cud",
+		Description: "キーバリューのリソースをcreate/update/deleteで操作するYAML形式",
+		Extensions:  []string{".yaml", ".yml", ".json"},
+		New:         NewCudYamlParser,
+		Detect:      detect,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// detect start_resourcesがマップである、またはedge_rulesがeffect/outcomesを持つ場合にcud形式と判定
+func detect(content string) bool {
+	var document map[string]any
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return false
+	}
+
+	if start, exists := document["start_resources"]; exists && start != nil {
+		if _, isMap := start.(map[string]any); !isMap {
+			return false
+		}
+	}
+	if rules, ok := document["edge_rules"].([]any); ok {
+		for _, rule := range rules {
+			fields, ok := rule.(map[string]any)
+			if !ok {
+				continue
+			}
+			if _, hasAction := fields["action"]; hasAction {
+				return false
+			}
+			if _, hasEffect := fields["effect"]; hasEffect {
+				return true
+			}
+			if _, hasOutcomes := fields["outcomes"]; hasOutcomes {
+				return true
+			}
+		}
+	}
+	_, isMap := document["start_resources"].(map[string]any)
+	return isMap
+}
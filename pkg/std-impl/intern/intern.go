@@ -0,0 +1,116 @@
+// Package intern はノードの実装（cud, stringlist）で共有する、リソースのコンパクトな表現を提供する
+//
+// 多くの状態で同じキーや値が繰り返し現れるため、文字列や値を1つのインスタンスにまとめ（インターン）、
+// 値のJSONとしての表現を一度だけ求めてキャッシュできるようにする。
+// インターンした値はweakポインタで保持するため、どのノードからも参照されなくなればGCで回収される。
+package intern
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"unique"
+	"weak"
+)
+
+// Symbol インターンした文字列
+// 同じ内容の文字列は1つのインスタンスを共有し、ポインタ1つ分の大きさで保持できる
+type Symbol struct {
+	handle unique.Handle[string]
+}
+
+// Intern 文字列をインターンする
+func Intern(s string) Symbol {
+	return Symbol{handle: unique.Make(s)}
+}
+
+// String インターンした文字列を取得
+func (s Symbol) String() string {
+	return s.handle.Value()
+}
+
+// Pool 同じキーで作成した値を1つのインスタンスにまとめる表
+type Pool[K comparable, T any] struct {
+	mu      sync.Mutex
+	entries map[K]weak.Pointer[T]
+}
+
+// NewPool 新しいPoolを作成
+func NewPool[K comparable, T any]() *Pool[K, T] {
+	return &Pool[K, T]{entries: make(map[K]weak.Pointer[T])}
+}
+
+// Get キーに対応するインスタンスを取得し、なければcreateで作成して登録する
+func (p *Pool[K, T]) Get(key K, create func() *T) *T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pointer, exists := p.entries[key]; exists {
+		if value := pointer.Value(); value != nil {
+			return value
+		}
+	}
+	value := create()
+	p.entries[key] = weak.Make(value)
+	// 回収されたら表から取り除く（その間に同じキーで作り直された場合は残す）
+	runtime.AddCleanup(value, func(key K) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if pointer, exists := p.entries[key]; exists && pointer.Value() == nil {
+			delete(p.entries, key)
+		}
+	}, key)
+	return value
+}
+
+// Len 登録されているインスタンスの数（回収待ちのものを含む）
+func (p *Pool[K, T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// QuoteString 文字列をJSONの文字列として表現する
+func QuoteString(s string) string {
+	if isPlainString(s) {
+		return `"` + s + `"`
+	}
+	marshaled, _ := json.Marshal(s)
+	return string(marshaled)
+}
+
+// Encode 値をJSONとして表現する（encoding/jsonのjson.Marshalと同じ結果になる）
+// よく使われる型はjson.Marshalを経由せずに求め、それ以外はjson.Marshalを使う
+func Encode(raw any) (string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case string:
+		return QuoteString(v), nil
+	}
+	marshaled, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return string(marshaled), nil
+}
+
+// isPlainString JSONの文字列としてエスケープが不要か（json.MarshalがHTMLのためにエスケープする<, >, &も含めて判定）
+func isPlainString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x80 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package intern
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	values := []any{
+		nil, true, false, 0, -42, int64(-9223372036854775808), uint64(18446744073709551615), 1.5, 1e21,
+		"", "plain", "quote\"backslash\\", "<script>&</script>", "日本語", "  ", "tab\tnewline\n",
+		[]any{1, "a", nil}, map[string]any{"b": 1, "a": []string{"x"}},
+	}
+	for _, value := range values {
+		expected, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %v", value, err)
+		}
+		encoded, err := Encode(value)
+		if err != nil {
+			t.Fatalf("failed to encode %v: %v", value, err)
+		}
+		if encoded != string(expected) {
+			t.Errorf("Encode(%#v): expected %s, got %s", value, expected, encoded)
+		}
+	}
+
+	if _, err := Encode(func() {}); err == nil {
+		t.Error("expected an error for a value that cannot be marshaled")
+	}
+}
+
+func TestSymbol(t *testing.T) {
+	a := Intern("resource")
+	b := Intern(string([]byte("resource")))
+	if a != b {
+		t.Error("symbols with the same string should be equal")
+	}
+	if a == Intern("other") {
+		t.Error("symbols with different strings should not be equal")
+	}
+	if a.String() != "resource" {
+		t.Errorf("expected resource, got %s", a.String())
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool := NewPool[string, string]()
+	created := 0
+	create := func() *string {
+		created++
+		value := "value"
+		return &value
+	}
+	first := pool.Get("key", create)
+	second := pool.Get("key", create)
+	if first != second {
+		t.Error("Get with the same key should return the same instance")
+	}
+	if created != 1 {
+		t.Errorf("expected create to be called once, got %d", created)
+	}
+	if pool.Len() != 1 {
+		t.Errorf("expected 1 entry, got %d", pool.Len())
+	}
+}
@@ -18,28 +18,30 @@ func NewDotFormatter() *DotFormatter {
 // Format ステートマシンをDOT形式で出力
 func (f *DotFormatter) Format(generator *core.Generator) (string, error) {
 	var dot strings.Builder
+	if comment := partialComment(generator); comment != "" {
+		dot.WriteString("// " + comment + "\n")
+	}
 	dot.WriteString("digraph G {\n")
 	dot.WriteString("  rankdir=LR;\n")
 	dot.WriteString("  node [shape=box];\n\n")
 
-	// ノードの出力（開始ノードを最初に出力）
-	startNode := generator.GetStartNode()
-	if startNode != nil {
-		nodeID := getDotNodeID(startNode)
-		label := getDotNodeLabel(startNode)
-		dot.WriteString(fmt.Sprintf("  %s [label=\"%s\"];\n", nodeID, label))
-	}
-
-	// 開始ノード以外のノードを出力
-	for _, node := range generator.GetNodes() {
-		// 開始ノードは既に出力済みなのでスキップ
-		if startNode != nil && (*node).GetID() == (*startNode).GetID() {
-			continue
-		}
-		nodeID := getDotNodeID(node)
-		label := getDotNodeLabel(node)
-		dot.WriteString(fmt.Sprintf("  %s [label=\"%s\"];\n", nodeID, label))
-	}
+	// ノードの出力（開始ノードを最初に出力し、グループ分けがある場合はclusterで入れ子にする）
+	groupWriter{
+		node: func(indent string, node *core.Node) {
+			// 展開されなかったノードは破線の枠で表示する
+			style := ""
+			if generator.IsFrontier(node) {
+				style = ", style=dashed"
+			}
+			dot.WriteString(fmt.Sprintf("%s%s [label=%s%s];\n", indent, getDotNodeID(node), quoteDotLines(withFrontierMarker(generator, node, (*node).GetResourcesString())), style))
+		},
+		open: func(indent string, index int, name string) {
+			dot.WriteString(fmt.Sprintf("%ssubgraph cluster_%d {\n%s  label=%s;\n", indent, index, indent, quoteDot(name)))
+		},
+		close: func(indent string) {
+			dot.WriteString(indent + "}\n")
+		},
+	}.write(generator.GetNodeGroupTree(), "  ", "  ")
 
 	dot.WriteString("\n")
 
@@ -47,8 +49,8 @@ func (f *DotFormatter) Format(generator *core.Generator) (string, error) {
 	for _, edge := range generator.GetEdges() {
 		fromID := getDotNodeID(edge.GetFrom())
 		toID := getDotNodeID(edge.GetTo())
-		edgeLabel := edge.GetRule().GetName()
-		dot.WriteString(fmt.Sprintf("  %s -> %s [label=\"%s\"];\n", fromID, toID, edgeLabel))
+		edgeLabel := quoteDot(edge.GetLabel())
+		dot.WriteString(fmt.Sprintf("  %s -> %s [label=%s];\n", fromID, toID, edgeLabel))
 	}
 
 	dot.WriteString("}\n")
@@ -57,34 +59,7 @@ func (f *DotFormatter) Format(generator *core.Generator) (string, error) {
 }
 
 // getDotNodeID ノードのDOT IDを生成
+// DOTのIDは二重引用符で囲めば任意の文字列（空文字を含む）を使えるため、ノードIDをそのままエスケープして使う（衝突しない）
 func getDotNodeID(node *core.Node) string {
-	id := fmt.Sprintf("\"%s\"", (*node).GetID())
-
-	// 明示的にemptyの場合
-	if id == "empty" {
-		return "empty"
-	}
-
-	// 空文字列の場合（これは起こるべきではないが、安全のため）
-	if id == "" {
-		return "empty"
-	}
-
-	// 通常のIDをDOTで使える形式に変換
-	// カンマをアンダースコアに、スペースをアンダースコアに置換
-	result := strings.ReplaceAll(id, ",", "_")
-	result = strings.ReplaceAll(result, " ", "_")
-
-	// その他の特殊文字も安全な文字に置換
-	result = strings.ReplaceAll(result, "-", "_")
-	result = strings.ReplaceAll(result, ".", "_")
-
-	return result
-}
-
-// getDotNodeLabel ノードのDOT表示名を生成
-func getDotNodeLabel(node *core.Node) string {
-	resources := (*node).GetResourcesString()
-
-	return strings.Join(resources, "\\n")
+	return quoteDot((*node).GetID())
 }
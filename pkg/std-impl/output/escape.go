@@ -0,0 +1,193 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// nodeIDs 出力形式で使えるノードIDの対応表
+// 英数字とアンダースコアに置き換えたIDが衝突する場合（a-bとa.bなど）は、後から割り当てたノードに連番を付けて区別する。
+// 割り当ての順序を固定するため、開始ノード、ノードID順の順に割り当てる。
+// グループのID（group_<番号>）とも衝突しないようにする。
+type nodeIDs struct {
+	sanitize func(id string) string
+	ids      map[string]string // ノードID -> 出力用のID
+	used     map[string]bool   // 割り当て済みの出力用のID
+}
+
+// newNodeIDs ジェネレーターのすべてのノードに出力用のIDを割り当てる
+func newNodeIDs(generator *core.Generator, sanitize func(id string) string) *nodeIDs {
+	n := &nodeIDs{
+		sanitize: sanitize,
+		ids:      make(map[string]string),
+		used:     make(map[string]bool),
+	}
+	if startNode := generator.GetStartNode(); startNode != nil {
+		n.get(startNode)
+	}
+	for _, node := range generator.GetNodes() {
+		n.get(node)
+	}
+	return n
+}
+
+// get ノードの出力用のIDを取得（未割り当ての場合は割り当てる）
+func (n *nodeIDs) get(node *core.Node) string {
+	id := (*node).GetID()
+	if safe, exists := n.ids[id]; exists {
+		return safe
+	}
+	base := n.sanitize(id)
+	safe := base
+	for suffix := 2; n.used[safe] || isGroupID(safe); suffix++ {
+		safe = fmt.Sprintf("%s_%d", base, suffix)
+	}
+	n.ids[id] = safe
+	n.used[safe] = true
+	return safe
+}
+
+// isGroupID グループ（subgraph, cluster, 複合状態）のIDとして予約した「group_<番号>」の形式かどうか
+func isGroupID(id string) bool {
+	number, found := strings.CutPrefix(id, "group_")
+	if !found || number == "" {
+		return false
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeIdentifier 英数字とアンダースコア以外の文字をアンダースコアに置き換える
+func sanitizeIdentifier(id string) string {
+	var safe strings.Builder
+	for _, r := range id {
+		if r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			safe.WriteRune(r)
+		} else {
+			safe.WriteRune('_')
+		}
+	}
+	return safe.String()
+}
+
+// mermaidReservedIDs Mermaidの構文と衝突するため、ノードIDとして使えない語
+var mermaidReservedIDs = map[string]bool{
+	"end": true, "graph": true, "flowchart": true, "subgraph": true, "direction": true,
+	"style": true, "class": true, "classDef": true, "click": true, "linkStyle": true, "default": true,
+}
+
+// sanitizeMermaidID ノードIDをMermaidのフローチャートで使えるIDに変換
+func sanitizeMermaidID(id string) string {
+	if id == "" {
+		return "empty"
+	}
+	safe := sanitizeIdentifier(id)
+	if mermaidReservedIDs[safe] {
+		safe += "_"
+	}
+	return safe
+}
+
+// sanitizeStateID ノードIDを状態遷移図（PlantUML, Mermaid stateDiagram）の状態の別名に変換
+func sanitizeStateID(id string) string {
+	return "s_" + sanitizeIdentifier(id)
+}
+
+// mermaidEscaper Mermaidのラベル中で構文と衝突する文字を実体参照に置き換える
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	"\"", "#quot;",
+	"|", "#124;",
+	"<", "#lt;",
+	">", "#gt;",
+	"[", "#91;",
+	"]", "#93;",
+	"{", "#123;",
+	"}", "#125;",
+	"(", "#40;",
+	")", "#41;",
+	";", "#59;",
+	"`", "#96;",
+	"\r\n", "<br/>",
+	"\n", "<br/>",
+	"\r", "<br/>",
+)
+
+// escapeMermaid Mermaidのラベルに使えるように文字列をエスケープ
+func escapeMermaid(text string) string {
+	return mermaidEscaper.Replace(text)
+}
+
+// escapeMermaidLines 複数行をエスケープして<br/>でつなぐ
+func escapeMermaidLines(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = escapeMermaid(line)
+	}
+	return strings.Join(escaped, "<br/>")
+}
+
+// escapeMermaidLine 改行を含まない1行として使えるようにエスケープ（状態の説明やノート、遷移のラベル）
+func escapeMermaidLine(text string) string {
+	return escapeMermaid(strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(text))
+}
+
+// dotEscaper DOTの二重引用符で囲んだ文字列の中で特別な意味を持つ文字をエスケープ
+var dotEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+	"\r\n", "\\n",
+	"\n", "\\n",
+	"\r", "\\n",
+)
+
+// quoteDot DOTの二重引用符で囲んだ文字列（IDやラベル）を作成
+func quoteDot(text string) string {
+	return "\"" + dotEscaper.Replace(text) + "\""
+}
+
+// quoteDotLines 複数行をエスケープして改行（\n）でつなぎ、二重引用符で囲む
+func quoteDotLines(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = dotEscaper.Replace(line)
+	}
+	return "\"" + strings.Join(escaped, "\\n") + "\""
+}
+
+// plantUMLEscaper PlantUMLの二重引用符で囲んだ表示名の中で使えない文字を置き換える
+// 表示名の中の二重引用符はエスケープできず、<と>は書式のタグとして解釈されるため、Unicodeの文字参照を使う
+var plantUMLEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "<U+0022>",
+	"<", "<U+003C>",
+	">", "<U+003E>",
+	"\r\n", "\\n",
+	"\n", "\\n",
+	"\r", "\\n",
+)
+
+// escapePlantUML PlantUMLの表示名に使えるように文字列をエスケープ
+func escapePlantUML(text string) string {
+	return plantUMLEscaper.Replace(text)
+}
+
+// escapePlantUMLLines 複数行をエスケープして改行（\n）でつなぐ
+func escapePlantUMLLines(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = escapePlantUML(line)
+	}
+	return strings.Join(escaped, "\\n")
+}
+
+// escapePlantUMLLine 改行を含まない1行として使えるようにエスケープ（遷移のラベル）
+func escapePlantUMLLine(text string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "<", "<U+003C>", ">", "<U+003E>").Replace(text)
+}
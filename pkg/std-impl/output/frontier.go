@@ -0,0 +1,27 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// frontierMarker 展開されなかったノードの表示名の先頭に付ける印
+const frontierMarker = "…"
+
+// partialComment グラフが不完全な場合に出力の先頭へコメントとして書く説明（完全な場合は空文字）
+func partialComment(generator *core.Generator) string {
+	frontier := generator.GetFrontierNodes()
+	if len(frontier) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("partial: 反復回数や深さの上限、または中断により探索を打ち切りました。%sの付いた%d個の状態は遷移先を調べていないため、デッドロックとは限りません", frontierMarker, len(frontier))
+}
+
+// withFrontierMarker 展開されなかったノードの場合、表示名の先頭に印を付ける
+func withFrontierMarker(generator *core.Generator, node *core.Node, lines []string) []string {
+	if !generator.IsFrontier(node) {
+		return lines
+	}
+	return append([]string{frontierMarker}, lines...)
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// GoTestFormatter 全遷移を網羅するテストケースをテーブル駆動のGoテストの雛形として出力するフォーマッター
+type GoTestFormatter struct {
+	packageName string
+}
+
+// NewGoTestFormatter 新しいGoTestFormatterを作成
+func NewGoTestFormatter(packageName string) *GoTestFormatter {
+	return &GoTestFormatter{packageName: packageName}
+}
+
+// Format 全遷移を網羅するテストケースをGoの_test.goとして出力
+func (f *GoTestFormatter) Format(generator *core.Generator) (string, error) {
+	var src strings.Builder
+	src.WriteString("// Code generated by blindspot testgen. 雛形のため、TODOを実装して使用してください。\n")
+	if comment := partialComment(generator); comment != "" {
+		src.WriteString("// " + comment + "\n")
+	}
+	src.WriteString("\n")
+	src.WriteString(fmt.Sprintf("package %s\n\n", f.packageName))
+	src.WriteString("import \"testing\"\n\n")
+	src.WriteString("func TestTransitions(t *testing.T) {\n")
+	src.WriteString("type step struct {\nrule string\nresources []string\n}\n")
+	src.WriteString("tests := []struct {\nname string\nstart []string\nsteps []step\n}{\n")
+
+	for _, testCase := range buildTestCases(generator) {
+		src.WriteString("{\n")
+		src.WriteString(fmt.Sprintf("name: %q,\n", testCase.Name))
+		src.WriteString(fmt.Sprintf("start: %s,\n", goStringSlice(testCase.Start)))
+		src.WriteString("steps: []step{\n")
+		for _, step := range testCase.Steps {
+			src.WriteString(fmt.Sprintf("{rule: %q, resources: %s},\n", step.Rule, goStringSlice(step.Resources)))
+		}
+		src.WriteString("},\n")
+		src.WriteString("},\n")
+	}
+
+	src.WriteString("}\n\n")
+	src.WriteString("for _, tt := range tests {\n")
+	src.WriteString("t.Run(tt.name, func(t *testing.T) {\n")
+	src.WriteString("// TODO: tt.start の状態をセットアップする\n")
+	src.WriteString("for _, s := range tt.steps {\n")
+	src.WriteString("// TODO: s.rule を実行し、リソースが s.resources と一致することを検証する\n")
+	src.WriteString("_ = s\n")
+	src.WriteString("}\n")
+	src.WriteString("})\n")
+	src.WriteString("}\n")
+	src.WriteString("}\n")
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format generated test: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// goStringSlice 文字列スライスをGoのリテラルとして表現
+func goStringSlice(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", value))
+	}
+	return fmt.Sprintf("[]string{%s}", strings.Join(quoted, ", "))
+}
@@ -0,0 +1,29 @@
+package output
+
+import "github.com/yuukiiwai/blindspot/pkg/core"
+
+// groupWriter グループの木を出力形式ごとの書式で書き出すための関数群
+type groupWriter struct {
+	node  func(indent string, node *core.Node)        // ノード1つを書き出す
+	open  func(indent string, index int, name string) // グループの開始を書き出す（indexは出力全体で一意な連番）
+	close func(indent string)                         // グループの終了を書き出す
+}
+
+// write グループの木を深さ優先で辿って書き出す
+// 各グループでは直接属するノードを先に、入れ子のグループを後に書き出し、階層ごとにindentStepずつ字下げする
+func (w groupWriter) write(group *core.NodeGroup, indent string, indentStep string) {
+	index := 0
+	w.writeGroup(group, indent, indentStep, &index)
+}
+
+func (w groupWriter) writeGroup(group *core.NodeGroup, indent string, indentStep string, index *int) {
+	for _, node := range group.Nodes {
+		w.node(indent, node)
+	}
+	for _, child := range group.Children {
+		*index++
+		w.open(indent, *index, child.Name)
+		w.writeGroup(child, indent+indentStep, indentStep, index)
+		w.close(indent)
+	}
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// GraphDocument ステートマシンのJSON表現
+type GraphDocument struct {
+	Partial bool           `json:"partial,omitempty"` // 反復回数や深さの上限、または中断により探索を打ち切った場合はtrue
+	Start   string         `json:"start"`
+	Nodes   []GraphDocNode `json:"nodes"`
+	Edges   []GraphDocEdge `json:"edges"`
+}
+
+// GraphDocNode ノードのJSON表現
+type GraphDocNode struct {
+	ID        string   `json:"id"`
+	Resources []string `json:"resources"`
+	Frontier  bool     `json:"frontier,omitempty"` // 発見されたが展開されなかった（遷移先を調べていない）ノード
+	Depth     int      `json:"depth"`              // 開始ノードからの深さ（遷移数）
+}
+
+// GraphDocEdge エッジのJSON表現
+type GraphDocEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Rule string `json:"rule"`
+}
+
+// NewGraphDocument ジェネレーターからJSON表現を作成
+func NewGraphDocument(generator *core.Generator) *GraphDocument {
+	document := &GraphDocument{
+		Partial: generator.IsPartial(),
+		Nodes:   []GraphDocNode{},
+		Edges:   []GraphDocEdge{},
+	}
+	if startNode := generator.GetStartNode(); startNode != nil {
+		document.Start = (*startNode).GetID()
+	}
+	for _, node := range generator.GetNodes() {
+		depth, _ := generator.GetDepth(node)
+		document.Nodes = append(document.Nodes, GraphDocNode{
+			ID:        (*node).GetID(),
+			Resources: (*node).GetResourcesString(),
+			Frontier:  generator.IsFrontier(node),
+			Depth:     depth,
+		})
+	}
+	for _, edge := range generator.GetEdges() {
+		document.Edges = append(document.Edges, GraphDocEdge{
+			From: (*edge.GetFrom()).GetID(),
+			To:   (*edge.GetTo()).GetID(),
+			Rule: edge.GetLabel(),
+		})
+	}
+	return document
+}
+
+// JsonFormatter JSON形式の出力フォーマッター
+type JsonFormatter struct{}
+
+// NewJsonFormatter 新しいJsonFormatterを作成
+func NewJsonFormatter() *JsonFormatter {
+	return &JsonFormatter{}
+}
+
+// Format ステートマシンをJSON形式で出力
+func (f *JsonFormatter) Format(generator *core.Generator) (string, error) {
+	marshaled, err := json.MarshalIndent(NewGraphDocument(generator), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal graph: %w", err)
+	}
+	return string(marshaled), nil
+}
@@ -19,68 +19,43 @@ func NewMermaidFormatter() *MermaidFormatter {
 func (f *MermaidFormatter) Format(generator *core.Generator) (string, error) {
 	var mermaid strings.Builder
 	mermaid.WriteString("graph TD\n")
-
-	// ノードの出力（開始ノードを最初に出力）
-	startNode := generator.GetStartNode()
-	if startNode != nil {
-		nodeID := getMermaidNodeID(startNode)
-		label := getMermaidNodeLabel(startNode)
-		mermaid.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", nodeID, label))
-	}
-
-	// 開始ノード以外のノードを出力
-	for _, node := range generator.GetNodes() {
-		// 開始ノードは既に出力済みなのでスキップ
-		if startNode != nil && (*node).GetID() == (*startNode).GetID() {
-			continue
-		}
-		nodeID := getMermaidNodeID(node)
-		label := getMermaidNodeLabel(node)
-		mermaid.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", nodeID, label))
+	if comment := partialComment(generator); comment != "" {
+		mermaid.WriteString("    %% " + comment + "\n")
 	}
+	ids := newNodeIDs(generator, sanitizeMermaidID)
+
+	// ノードの出力（開始ノードを最初に出力し、グループ分けがある場合はsubgraphで入れ子にする）
+	groupWriter{
+		node: func(indent string, node *core.Node) {
+			mermaid.WriteString(fmt.Sprintf("%s%s[\"%s\"]\n", indent, ids.get(node), escapeMermaidLines(withFrontierMarker(generator, node, (*node).GetResourcesString()))))
+		},
+		open: func(indent string, index int, name string) {
+			mermaid.WriteString(fmt.Sprintf("%ssubgraph group_%d[\"%s\"]\n", indent, index, escapeMermaidLine(name)))
+		},
+		close: func(indent string) {
+			mermaid.WriteString(indent + "end\n")
+		},
+	}.write(generator.GetNodeGroupTree(), "    ", "    ")
 
 	mermaid.WriteString("\n")
 
 	// エッジの出力
 	for _, edge := range generator.GetEdges() {
-		fromID := getMermaidNodeID(edge.GetFrom())
-		toID := getMermaidNodeID(edge.GetTo())
-		edgeLabel := edge.GetRule().GetName()
+		fromID := ids.get(edge.GetFrom())
+		toID := ids.get(edge.GetTo())
+		edgeLabel := escapeMermaidLine(edge.GetLabel())
 		mermaid.WriteString(fmt.Sprintf("    %s -->|%s| %s\n", fromID, edgeLabel, toID))
 	}
 
-	return mermaid.String(), nil
-}
-
-// getMermaidNodeID ノードのMermaid IDを生成
-func getMermaidNodeID(node *core.Node) string {
-	id := (*node).GetID()
-
-	// 明示的にemptyの場合
-	if id == "empty" {
-		return "empty"
-	}
-
-	// 空文字列の場合（これは起こるべきではないが、安全のため）
-	if id == "" {
-		return "empty"
+	// 展開されなかったノードは破線の枠で表示する
+	if frontier := generator.GetFrontierNodes(); len(frontier) > 0 {
+		frontierIDs := make([]string, len(frontier))
+		for i, node := range frontier {
+			frontierIDs[i] = ids.get(node)
+		}
+		mermaid.WriteString("\n    classDef frontier stroke-dasharray: 5 5\n")
+		mermaid.WriteString(fmt.Sprintf("    class %s frontier\n", strings.Join(frontierIDs, ",")))
 	}
 
-	// 通常のIDをMermaidで使える形式に変換
-	// カンマをアンダースコアに、スペースをアンダースコアに置換
-	result := strings.ReplaceAll(id, ",", "_")
-	result = strings.ReplaceAll(result, " ", "_")
-
-	// その他の特殊文字も安全な文字に置換
-	result = strings.ReplaceAll(result, "-", "_")
-	result = strings.ReplaceAll(result, ".", "_")
-
-	return result
-}
-
-// getMermaidNodeLabel ノードのMermaid表示名を生成
-func getMermaidNodeLabel(node *core.Node) string {
-	resources := (*node).GetResourcesString()
-
-	return strings.Join(resources, "<br/>")
+	return mermaid.String(), nil
 }
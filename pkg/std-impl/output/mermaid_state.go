@@ -0,0 +1,92 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// MermaidStateFormatter Mermaidの状態遷移図（stateDiagram-v2）の出力フォーマッター
+type MermaidStateFormatter struct {
+	// notes trueの場合はリソースを状態の説明ではなくノートとして出力する
+	notes bool
+}
+
+// NewMermaidStateFormatter 新しいMermaidStateFormatterを作成
+// notesがtrueの場合、リソースを各状態の横のノートとして出力する
+func NewMermaidStateFormatter(notes bool) *MermaidStateFormatter {
+	return &MermaidStateFormatter{notes: notes}
+}
+
+// Format ステートマシンをMermaidのstateDiagram-v2で出力
+// 状態はS1, S2, ...と番号を振って表示し、リソースは状態の説明（またはノート）として出力する。
+// 開始状態は[*]からの遷移、デッドロックの状態は[*]への遷移で表す。
+// 反復回数や深さの上限により展開されなかった状態は、名前に…を付けて破線の枠で表示する。
+func (f *MermaidStateFormatter) Format(generator *core.Generator) (string, error) {
+	var mermaid strings.Builder
+	mermaid.WriteString("stateDiagram-v2\n")
+	if comment := partialComment(generator); comment != "" {
+		mermaid.WriteString("    %% " + comment + "\n")
+	}
+
+	tree := generator.GetNodeGroupTree()
+	ids := newNodeIDs(generator, sanitizeStateID)
+	var notes strings.Builder
+	number := 0
+	groupWriter{
+		node: func(indent string, node *core.Node) {
+			number++
+			id := ids.get(node)
+			name := fmt.Sprintf("S%d", number)
+			if generator.IsFrontier(node) {
+				name += " " + frontierMarker
+			}
+			mermaid.WriteString(fmt.Sprintf("%sstate \"%s\" as %s\n", indent, name, id))
+			if f.notes {
+				// ノートは複合状態の中に置けないため、最後にまとめて出力する
+				notes.WriteString(fmt.Sprintf("    note right of %s\n", id))
+				for _, resource := range (*node).GetResourcesString() {
+					notes.WriteString(fmt.Sprintf("        %s\n", escapeMermaidLine(resource)))
+				}
+				notes.WriteString("    end note\n")
+				return
+			}
+			for _, resource := range (*node).GetResourcesString() {
+				mermaid.WriteString(fmt.Sprintf("%s%s : %s\n", indent, id, escapeMermaidLine(resource)))
+			}
+		},
+		open: func(indent string, index int, name string) {
+			mermaid.WriteString(fmt.Sprintf("%sstate \"%s\" as group_%d\n", indent, escapeMermaidLine(name), index))
+			mermaid.WriteString(fmt.Sprintf("%sstate group_%d {\n", indent, index))
+		},
+		close: func(indent string) {
+			mermaid.WriteString(indent + "}\n")
+		},
+	}.write(tree, "    ", "    ")
+	mermaid.WriteString(notes.String())
+
+	mermaid.WriteString("\n")
+
+	if startNode := generator.GetStartNode(); startNode != nil {
+		mermaid.WriteString(fmt.Sprintf("    [*] --> %s\n", ids.get(startNode)))
+	}
+	for _, edge := range generator.GetEdges() {
+		mermaid.WriteString(fmt.Sprintf("    %s --> %s : %s\n", ids.get(edge.GetFrom()), ids.get(edge.GetTo()), escapeMermaidLine(edge.GetLabel())))
+	}
+	for _, node := range generator.GetDeadlockNodes() {
+		mermaid.WriteString(fmt.Sprintf("    %s --> [*]\n", ids.get(node)))
+	}
+
+	// 展開されなかったノードは破線の枠で表示する（デッドロックではないため[*]へは遷移させない）
+	if frontier := generator.GetFrontierNodes(); len(frontier) > 0 {
+		frontierIDs := make([]string, len(frontier))
+		for i, node := range frontier {
+			frontierIDs[i] = ids.get(node)
+		}
+		mermaid.WriteString("\n    classDef frontier stroke-dasharray: 5 5\n")
+		mermaid.WriteString(fmt.Sprintf("    class %s frontier\n", strings.Join(frontierIDs, ",")))
+	}
+
+	return mermaid.String(), nil
+}
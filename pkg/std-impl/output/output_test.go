@@ -0,0 +1,161 @@
+package output
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// hostileNode 出力形式の構文と衝突する文字を含むノード
+type hostileNode struct {
+	id    string
+	lines []string
+}
+
+func (n hostileNode) GetID() string                { return n.id }
+func (n hostileNode) Equals(other core.Node) bool  { return n.id == other.GetID() }
+func (n hostileNode) GetResources() any            { return n.id }
+func (n hostileNode) GetResourcesString() []string { return n.lines }
+
+// hostileNodes IDが衝突しやすいノードと、構文を壊しやすいリソースの組み合わせ
+var hostileNodes = map[string][]string{
+	"start":   {`status:"new"`, `note:"a|b"`},
+	"a-b":     {`label:"<script>alert(1)</script>"`},
+	"a.b":     {`brackets:"[x] {y} (z)"`},
+	"a_b":     {`hash:"#1; done"`, "multi:\"line1\nline2\""},
+	"end":     {`path:"C:\\temp\\new"`},
+	"group_1": {"backtick:\"`code`\""},
+	"":        {"empty"},
+}
+
+func newHostileNode(resources any) core.Node {
+	id := resources.(string)
+	return hostileNode{id: id, lines: hostileNodes[id]}
+}
+
+// newHostileGenerator startから各ノードへ、構文を壊しやすい名前のルールで遷移するグラフを生成
+func newHostileGenerator(t *testing.T) *core.Generator {
+	t.Helper()
+	var rules []*core.EdgeRule
+	for _, to := range []string{"a-b", "a.b", "a_b", "end", "group_1", ""} {
+		target := to
+		rule, err := core.NewEdgeRule(
+			`go|to"`+target+`"; [next]`,
+			func(n *core.Node) *core.Node {
+				next := newHostileNode(target)
+				return &next
+			},
+			func(n *core.Node) bool { return (*n).GetID() == "start" },
+			func(n *core.Node) bool { return false },
+		)
+		if err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	generator := core.NewGenerator(newHostileNode, newHostileNode("start"), rules, nil)
+	generator.SetNodeGrouper(func(n *core.Node) []string {
+		if strings.HasPrefix((*n).GetID(), "a") {
+			return []string{`kind: "a"|<b>`}
+		}
+		return nil
+	})
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	return generator
+}
+
+// newPartialGenerator start→a-b→a.b→a_bと遷移するグラフを、a.bを展開する前に反復回数の上限で打ち切って生成
+func newPartialGenerator(t *testing.T) *core.Generator {
+	t.Helper()
+	next := map[string]string{"start": "a-b", "a-b": "a.b", "a.b": "a_b"}
+	rule, err := core.NewEdgeRule(
+		"next",
+		func(n *core.Node) *core.Node {
+			to := newHostileNode(next[(*n).GetID()])
+			return &to
+		},
+		func(n *core.Node) bool { _, exists := next[(*n).GetID()]; return exists },
+		func(n *core.Node) bool { return false },
+	)
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	limit := int64(2)
+	generator := core.NewGenerator(newHostileNode, newHostileNode("start"), []*core.EdgeRule{rule}, &limit)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	return generator
+}
+
+func TestFormattersGolden(t *testing.T) {
+	testFormattersGolden(t, "hostile", newHostileGenerator(t))
+}
+
+func TestFormattersGoldenPartial(t *testing.T) {
+	generator := newPartialGenerator(t)
+	if !generator.IsPartial() {
+		t.Fatal("expected the graph to be partial")
+	}
+	testFormattersGolden(t, "partial", generator)
+}
+
+// testFormattersGolden すべてのフォーマッターの出力をtestdata/<prefix>.<形式>.goldenと比較
+func testFormattersGolden(t *testing.T, prefix string, generator *core.Generator) {
+	formatters := map[string]core.Formatter{
+		"mermaid":             NewMermaidFormatter(),
+		"mermaid-state":       NewMermaidStateFormatter(false),
+		"mermaid-state-notes": NewMermaidStateFormatter(true),
+		"dot":                 NewDotFormatter(),
+		"plantuml":            NewPlantUMLFormatter(),
+		"json":                NewJsonFormatter(),
+		"visjs":               NewVisjsFormatter(),
+	}
+	for name, formatter := range formatters {
+		t.Run(name, func(t *testing.T) {
+			result, err := formatter.Format(generator)
+			if err != nil {
+				t.Fatalf("failed to format: %v", err)
+			}
+			golden := filepath.Join("testdata", prefix+"."+name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(result), 0o644); err != nil {
+					t.Fatalf("failed to update golden file: %v", err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create): %v", err)
+			}
+			if result != string(expected) {
+				t.Errorf("output does not match %s\n--- got ---\n%s\n--- expected ---\n%s", golden, result, expected)
+			}
+		})
+	}
+}
+
+func TestNodeIDsAreCollisionFree(t *testing.T) {
+	generator := newHostileGenerator(t)
+	for name, sanitize := range map[string]func(string) string{"mermaid": sanitizeMermaidID, "state": sanitizeStateID} {
+		ids := newNodeIDs(generator, sanitize)
+		seen := make(map[string]string)
+		for _, node := range generator.GetNodes() {
+			id := ids.get(node)
+			if other, exists := seen[id]; exists {
+				t.Errorf("%s: nodes %q and %q share the id %q", name, other, (*node).GetID(), id)
+			}
+			if isGroupID(id) || (name == "mermaid" && mermaidReservedIDs[id]) {
+				t.Errorf("%s: node %q got a reserved id %q", name, (*node).GetID(), id)
+			}
+			seen[id] = (*node).GetID()
+		}
+	}
+}
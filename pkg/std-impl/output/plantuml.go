@@ -0,0 +1,57 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// PlantUMLFormatter PlantUMLの状態遷移図の出力フォーマッター
+type PlantUMLFormatter struct{}
+
+// NewPlantUMLFormatter 新しいPlantUMLFormatterを作成
+func NewPlantUMLFormatter() *PlantUMLFormatter {
+	return &PlantUMLFormatter{}
+}
+
+// Format ステートマシンをPlantUMLの状態遷移図で出力
+// グループ分けがある場合は入れ子の状態（複合状態）として出力する
+func (f *PlantUMLFormatter) Format(generator *core.Generator) (string, error) {
+	var plantuml strings.Builder
+	plantuml.WriteString("@startuml\n")
+	if comment := partialComment(generator); comment != "" {
+		plantuml.WriteString("' " + comment + "\n")
+	}
+	plantuml.WriteString("hide empty description\n\n")
+	ids := newNodeIDs(generator, sanitizeStateID)
+
+	groupWriter{
+		node: func(indent string, node *core.Node) {
+			// 展開されなかったノードは破線の枠で表示する
+			style := ""
+			if generator.IsFrontier(node) {
+				style = " ##[dashed]"
+			}
+			plantuml.WriteString(fmt.Sprintf("%sstate \"%s\" as %s%s\n", indent, escapePlantUMLLines(withFrontierMarker(generator, node, (*node).GetResourcesString())), ids.get(node), style))
+		},
+		open: func(indent string, index int, name string) {
+			plantuml.WriteString(fmt.Sprintf("%sstate \"%s\" as group_%d {\n", indent, escapePlantUML(name), index))
+		},
+		close: func(indent string) {
+			plantuml.WriteString(indent + "}\n")
+		},
+	}.write(generator.GetNodeGroupTree(), "", "  ")
+
+	plantuml.WriteString("\n")
+
+	if startNode := generator.GetStartNode(); startNode != nil {
+		plantuml.WriteString(fmt.Sprintf("[*] --> %s\n", ids.get(startNode)))
+	}
+	for _, edge := range generator.GetEdges() {
+		plantuml.WriteString(fmt.Sprintf("%s --> %s : %s\n", ids.get(edge.GetFrom()), ids.get(edge.GetTo()), escapePlantUMLLine(edge.GetLabel())))
+	}
+
+	plantuml.WriteString("@enduml\n")
+	return plantuml.String(), nil
+}
@@ -0,0 +1,55 @@
+package output
+
+import "github.com/yuukiiwai/blindspot/pkg/core"
+
+func init() {
+	registrations := []core.FormatterRegistration{
+		{
+			Name:        "mermaid",
+			Description: "Mermaidのフローチャート",
+			Extensions:  []string{".mmd", ".md"},
+			New:         func() (core.Formatter, error) { return NewMermaidFormatter(), nil },
+		},
+		{
+			Name:        "mermaid-state",
+			Description: "Mermaidの状態遷移図（stateDiagram-v2、リソースを状態の説明として表示）",
+			Extensions:  []string{},
+			New:         func() (core.Formatter, error) { return NewMermaidStateFormatter(false), nil },
+		},
+		{
+			Name:        "mermaid-state-notes",
+			Description: "Mermaidの状態遷移図（stateDiagram-v2、リソースをノートとして表示）",
+			Extensions:  []string{},
+			New:         func() (core.Formatter, error) { return NewMermaidStateFormatter(true), nil },
+		},
+		{
+			Name:        "visjs",
+			Description: "Vis.js（vis-network）で表示するHTML",
+			Extensions:  []string{".html"},
+			New:         func() (core.Formatter, error) { return NewVisjsFormatter(), nil },
+		},
+		{
+			Name:        "dot",
+			Description: "GraphvizのDOT形式",
+			Extensions:  []string{".dot", ".gv"},
+			New:         func() (core.Formatter, error) { return NewDotFormatter(), nil },
+		},
+		{
+			Name:        "plantuml",
+			Description: "PlantUMLの状態遷移図",
+			Extensions:  []string{".puml", ".plantuml"},
+			New:         func() (core.Formatter, error) { return NewPlantUMLFormatter(), nil },
+		},
+		{
+			Name:        "json",
+			Description: "ノードとエッジのJSON",
+			Extensions:  []string{".json"},
+			New:         func() (core.Formatter, error) { return NewJsonFormatter(), nil },
+		},
+	}
+	for _, registration := range registrations {
+		if err := core.RegisterFormatter(registration); err != nil {
+			panic(err)
+		}
+	}
+}
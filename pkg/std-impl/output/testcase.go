@@ -0,0 +1,73 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// TestCase 全遷移を網羅するパス1本分のテストケース
+type TestCase struct {
+	Name  string         `json:"name"`
+	Start []string       `json:"start"`
+	Steps []TestCaseStep `json:"steps"`
+}
+
+// TestCaseStep テストケースの1ステップ（適用するルールと適用後の期待リソース）
+type TestCaseStep struct {
+	Rule      string   `json:"rule"`
+	Resources []string `json:"resources"`
+}
+
+// buildTestCases ジェネレーターから全遷移を網羅するテストケースを構築
+func buildTestCases(generator *core.Generator) []TestCase {
+	startNode := generator.GetStartNode()
+	if startNode == nil {
+		return nil
+	}
+
+	paths := core.CoverAllTransitions(generator)
+	cases := make([]TestCase, 0, len(paths))
+	for i, path := range paths {
+		steps := make([]TestCaseStep, 0, len(path))
+		for _, edge := range path {
+			steps = append(steps, TestCaseStep{
+				Rule:      edge.GetLabel(),
+				Resources: (*edge.GetTo()).GetResourcesString(),
+			})
+		}
+		cases = append(cases, TestCase{
+			Name:  fmt.Sprintf("path_%d", i+1),
+			Start: (*startNode).GetResourcesString(),
+			Steps: steps,
+		})
+	}
+	return cases
+}
+
+// TestCaseJsonFormatter 全遷移を網羅するテストケースをJSON形式で出力するフォーマッター
+type TestCaseJsonFormatter struct{}
+
+// NewTestCaseJsonFormatter 新しいTestCaseJsonFormatterを作成
+func NewTestCaseJsonFormatter() *TestCaseJsonFormatter {
+	return &TestCaseJsonFormatter{}
+}
+
+// Format 全遷移を網羅するテストケースをJSON形式で出力
+func (f *TestCaseJsonFormatter) Format(generator *core.Generator) (string, error) {
+	document := struct {
+		Cases []TestCase `json:"cases"`
+	}{
+		Cases: buildTestCases(generator),
+	}
+	if document.Cases == nil {
+		document.Cases = []TestCase{}
+	}
+
+	marshaled, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal test cases: %w", err)
+	}
+	return string(marshaled) + "\n", nil
+}
@@ -1,12 +1,19 @@
 package output
 
 import (
-	"errors"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
 )
 
+// visjsScriptURL 出力するHTMLが読み込むVis.js（vis-network）のスクリプト
+const visjsScriptURL = "https://unpkg.com/vis-network@9.1.9/standalone/umd/vis-network.min.js"
+
 // VisjsFormatter Vis.js形式の出力フォーマッター
+// vis-networkでステートマシンを表示する、単体で開けるHTMLを出力する
 type VisjsFormatter struct{}
 
 // NewVisjsFormatter 新しいVisjsFormatterを作成
@@ -14,8 +21,100 @@ func NewVisjsFormatter() *VisjsFormatter {
 	return &VisjsFormatter{}
 }
 
+// visjsNode vis-networkのノード
+type visjsNode struct {
+	ID              string                `json:"id"`
+	Label           string                `json:"label"`
+	Group           string                `json:"group,omitempty"`           // グループ分けがある場合は「親/子」の形式のグループ名
+	Color           *visjsColor           `json:"color,omitempty"`           // 開始ノードのみ色を変える
+	ShapeProperties *visjsShapeProperties `json:"shapeProperties,omitempty"` // 展開されなかったノードは破線の枠で表示する
+}
+
+type visjsColor struct {
+	Border string `json:"border"`
+}
+
+type visjsShapeProperties struct {
+	BorderDashes bool `json:"borderDashes"`
+}
+
+// visjsEdge vis-networkのエッジ
+type visjsEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label"`
+}
+
 // Format ステートマシンをVis.js形式で出力
+// ノードIDやラベルはJSONとして埋め込むため、エスケープ（<, >, &を含む）はencoding/jsonに任せる
 func (f *VisjsFormatter) Format(generator *core.Generator) (string, error) {
-	// TODO: Implement
-	return "", errors.New("not implemented")
+	nodes := []visjsNode{}
+	var path []string
+	groupWriter{
+		node: func(_ string, node *core.Node) {
+			visNode := visjsNode{
+				ID:    (*node).GetID(),
+				Label: strings.Join(withFrontierMarker(generator, node, (*node).GetResourcesString()), "\n"),
+				Group: strings.Join(path, "/"),
+			}
+			if startNode := generator.GetStartNode(); startNode != nil && (*startNode).GetID() == (*node).GetID() {
+				visNode.Color = &visjsColor{Border: "#d62728"}
+			}
+			if generator.IsFrontier(node) {
+				visNode.ShapeProperties = &visjsShapeProperties{BorderDashes: true}
+			}
+			nodes = append(nodes, visNode)
+		},
+		open: func(_ string, _ int, name string) {
+			path = append(path, name)
+		},
+		close: func(_ string) {
+			path = path[:len(path)-1]
+		},
+	}.write(generator.GetNodeGroupTree(), "", "")
+
+	edges := []visjsEdge{}
+	for _, edge := range generator.GetEdges() {
+		edges = append(edges, visjsEdge{
+			From:  (*edge.GetFrom()).GetID(),
+			To:    (*edge.GetTo()).GetID(),
+			Label: edge.GetLabel(),
+		})
+	}
+
+	nodesJSON, err := json.MarshalIndent(nodes, "    ", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal nodes: %w", err)
+	}
+	edgesJSON, err := json.MarshalIndent(edges, "    ", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal edges: %w", err)
+	}
+
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n")
+	page.WriteString("<html>\n<head>\n")
+	page.WriteString("  <meta charset=\"utf-8\">\n")
+	page.WriteString("  <title>blindspot</title>\n")
+	page.WriteString(fmt.Sprintf("  <script src=%q></script>\n", visjsScriptURL))
+	page.WriteString("  <style>html, body, #graph { width: 100%; height: 100%; margin: 0; }</style>\n")
+	page.WriteString("</head>\n<body>\n")
+	if comment := partialComment(generator); comment != "" {
+		page.WriteString("  <p>" + html.EscapeString(comment) + "</p>\n")
+	}
+	page.WriteString("  <div id=\"graph\"></div>\n")
+	page.WriteString("  <script>\n")
+	page.WriteString("    const nodes = " + string(nodesJSON) + ";\n")
+	page.WriteString("    const edges = " + string(edgesJSON) + ";\n")
+	page.WriteString("    new vis.Network(document.getElementById(\"graph\"), {\n")
+	page.WriteString("      nodes: new vis.DataSet(nodes),\n")
+	page.WriteString("      edges: new vis.DataSet(edges),\n")
+	page.WriteString("    }, {\n")
+	page.WriteString("      nodes: { shape: \"box\" },\n")
+	page.WriteString("      edges: { arrows: \"to\" },\n")
+	page.WriteString("      layout: { hierarchical: { direction: \"LR\", sortMethod: \"directed\" } },\n")
+	page.WriteString("    });\n")
+	page.WriteString("  </script>\n")
+	page.WriteString("</body>\n</html>\n")
+	return page.String(), nil
 }
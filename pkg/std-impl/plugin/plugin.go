@@ -0,0 +1,139 @@
+// Package plugin は外部コマンドとして実装されたパーサー・フォーマッターを
+// 標準入出力のJSONでやり取りして利用する
+//
+// パーサープラグインは標準入力で ParseRequest を受け取り、既存の入力形式へ変換した
+// 結果を ParseResponse として標準出力に書き出す。
+// フォーマッタープラグインは標準入力で FormatRequest を受け取り、出力を
+// FormatResponse として標準出力に書き出す。
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/output"
+)
+
+const (
+	// ParserCommandPrefix パーサープラグインの実行ファイル名の接頭辞（blindspot-parser-<名前>）
+	ParserCommandPrefix = "blindspot-parser-"
+	// FormatterCommandPrefix フォーマッタープラグインの実行ファイル名の接頭辞（blindspot-formatter-<名前>）
+	FormatterCommandPrefix = "blindspot-formatter-"
+)
+
+// ParseRequest パーサープラグインへの入力
+type ParseRequest struct {
+	Content string `json:"content"` // 入力ファイルの内容
+}
+
+// ParseResponse パーサープラグインの出力
+type ParseResponse struct {
+	Format  string `json:"format"`          // 変換後の入力形式（登録済みのパーサー名）
+	Content string `json:"content"`         // 変換後のルール定義
+	Error   string `json:"error,omitempty"` // エラーの場合のメッセージ
+}
+
+// FormatRequest フォーマッタープラグインへの入力
+type FormatRequest struct {
+	Graph *output.GraphDocument `json:"graph"`
+}
+
+// FormatResponse フォーマッタープラグインの出力
+type FormatResponse struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// FindParserCommand PATHから名前に対応するパーサープラグインを探す
+func FindParserCommand(name string) (string, error) {
+	return exec.LookPath(ParserCommandPrefix + name)
+}
+
+// FindFormatterCommand PATHから名前に対応するフォーマッタープラグインを探す
+func FindFormatterCommand(name string) (string, error) {
+	return exec.LookPath(FormatterCommandPrefix + name)
+}
+
+// Parser 外部コマンドで入力を既存の形式へ変換してからパースするパーサー
+type Parser struct {
+	command string
+	args    []string
+	resolve func(format string) (core.Parser, error)
+}
+
+// NewParser 新しいParserを作成
+// resolveには変換後の形式名からパーサーを取得する関数を渡す
+func NewParser(resolve func(format string) (core.Parser, error), command string, args ...string) *Parser {
+	return &Parser{command: command, args: args, resolve: resolve}
+}
+
+// Parse 外部コマンドで入力を変換し、変換後の形式のパーサーでパースする
+func (p *Parser) Parse(input string) (
+	firstResource core.Node,
+	newNode func(any) core.Node,
+	edgeRules []*core.EdgeRule,
+	err error,
+) {
+	var response ParseResponse
+	if err := run(p.command, p.args, ParseRequest{Content: input}, &response); err != nil {
+		return nil, nil, nil, err
+	}
+	if response.Error != "" {
+		return nil, nil, nil, fmt.Errorf("parser plugin %s: %s", p.command, response.Error)
+	}
+
+	parser, err := p.resolve(response.Format)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parser plugin %s returned unsupported format: %w", p.command, err)
+	}
+	return parser.Parse(response.Content)
+}
+
+// Formatter 外部コマンドで出力を生成するフォーマッター
+type Formatter struct {
+	command string
+	args    []string
+}
+
+// NewFormatter 新しいFormatterを作成
+func NewFormatter(command string, args ...string) *Formatter {
+	return &Formatter{command: command, args: args}
+}
+
+// Format ステートマシンのJSON表現を外部コマンドに渡して出力を生成
+func (f *Formatter) Format(generator *core.Generator) (string, error) {
+	var response FormatResponse
+	if err := run(f.command, f.args, FormatRequest{Graph: output.NewGraphDocument(generator)}, &response); err != nil {
+		return "", err
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("formatter plugin %s: %s", f.command, response.Error)
+	}
+	return response.Output, nil
+}
+
+// run 外部コマンドを実行し、requestをJSONで標準入力に渡してresponseへ標準出力をデコードする
+func run(command string, args []string, request any, response any) error {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin request: %w", err)
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run plugin %s: %w", command, err)
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return fmt.Errorf("failed to decode plugin %s response: %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+	_ "github.com/yuukiiwai/blindspot/pkg/std-impl/stringlist"
+)
+
+// TestHelperProcess プラグインとして起動されるテスト用のプロセス
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("BLINDSPOT_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	encoder := json.NewEncoder(os.Stdout)
+	switch mode {
+	case "parser":
+		var request ParseRequest
+		json.NewDecoder(os.Stdin).Decode(&request)
+		// 1行に1つのルール名を書く独自形式を、stringlistのcreateルールへ変換する
+		var rules []string
+		for _, name := range strings.Fields(request.Content) {
+			rules = append(rules, `{"name": "create_`+name+`", "action": "create", "rule": ["`+name+`"], "fire_condition": [], "block_condition": []}`)
+		}
+		encoder.Encode(ParseResponse{
+			Format:  "stringlist",
+			Content: `{"start_resources": [], "edge_rules": [` + strings.Join(rules, ",") + `]}`,
+		})
+	case "formatter":
+		var request FormatRequest
+		json.NewDecoder(os.Stdin).Decode(&request)
+		encoder.Encode(FormatResponse{Output: strings.Repeat("*", len(request.Graph.Nodes))})
+	case "error":
+		encoder.Encode(FormatResponse{Error: "broken"})
+	}
+}
+
+// helperCommand 自身のテストバイナリをプラグインとして起動する
+func helperCommand(t *testing.T, mode string) (string, []string) {
+	t.Setenv("BLINDSPOT_TEST_PLUGIN", mode)
+	return os.Args[0], []string{"-test.run=TestHelperProcess"}
+}
+
+func resolveRegistered(format string) (core.Parser, error) {
+	registration, ok := core.LookupParser(format)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return registration.New()
+}
+
+func TestParserAndFormatter(t *testing.T) {
+	command, args := helperCommand(t, "parser")
+	parser := NewParser(resolveRegistered, command, args...)
+	firstResource, newNode, edgeRules, err := parser.Parse("a b")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if len(edgeRules) != 2 || edgeRules[0].GetName() != "create_a" {
+		t.Fatalf("unexpected rules: %v", edgeRules)
+	}
+
+	limit := int64(100)
+	generator := core.NewGenerator(newNode, firstResource, edgeRules, &limit)
+	if err := generator.Generate(); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	command, args = helperCommand(t, "formatter")
+	result, err := NewFormatter(command, args...).Format(generator)
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	// fire_conditionが空のルールは空の状態でのみ発火するため、empty, a, b の3状態
+	if result != "***" {
+		t.Errorf("expected ***, got %q", result)
+	}
+
+	command, args = helperCommand(t, "error")
+	if _, err := NewFormatter(command, args...).Format(generator); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected plugin error, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// Query ノードのリソースに対して評価するexpr-lang式
+type Query struct {
+	source  string
+	program *vm.Program
+}
+
+// Compile expr-lang式をコンパイルしてQueryを作成
+func Compile(source string) (*Query, error) {
+	program, err := expr.Compile(source, expr.AllowUndefinedVariables())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile query: %s, error: %w", source, err)
+	}
+	return &Query{source: source, program: program}, nil
+}
+
+// String 式の文字列表現を取得
+func (q *Query) String() string {
+	return q.source
+}
+
+// Run ノードのリソースに対して式を評価
+func (q *Query) Run(node *core.Node) (any, error) {
+	result, err := expr.Run(q.program, Env(node))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate query: %s, error: %w", q.source, err)
+	}
+	return result, nil
+}
+
+// Match ノードのリソースに対して式を評価し、真偽値として返す
+func (q *Query) Match(node *core.Node) (bool, error) {
+	result, err := q.Run(node)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("query must return bool: %s, got: %T", q.source, result)
+	}
+	return matched, nil
+}
+
+// Distance ノードのリソースに対して式を評価し、数値として返す（探索の見積もりに使用）
+func (q *Query) Distance(node *core.Node) (float64, error) {
+	result, err := q.Run(node)
+	if err != nil {
+		return 0, err
+	}
+	switch v := result.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("query must return a number: %s, got: %T", q.source, result)
+}
+
+// Conjuncts 式を最上位の && (and) で分割し、それぞれをQueryとしてコンパイルする
+// && を含まない場合は式自体のみを返す
+func (q *Query) Conjuncts() ([]*Query, error) {
+	tree, err := parser.Parse(q.source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %s, error: %w", q.source, err)
+	}
+	var nodes []ast.Node
+	var split func(node ast.Node)
+	split = func(node ast.Node) {
+		if binary, ok := node.(*ast.BinaryNode); ok && (binary.Operator == "&&" || binary.Operator == "and") {
+			split(binary.Left)
+			split(binary.Right)
+			return
+		}
+		nodes = append(nodes, node)
+	}
+	split(tree.Node)
+	if len(nodes) == 1 {
+		return []*Query{q}, nil
+	}
+
+	conjuncts := make([]*Query, 0, len(nodes))
+	for _, node := range nodes {
+		conjunct, err := Compile(node.String())
+		if err != nil {
+			return nil, err
+		}
+		conjuncts = append(conjuncts, conjunct)
+	}
+	return conjuncts, nil
+}
+
+// Identifiers 式が参照する変数の名前を、現れた順に重複なく取得する
+// 式が環境全体（$env）を参照する場合は "$env" を含む
+func Identifiers(source string) ([]string, error) {
+	tree, err := parser.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression: %s, error: %w", source, err)
+	}
+	collector := &identifierCollector{seen: make(map[string]bool)}
+	ast.Walk(&tree.Node, collector)
+	return collector.names, nil
+}
+
+// identifierCollector 式の構文木から変数の名前を集めるast.Visitor
+type identifierCollector struct {
+	names []string
+	seen  map[string]bool
+}
+
+// Visit ast.Visitorの実装
+func (c *identifierCollector) Visit(node *ast.Node) {
+	identifier, ok := (*node).(*ast.IdentifierNode)
+	if !ok || c.seen[identifier.Value] {
+		return
+	}
+	c.seen[identifier.Value] = true
+	c.names = append(c.names, identifier.Value)
+}
+
+// UnmetDistance 目標の式のうち、成り立っていない部分式（&&で分割したもの）の数から、目標までの遷移数の下限を返す関数を作成
+// 1回の遷移で成否が変わる部分式は、ルールが変更するキーを参照するものに限られる。
+// そのため成り立っていない部分式の数を、1つのルールが成否を変えうる部分式の最大数で割って切り上げる（A*で最短のパスが求まる）。
+// Accessを推定していないルールは、すべての部分式の成否を変えうるものとして扱う。評価に失敗した部分式は成り立っていないとみなす
+func UnmetDistance(target *Query, rules []*core.EdgeRule) (func(node *core.Node) float64, error) {
+	conjuncts, err := target.Conjuncts()
+	if err != nil {
+		return nil, err
+	}
+	perStep, err := maxConjunctsPerRule(conjuncts, rules)
+	if err != nil {
+		return nil, err
+	}
+	return func(node *core.Node) float64 {
+		unmet := 0
+		for _, conjunct := range conjuncts {
+			if matched, err := conjunct.Match(node); err != nil || !matched {
+				unmet++
+			}
+		}
+		return math.Ceil(float64(unmet) / float64(perStep))
+	}, nil
+}
+
+// maxConjunctsPerRule 1つのルールの遷移で成否が変わりうる部分式の最大数（1以上）
+// resourcesや$envを参照する部分式は、どのキーの変更でも成否が変わりうるものとして数える
+func maxConjunctsPerRule(conjuncts []*Query, rules []*core.EdgeRule) (int, error) {
+	reads := make([][]string, len(conjuncts))
+	for i, conjunct := range conjuncts {
+		names, err := Identifiers(conjunct.source)
+		if err != nil {
+			return 0, err
+		}
+		reads[i] = names
+	}
+
+	most := 1
+	for _, rule := range rules {
+		if rule.Access == nil {
+			return len(conjuncts), nil
+		}
+		writes := make(map[string]bool, len(rule.Access.Writes))
+		for _, key := range rule.Access.Writes {
+			writes[key] = true
+		}
+		if len(writes) == 0 {
+			continue
+		}
+		count := 0
+		for _, names := range reads {
+			for _, name := range names {
+				if name == "resources" || name == "$env" || writes[name] {
+					count++
+					break
+				}
+			}
+		}
+		most = max(most, count)
+	}
+	return most, nil
+}
+
+// Env 式を評価する環境を作成
+// リソースがmap[string]anyの場合は各キーを変数として参照でき、
+// いずれの形式でも resources でリソース全体を参照できる
+func Env(node *core.Node) map[string]any {
+	resources := (*node).GetResources()
+	env := map[string]any{}
+	if values, ok := resources.(map[string]any); ok {
+		for k, v := range values {
+			env[k] = v
+		}
+	}
+	if _, exists := env["resources"]; !exists {
+		env["resources"] = resources
+	}
+	return env
+}
@@ -0,0 +1,141 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+// testNode テスト用のノード
+type testNode struct {
+	resources any
+}
+
+func (n testNode) GetID() string                { return "test" }
+func (n testNode) Equals(other core.Node) bool  { return n.GetID() == other.GetID() }
+func (n testNode) GetResources() any            { return n.resources }
+func (n testNode) GetResourcesString() []string { return nil }
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		resources any
+		expected  bool
+	}{
+		{"map key", `status == "running"`, map[string]any{"status": "running"}, true},
+		{"undefined key", `log != nil`, map[string]any{"status": "running"}, false},
+		{"string list", `"a" in resources`, []string{"a", "b"}, true},
+		{"string list missing", `"c" in resources`, []string{"a", "b"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("failed to compile: %v", err)
+			}
+			var node core.Node = testNode{resources: tt.resources}
+			matched, err := q.Match(&node)
+			if err != nil {
+				t.Fatalf("failed to match: %v", err)
+			}
+			if matched != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+
+	q, err := Compile(`len(resources)`)
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+	var node core.Node = testNode{resources: []string{"a"}}
+	if _, err := q.Match(&node); err == nil {
+		t.Error("expected error for non-bool query")
+	}
+}
+
+func TestUnmetDistance(t *testing.T) {
+	target, err := Compile(`status == "done" && owner == "team-b" and (retries > 2 || failed)`)
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+	conjuncts, err := target.Conjuncts()
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+	if len(conjuncts) != 3 {
+		t.Fatalf("expected 3 conjuncts, got %d", len(conjuncts))
+	}
+	distance, err := UnmetDistance(target, nil)
+	if err != nil {
+		t.Fatalf("failed to create distance: %v", err)
+	}
+
+	tests := []struct {
+		resources map[string]any
+		expected  float64
+	}{
+		{map[string]any{"status": "new", "owner": "team-a", "retries": 0}, 3},
+		{map[string]any{"status": "done", "owner": "team-a", "retries": 0}, 2},
+		{map[string]any{"status": "done", "owner": "team-b", "retries": 0, "failed": true}, 0},
+	}
+	for _, tt := range tests {
+		var node core.Node = testNode{resources: tt.resources}
+		if d := distance(&node); d != tt.expected {
+			t.Errorf("%v: expected %v, got %v", tt.resources, tt.expected, d)
+		}
+	}
+
+	// statusとownerを同時に変えるルールがある場合、2つの条件を1回の遷移で満たせるため見積もりを半分（切り上げ）にする
+	handover := &core.EdgeRule{Access: &core.RuleAccess{Writes: []string{"status", "owner"}}}
+	retry := &core.EdgeRule{Access: &core.RuleAccess{Writes: []string{"retries"}}}
+	distance, err = UnmetDistance(target, []*core.EdgeRule{handover, retry})
+	if err != nil {
+		t.Fatalf("failed to create distance: %v", err)
+	}
+	var start core.Node = testNode{resources: map[string]any{"status": "new", "owner": "team-a", "retries": 0}}
+	if d := distance(&start); d != 2 {
+		t.Errorf("expected ceil(3/2) = 2, got %v", d)
+	}
+	// Accessのないルールはすべての条件を変えうるものとして扱う
+	distance, err = UnmetDistance(target, []*core.EdgeRule{retry, {}})
+	if err != nil {
+		t.Fatalf("failed to create distance: %v", err)
+	}
+	if d := distance(&start); d != 1 {
+		t.Errorf("expected 1 for a rule without access sets, got %v", d)
+	}
+
+	q, err := Compile(`retries * 2`)
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+	var node core.Node = testNode{resources: map[string]any{"retries": 3}}
+	if d, err := q.Distance(&node); err != nil || d != 6 {
+		t.Errorf("expected 6, got %v (%v)", d, err)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected []string
+	}{
+		{`status == "done" && owner != nil`, []string{"status", "owner"}},
+		{`len(items) > 0 && items[0].name == status`, []string{"items", "status"}},
+		{`all(items, {.ok}) || $env["x"] == 1`, []string{"items", "$env"}},
+		{`true`, nil},
+	}
+	for _, tt := range tests {
+		names, err := Identifiers(tt.source)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", tt.source, err)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.source, tt.expected, names)
+		}
+	}
+}
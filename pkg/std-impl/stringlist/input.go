@@ -6,6 +6,7 @@ import (
 	"slices"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/intern"
 )
 
 type RuledJson struct {
@@ -16,49 +17,92 @@ type RuledJson struct {
 		Rule           []string `json:"rule"`            // create, deleteは対象の文字列1つ, updateは[0]が既存, [1]が新しいもの
 		FireCondition  []string `json:"fire_condition"`  // ある物を指定して、その物がある場合に発火する
 		BlockCondition []string `json:"block_condition"` // Fireがtrueのときに評価する。ある物を指定して、その物がある場合にブロックする
+		Priority       int      `json:"priority"`        // 優先度（高いルールが実行可能な場合、低いルールは遷移しない）
+		Weight         *float64 `json:"weight"`          // 重み（省略時は1、マルコフ連鎖の解析で遷移確率の比として使用）
 	} `json:"edge_rules"`
 }
 
 func createFireConditionFunc(conditions []string) func(*core.Node) bool {
+	symbols := internAll(conditions)
 	return func(n *core.Node) bool {
-		resources, ok := (*n).GetResources().([]string)
-		if !ok {
-			panic(fmt.Sprintf("node resources is not []string: %v", n))
-		}
+		node := asStringListNode(n)
 		// 条件が空の場合は、ノードのリソースも空の場合のみtrueを返す
-		if len(conditions) == 0 {
-			return len(resources) == 0
+		if len(symbols) == 0 {
+			return len(node.items) == 0
 		}
-
-		for _, condition := range conditions {
-			if slices.Contains(resources, condition) {
-				return true
-			}
-		}
-		return false
+		return node.containsAny(symbols)
 	}
 }
 
 func createBlockConditionFunc(conditions []string) func(*core.Node) bool {
+	symbols := internAll(conditions)
 	return func(n *core.Node) bool {
-		resources, ok := (*n).GetResources().([]string)
-		if !ok {
-			panic(fmt.Sprintf("node resources is not []string: %v", n))
-		}
 		// 条件が空の場合は常にfalseを返す（ブロックしない）
-		if len(conditions) == 0 {
+		if len(symbols) == 0 {
 			return false
 		}
+		return asStringListNode(n).containsAny(symbols)
+	}
+}
 
-		for _, condition := range conditions {
-			if slices.Contains(resources, condition) {
-				return true
-			}
-		}
-		return false
+// internAll 文字列をまとめてインターンする
+func internAll(values []string) []intern.Symbol {
+	symbols := make([]intern.Symbol, len(values))
+	for i, value := range values {
+		symbols[i] = intern.Intern(value)
+	}
+	return symbols
+}
+
+// asStringListNode ノードをstringListNodeとして取得（他の実装の場合はリソースのリストから作成する）
+func asStringListNode(n *core.Node) stringListNode {
+	if node, ok := (*n).(stringListNode); ok {
+		return node
+	}
+	resources, ok := (*n).GetResources().([]string)
+	if !ok {
+		panic(fmt.Sprintf("node resources is not []string: %v", n))
+	}
+	return newStringListNode(resources)
+}
+
+// ruleSymbols ルールの対象の文字列をインターンする（count個に満たない場合はエラー）
+func ruleSymbols(name string, rule []string, count int) ([]intern.Symbol, error) {
+	if len(rule) < count {
+		return nil, fmt.Errorf("rule %s requires %d element(s) in rule, got %v", name, count, rule)
+	}
+	return internAll(rule[:count]), nil
+}
+
+// setConditionTexts 条件の人が読める表現をルールに設定
+func setConditionTexts(edgeRule *core.EdgeRule, fireConditions []string, blockConditions []string) {
+	if len(fireConditions) == 0 {
+		edgeRule.FireConditionText = "empty"
+	} else {
+		edgeRule.FireConditionText = fmt.Sprintf("any of %v", fireConditions)
+	}
+	if len(blockConditions) > 0 {
+		edgeRule.BlockConditionText = fmt.Sprintf("any of %v", blockConditions)
 	}
 }
 
+// inferAccess ルールが参照・変更するリソースを、文字列ごとにキーとみなして推定する（半順序削減に使用）
+// 条件は指定した文字列の有無を参照し、空の発火条件はリソースが空かどうかを見るためすべてを参照する。
+// updateは置き換える文字列の有無を参照し、置き換える前後の文字列を変更する。create, deleteは対象の文字列を変更する。
+func inferAccess(action string, rule []string, fireConditions []string, blockConditions []string) *core.RuleAccess {
+	access := &core.RuleAccess{ReadsAll: len(fireConditions) == 0}
+	access.Reads = append(access.Reads, fireConditions...)
+	access.Reads = append(access.Reads, blockConditions...)
+	switch action {
+	case "create", "delete":
+		access.Writes = append(access.Writes, rule[:min(len(rule), 1)]...)
+	case "update":
+		access.Reads = append(access.Reads, rule[:min(len(rule), 1)]...)
+		access.Writes = append(access.Writes, rule[:min(len(rule), 2)]...)
+	}
+	return access
+}
+
 func NewRuledJsonParser() (core.Parser, error) {
 	return &RuledJson{}, nil
 }
@@ -83,20 +127,27 @@ func (r *RuledJson) Parse(input string) (
 		currentRule := rule
 		fireCondition := createFireConditionFunc(currentRule.FireCondition)
 		blockCondition := createBlockConditionFunc(currentRule.BlockCondition)
+		if currentRule.Weight != nil && *currentRule.Weight < 0 {
+			return nil, nil, nil, fmt.Errorf("weight cannot be negative for rule: %s", currentRule.Name)
+		}
 
 		switch currentRule.Action {
 		case "create":
+			target, err := ruleSymbols(currentRule.Name, currentRule.Rule, 1)
+			if err != nil {
+				return nil, nil, nil, err
+			}
 			edgeRule, err := core.NewEdgeRule(
 				currentRule.Name,
 				func(n *core.Node) *core.Node {
-					currentResources, ok := (*n).GetResources().([]string)
-					if !ok {
-						panic(fmt.Sprintf("node resources is not []string: %v", n))
+					current := asStringListNode(n)
+					items := make([]intern.Symbol, len(current.items), len(current.items)+1)
+					copy(items, current.items)
+					// 空文字列はリソースとして扱わない
+					if currentRule.Rule[0] != "" {
+						items = append(items, target[0])
 					}
-					newResources := make([]string, len(currentResources)+1)
-					copy(newResources, currentResources)
-					newResources[len(currentResources)] = currentRule.Rule[0]
-					newNode := newNode(newResources)
+					var newNode core.Node = newStringListNodeFromSymbols(items)
 					return &newNode
 				},
 				fireCondition,
@@ -105,24 +156,36 @@ func (r *RuledJson) Parse(input string) (
 			if err != nil {
 				return nil, nil, nil, err
 			}
+			setConditionTexts(edgeRule, currentRule.FireCondition, currentRule.BlockCondition)
+			edgeRule.Digest = core.RuleDigest(currentRule)
+			edgeRule.Access = inferAccess(currentRule.Action, currentRule.Rule, currentRule.FireCondition, currentRule.BlockCondition)
+			edgeRule.Priority = currentRule.Priority
+			if currentRule.Weight != nil {
+				edgeRule.Weight = *currentRule.Weight
+			}
 			edgeRules = append(edgeRules, edgeRule)
 		case "update":
+			targets, err := ruleSymbols(currentRule.Name, currentRule.Rule, 2)
+			if err != nil {
+				return nil, nil, nil, err
+			}
 			edgeRule, err := core.NewEdgeRule(
 				currentRule.Name,
 				func(n *core.Node) *core.Node {
-					currentResources, ok := (*n).GetResources().([]string)
-					if !ok {
-						panic(fmt.Sprintf("node resources is not []string: %v", n))
-					}
-					newResources := make([]string, len(currentResources))
-					copy(newResources, currentResources)
+					current := asStringListNode(n)
+					items := make([]intern.Symbol, len(current.items))
+					copy(items, current.items)
 
-					targetIndex := slices.Index(newResources, currentRule.Rule[0])
+					targetIndex := slices.Index(items, targets[0])
 					if targetIndex == -1 {
-						panic(fmt.Sprintf("rule: %v, rule.Rule[0] %s not found in %v", currentRule, currentRule.Rule[0], currentResources))
+						panic(fmt.Sprintf("rule: %v, rule.Rule[0] %s not found in %v", currentRule, currentRule.Rule[0], current.GetResources()))
+					}
+					items[targetIndex] = targets[1]
+					// 空文字列に置き換えた場合はリソースから取り除く
+					if currentRule.Rule[1] == "" {
+						items = slices.Delete(items, targetIndex, targetIndex+1)
 					}
-					newResources[targetIndex] = currentRule.Rule[1]
-					newNode := newNode(newResources)
+					var newNode core.Node = newStringListNodeFromSymbols(items)
 					return &newNode
 				},
 				fireCondition,
@@ -131,25 +194,33 @@ func (r *RuledJson) Parse(input string) (
 			if err != nil {
 				return nil, nil, nil, err
 			}
+			setConditionTexts(edgeRule, currentRule.FireCondition, currentRule.BlockCondition)
+			edgeRule.Digest = core.RuleDigest(currentRule)
+			edgeRule.Access = inferAccess(currentRule.Action, currentRule.Rule, currentRule.FireCondition, currentRule.BlockCondition)
+			edgeRule.Priority = currentRule.Priority
+			if currentRule.Weight != nil {
+				edgeRule.Weight = *currentRule.Weight
+			}
 			edgeRules = append(edgeRules, edgeRule)
 		case "delete":
+			target, err := ruleSymbols(currentRule.Name, currentRule.Rule, 1)
+			if err != nil {
+				return nil, nil, nil, err
+			}
 			edgeRule, err := core.NewEdgeRule(
 				currentRule.Name,
 				func(n *core.Node) *core.Node {
-					currentResources, ok := (*n).GetResources().([]string)
-					if !ok {
-						panic(fmt.Sprintf("node resources is not []string: %v", n))
-					}
-					newResources := make([]string, 0, len(currentResources))
+					current := asStringListNode(n)
+					items := make([]intern.Symbol, 0, len(current.items))
 
 					// 削除対象以外の要素をコピー
-					for _, resource := range currentResources {
-						if resource != currentRule.Rule[0] {
-							newResources = append(newResources, resource)
+					for _, item := range current.items {
+						if item != target[0] {
+							items = append(items, item)
 						}
 					}
 
-					newNode := newNode(newResources)
+					var newNode core.Node = newStringListNodeFromSymbols(items)
 					return &newNode
 				},
 				fireCondition,
@@ -158,6 +229,13 @@ func (r *RuledJson) Parse(input string) (
 			if err != nil {
 				return nil, nil, nil, err
 			}
+			setConditionTexts(edgeRule, currentRule.FireCondition, currentRule.BlockCondition)
+			edgeRule.Digest = core.RuleDigest(currentRule)
+			edgeRule.Access = inferAccess(currentRule.Action, currentRule.Rule, currentRule.FireCondition, currentRule.BlockCondition)
+			edgeRule.Priority = currentRule.Priority
+			if currentRule.Weight != nil {
+				edgeRule.Weight = *currentRule.Weight
+			}
 			edgeRules = append(edgeRules, edgeRule)
 		default:
 			return nil, nil, nil, fmt.Errorf("the rule action is not defined: %v, action: %s", currentRule, currentRule.Action)
@@ -5,13 +5,14 @@ import (
 	"strings"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
+	"github.com/yuukiiwai/blindspot/pkg/std-impl/intern"
 )
 
-// Node リソースの状態を表現
+// StringListNode リソースの状態を表現
+// ライブラリとして直接ノードを作る場合に使う。パーサーはよりコンパクトなstringListNodeを生成するが、
+// 同じリソースであれば同じIDになるため、混在させてもよい。
 type StringListNode []string
 
-// NewNode 新しいNodeを作成
-
 // GetID ノードの一意な識別子を生成
 func (n StringListNode) GetID() string {
 	if len(n) == 0 {
@@ -38,18 +39,78 @@ func (n StringListNode) GetResourcesString() []string {
 	return n
 }
 
-func newStringListNode(resources []string) StringListNode {
-	// 空文字列を除去してソート
-	filtered := make([]string, 0, len(resources))
+// stringListNode パーサーが生成する、リソースの状態のコンパクトな表現
+// リソースの文字列はインターンして文字列の順に並べて保持し、IDは作成時に一度だけ求めてキャッシュする。
+type stringListNode struct {
+	items []intern.Symbol // 文字列の順に並べたリソース
+	id    string
+}
+
+// GetID ノードの一意な識別子を取得
+func (n stringListNode) GetID() string {
+	return n.id
+}
+
+// Equals ノードが同じかどうかを判定
+func (n stringListNode) Equals(other core.Node) bool {
+	return n.GetID() == other.GetID()
+}
+
+// GetResources リソースのリストを取得
+func (n stringListNode) GetResources() any {
+	resources := make([]string, len(n.items))
+	for i, item := range n.items {
+		resources[i] = item.String()
+	}
+	return resources
+}
+
+// GetResourcesString リソースのリストを文字列で取得
+func (n stringListNode) GetResourcesString() []string {
+	if len(n.items) == 0 {
+		return []string{"empty"}
+	}
+	return n.GetResources().([]string)
+}
+
+// containsAny いずれかのリソースを持っているかどうか（インターンした文字列どうしの比較で判定する）
+func (n stringListNode) containsAny(conditions []intern.Symbol) bool {
+	for _, item := range n.items {
+		for _, condition := range conditions {
+			if item == condition {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newStringListNode リソースのリストからコンパクトな表現のノードを作成
+func newStringListNode(resources []string) stringListNode {
+	// 空文字列を除去してインターンする
+	items := make([]intern.Symbol, 0, len(resources))
 	for _, resource := range resources {
 		if resource != "" {
-			filtered = append(filtered, resource)
+			items = append(items, intern.Intern(resource))
 		}
 	}
+	return newStringListNodeFromSymbols(items)
+}
 
-	sorted := make([]string, len(filtered))
-	copy(sorted, filtered)
-	sort.Strings(sorted)
+// newStringListNodeFromSymbols インターンした文字列からノードを作成（itemsは並べ替えるため、呼び出し側のものを渡さないこと）
+func newStringListNodeFromSymbols(items []intern.Symbol) stringListNode {
+	sort.Slice(items, func(i, j int) bool { return items[i].String() < items[j].String() })
 
-	return StringListNode(sorted)
+	id := "empty"
+	if len(items) > 0 {
+		var joined strings.Builder
+		for i, item := range items {
+			if i > 0 {
+				joined.WriteByte(',')
+			}
+			joined.WriteString(item.String())
+		}
+		id = joined.String()
+	}
+	return stringListNode{items: items, id: id}
 }
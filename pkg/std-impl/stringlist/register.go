@@ -0,0 +1,50 @@
+package stringlist
+
+import (
+	"encoding/json"
+
+	"github.com/yuukiiwai/blindspot/pkg/core"
+)
+
+func init() {
+	err := core.RegisterParser(core.ParserRegistration{
+		Name:        "stringlist",
+		Description: "文字列のリストをリソースとするJSON形式",
+		Extensions:  []string{".json"},
+		New:         NewRuledJsonParser,
+		Detect:      detect,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// detect start_resourcesがリストである、またはedge_rulesがactionを持つ場合にstringlist形式と判定
+func detect(content string) bool {
+	var document map[string]any
+	if err := json.Unmarshal([]byte(content), &document); err != nil {
+		return false
+	}
+
+	if start, exists := document["start_resources"]; exists && start != nil {
+		if _, isList := start.([]any); !isList {
+			return false
+		}
+	}
+	if rules, ok := document["edge_rules"].([]any); ok {
+		for _, rule := range rules {
+			fields, ok := rule.(map[string]any)
+			if !ok {
+				continue
+			}
+			if _, hasEffect := fields["effect"]; hasEffect {
+				return false
+			}
+			if _, hasAction := fields["action"]; hasAction {
+				return true
+			}
+		}
+	}
+	_, isList := document["start_resources"].([]any)
+	return isList
+}
@@ -2,6 +2,8 @@
 package stringlist
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/yuukiiwai/blindspot/pkg/core"
@@ -79,3 +81,127 @@ func TestStringListNode(t *testing.T) {
 		t.Errorf("expected %s, but got %s", expectedOutput, outputResult)
 	}
 }
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"stringlist", `{"start_resources": [], "edge_rules": [{"name": "x", "action": "create"}]}`, true},
+		{"cud json", `{"start_resources": {}, "edge_rules": [{"name": "x", "effect": []}]}`, false},
+		{"yaml", "start_resources: []\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if detect(tt.content) != tt.expected {
+				t.Errorf("expected %v for %s", tt.expected, tt.content)
+			}
+		})
+	}
+}
+
+func TestStringListNodeID(t *testing.T) {
+	node := newStringListNode([]string{"b", "", "a", "b"})
+	if node.GetID() != "a,b,b" {
+		t.Errorf("expected a,b,b, got %s", node.GetID())
+	}
+	resources := node.GetResources().([]string)
+	if strings.Join(resources, ",") != "a,b,b" {
+		t.Errorf("expected [a b b], got %v", resources)
+	}
+	if empty := newStringListNode(nil); empty.GetID() != "empty" || empty.GetResourcesString()[0] != "empty" {
+		t.Errorf("expected empty node, got %s", empty.GetID())
+	}
+
+	// ライブラリとして直接作成したStringListNodeも同じIDになる
+	if id := (StringListNode{"a", "b", "b"}).GetID(); id != node.GetID() {
+		t.Errorf("expected StringListNode ID %s, got %s", node.GetID(), id)
+	}
+	if id := (StringListNode{}).GetID(); id != "empty" {
+		t.Errorf("expected empty StringListNode ID, got %s", id)
+	}
+}
+
+func TestInferAccess(t *testing.T) {
+	// 4つのリソースを独立に作成するだけのルール（16状態、デッドロックはすべてを作成した状態のみ）
+	rules := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		rules = append(rules, fmt.Sprintf(`{"name": "create_%[1]d", "action": "create", "rule": ["r%[1]d"], "fire_condition": ["base"], "block_condition": ["r%[1]d"]}`, i))
+	}
+	rules = append(rules, `{"name": "rename", "action": "update", "rule": ["old", "new"], "fire_condition": [], "block_condition": []}`)
+	parser, _ := NewRuledJsonParser()
+	firstResource, newNode, edgeRules, err := parser.Parse(fmt.Sprintf(`{"start_resources": ["base"], "edge_rules": [%s]}`, strings.Join(rules, ",")))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	rename := edgeRules[4].Access
+	if rename == nil || !rename.ReadsAll || strings.Join(rename.Reads, ",") != "old" || strings.Join(rename.Writes, ",") != "old,new" {
+		t.Errorf("unexpected access for update: %+v", rename)
+	}
+
+	generate := func(options *core.ReductionOptions) *core.Generator {
+		generator := core.NewGenerator(newNode, firstResource, edgeRules[:4], nil)
+		generator.SetPartialOrderReduction(options)
+		if err := generator.Generate(); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		return generator
+	}
+	full := generate(nil)
+	reduced := generate(&core.ReductionOptions{})
+	if len(reduced.GetNodes()) >= len(full.GetNodes()) {
+		t.Errorf("expected fewer states with reduction, got %d of %d", len(reduced.GetNodes()), len(full.GetNodes()))
+	}
+	deadlocks := reduced.GetDeadlockNodes()
+	if len(deadlocks) != 1 || (*deadlocks[0]).GetID() != "base,r0,r1,r2,r3" {
+		t.Errorf("expected the deadlock to be preserved, got %v", deadlocks)
+	}
+	// resourcesを参照する条件は特定のキーに絞れないため、何かを作成するルールはすべて展開する
+	visible := generate(&core.ReductionOptions{VisibleAll: true})
+	if len(visible.GetNodes()) != len(full.GetNodes()) {
+		t.Errorf("expected no reduction when every key is visible, got %d of %d", len(visible.GetNodes()), len(full.GetNodes()))
+	}
+}
+
+// benchmarkRules n個のリソースを独立に作成・削除するルール（2^n個の状態になる）
+func benchmarkRules(n int) string {
+	rules := make([]string, 0, n*2)
+	for i := 0; i < n; i++ {
+		rules = append(rules,
+			fmt.Sprintf(`{"name": "create_%[1]d", "action": "create", "rule": ["r%[1]d"], "fire_condition": ["base"], "block_condition": ["r%[1]d"]}`, i),
+			fmt.Sprintf(`{"name": "delete_%[1]d", "action": "delete", "rule": ["r%[1]d"], "fire_condition": ["r%[1]d"], "block_condition": []}`, i),
+		)
+	}
+	return fmt.Sprintf(`{"start_resources": ["base"], "edge_rules": [%s]}`, strings.Join(rules, ","))
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	parser, err := NewRuledJsonParser()
+	if err != nil {
+		b.Fatalf("failed to create parser: %v", err)
+	}
+	var states int
+	for i := 0; i < b.N; i++ {
+		firstResource, newNode, edgeRules, err := parser.Parse(benchmarkRules(8))
+		if err != nil {
+			b.Fatalf("failed to parse: %v", err)
+		}
+		generator := core.NewGenerator(newNode, firstResource, edgeRules, nil)
+		if err := generator.Generate(); err != nil {
+			b.Fatalf("failed to generate: %v", err)
+		}
+		states = len(generator.GetNodes())
+	}
+	b.ReportMetric(float64(states)*float64(b.N)/b.Elapsed().Seconds(), "states/s")
+}
+
+func BenchmarkGetID(b *testing.B) {
+	node := newStringListNode([]string{"base", "r1", "r2", "r3", "r4"})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node.GetID()
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ids/s")
+}